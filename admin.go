@@ -0,0 +1,340 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 admin.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startAdminServer(srvInfo serverInfo)
+--  func handleAdmin(srvInfo serverInfo, conn net.Conn)
+--  func adminCommand(srvInfo serverInfo, command []string) string
+--  func newAcceptGate() *acceptGate
+--  func (gate *acceptGate) pause()
+--  func (gate *acceptGate) resume()
+--  func (gate *acceptGate) wait()
+--  func (gate *acceptGate) isPaused() bool
+--
+-- NOTES: This file serves a line based admin protocol on -admin-addr. Each
+--        command is a line and each reply is a line starting with OK or ERR.
+--        When -auth-token is set the first line must be "auth <token>".
+--
+--        stats       reports the observer's statistics.
+--        pause       stops the workers accepting connections.
+--        resume      lets the workers accept connections again.
+--        workers N   grows or shrinks the pool towards N workers.
+--        reset       clears the statistics, as -reset-on-report does.
+--        shutdown    stops the server as if the listener had closed.
+--        quit        closes the admin connection.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// adminMaxLine the longest admin command accepted.
+const adminMaxLine = 1024
+
+// acceptGate holds the workers back from accepting while the server is paused.
+type acceptGate struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	paused bool
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startAdminServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startAdminServer(srvInfo serverInfo)
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing if -admin-addr isn't set. Exits the program if the
+--						address can't be listened on.
+------------------------------------------------------------------------------*/
+func startAdminServer(srvInfo serverInfo) {
+	if srvInfo.config.adminAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", srvInfo.config.adminAddr)
+	if err != nil {
+		log.Fatalln("Unable to listen for admin connections:", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Println("Admin listener stopped:", err)
+				return
+			}
+			go handleAdmin(srvInfo, conn)
+		}
+	}()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    handleAdmin
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func handleAdmin(srvInfo serverInfo, conn net.Conn)
+--   srvInfo:		information about the overall server
+--      conn:		an admin client.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Reads commands with the same line reader as the echo
+--						connections until the client closes the connection or quits.
+------------------------------------------------------------------------------*/
+func handleAdmin(srvInfo serverInfo, conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := srvInfo.config.authToken == ""
+	for {
+		line, err := readLine(reader, '\n', adminMaxLine)
+		if err != nil {
+			if err == errRequestTooLarge {
+				fmt.Fprintln(conn, "ERR command too long")
+			}
+			return
+		}
+		command := strings.Fields(string(line))
+		if len(command) == 0 {
+			continue
+		}
+
+		if !authenticated {
+			if len(command) != 2 || command[0] != "auth" ||
+				subtle.ConstantTimeCompare([]byte(command[1]), []byte(srvInfo.config.authToken)) != 1 {
+				fmt.Fprintln(conn, "ERR unauthorized")
+				return
+			}
+			authenticated = true
+			fmt.Fprintln(conn, "OK")
+			continue
+		}
+		if command[0] == "quit" {
+			fmt.Fprintln(conn, "OK bye")
+			return
+		}
+		fmt.Fprintln(conn, adminCommand(srvInfo, command))
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    adminCommand
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func adminCommand(srvInfo serverInfo, command []string) string
+--   srvInfo:		information about the overall server
+--   command:		the command and its arguments.
+--
+-- RETURNS:     string the reply, without its newline.
+--
+-- NOTES:			Commands that touch the observer's state are sent to the
+--						observer, the same as the control endpoint's.
+------------------------------------------------------------------------------*/
+func adminCommand(srvInfo serverInfo, command []string) string {
+	switch command[0] {
+	case "stats":
+		snapshot := readStats(srvInfo)
+		reply := fmt.Sprintf("OK connections=%d peak=%d total=%d requests=%d received=%d sent=%d",
+			snapshot.CurrentConnections, snapshot.PeakConnections, snapshot.TotalConnections,
+			snapshot.TotalRequests, snapshot.BytesReceived, snapshot.BytesSent)
+		reasons := make([]string, 0, len(snapshot.CloseReasons))
+		for reason := range snapshot.CloseReasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			if reason == "" {
+				reply += fmt.Sprintf(" closed[client]=%d", snapshot.CloseReasons[reason])
+			} else {
+				reply += fmt.Sprintf(" closed[%s]=%d", reason, snapshot.CloseReasons[reason])
+			}
+		}
+		return reply
+	case "pause":
+		srvInfo.acceptGate.pause()
+		return "OK paused"
+	case "resume":
+		srvInfo.acceptGate.resume()
+		return "OK resumed"
+	case "workers":
+		if len(command) != 2 {
+			return "ERR workers needs a number of workers"
+		}
+		target, err := strconv.Atoi(command[1])
+		if err != nil || target < 1 {
+			return "ERR workers needs a positive number of workers"
+		}
+		resize := workerResize{target: target, reply: make(chan int, 1)}
+		srvInfo.workerResize <- resize
+		return fmt.Sprint("OK workers=", <-resize.reply)
+	case "reset":
+		reply := make(chan bool, 1)
+		srvInfo.statsResets <- reply
+		<-reply
+		return "OK reset"
+	case "shutdown":
+		requestShutdown(srvInfo, nil)
+		return "OK shutting down"
+	}
+
+	return "ERR unknown command " + strconv.Quote(command[0])
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newAcceptGate
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newAcceptGate() *acceptGate
+--
+-- RETURNS:     *acceptGate an open gate.
+------------------------------------------------------------------------------*/
+func newAcceptGate() *acceptGate {
+	gate := &acceptGate{}
+	gate.cond = sync.NewCond(&gate.mutex)
+
+	return gate
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    pause
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (gate *acceptGate) pause()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Workers already waiting in Accept still take one connection
+--						each, new connections then wait in the listen backlog.
+------------------------------------------------------------------------------*/
+func (gate *acceptGate) pause() {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	gate.paused = true
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    resume
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (gate *acceptGate) resume()
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (gate *acceptGate) resume() {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	gate.paused = false
+	gate.cond.Broadcast()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    wait
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (gate *acceptGate) wait()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called by a worker before it accepts, blocks while paused.
+------------------------------------------------------------------------------*/
+func (gate *acceptGate) wait() {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	for gate.paused {
+		gate.cond.Wait()
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isPaused
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (gate *acceptGate) isPaused() bool
+--
+-- RETURNS:     bool true while the workers are held back from accepting.
+------------------------------------------------------------------------------*/
+func (gate *acceptGate) isPaused() bool {
+	gate.mutex.Lock()
+	defer gate.mutex.Unlock()
+
+	return gate.paused
+}
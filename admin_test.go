@@ -0,0 +1,119 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 admin_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func adminSession(t *testing.T, srvInfo serverInfo) func(command string) string
+--  func TestAdminStatsAndShutdown(t *testing.T)
+--  func TestAdminUnauthorized(t *testing.T)
+--
+-- NOTES: Tests for the admin CLI. handleAdmin serves one end of a net.Pipe,
+--        the test types its commands on the other. The shutdown test runs
+--        the observer in a child test process, as exitServer ends the process
+--        it runs in.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// adminSession starts an admin session on srvInfo, returning a function that
+// issues a command and returns its reply, or "" once the session is over.
+func adminSession(t *testing.T, srvInfo serverInfo) func(command string) string {
+	server, client := net.Pipe()
+	go handleAdmin(srvInfo, server)
+	t.Cleanup(func() { client.Close() })
+	reader := bufio.NewReader(client)
+
+	return func(command string) string {
+		t.Helper()
+		go io.WriteString(client, command+"\n")
+		reply, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			t.Fatalf("reading the reply to %q: %v", command, err)
+		}
+
+		return strings.TrimSuffix(reply, "\n")
+	}
+}
+
+// TestAdminStatsAndShutdown checks that an authenticated session gets the
+// observer's counts from stats and that shutdown stops the server, which
+// exits with 0 once it has written its report.
+func TestAdminStatsAndShutdown(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{authToken: "secret",
+			reportFile: reportFile, reportFormat: "json"})
+		go observerLoop(srvInfo, nil)
+		srvInfo.serverConnection <- newConnectionConst
+		srvInfo.serverConnection <- newConnectionConst
+		srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 3,
+			BytesReceived: 12, BytesSent: 12, CloseReason: closeReasonIdle}
+
+		admin := adminSession(t, srvInfo)
+		for _, command := range []string{"auth secret", "stats", "bogus", "shutdown"} {
+			os.Stdout.WriteString("reply " + admin(command) + "\n")
+		}
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the shutdown didn't stop the server
+	}
+
+	output, status, reportFile := runTestChild(t, "TestAdminStatsAndShutdown")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0, output %q", status, output)
+	}
+	// the server's own output can land before the last reply
+	var replies []string
+	for _, line := range strings.Split(output, "\n") {
+		if reply, ok := strings.CutPrefix(line, "reply "); ok {
+			replies = append(replies, reply)
+		}
+	}
+	want := []string{"OK",
+		"OK connections=1 peak=2 total=1 requests=3 received=12 sent=12 closed[" + closeReasonIdle + "]=1",
+		`ERR unknown command "bogus"`, "OK shutting down"}
+	if len(replies) < len(want) {
+		t.Fatalf("the session replied %q, want %q", replies, want)
+	}
+	for i := range want {
+		if replies[i] != want[i] {
+			t.Errorf("reply %d was %q, want %q", i, replies[i], want[i])
+		}
+	}
+	if _, err := os.Stat(reportFile); err != nil {
+		t.Error("the report wasn't written:", err)
+	}
+}
+
+// TestAdminUnauthorized checks that a session which doesn't authenticate
+// first is refused and hung up on without running its command.
+func TestAdminUnauthorized(t *testing.T) {
+	for _, first := range []string{"shutdown", "auth wrong", "auth"} {
+		srvInfo := newTestServerInfo(serverConfig{authToken: "secret"})
+		admin := adminSession(t, srvInfo)
+		if reply := admin(first); reply != "ERR unauthorized" {
+			t.Errorf("%q replied %q, want ERR unauthorized", first, reply)
+		}
+		if reply := admin("stats"); reply != "" {
+			t.Errorf("the session was still open after %q, stats replied %q", first, reply)
+		}
+		if len(srvInfo.shutdown) != 0 {
+			t.Errorf("%q stopped the server", first)
+		}
+	}
+}
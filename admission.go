@@ -0,0 +1,204 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 admission.go
+--
+-- REVISIONS: 	October 15, 2026 - Added the cap on connections served at once
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newAdmissionBucket(perSecond float64, queueSize int) *admissionBucket
+--  func (bucket *admissionBucket) admit(now time.Time) (time.Duration, bool)
+--  func newConnectionLimit(connections int) connectionLimit
+--  func (limit connectionLimit) acquire() bool
+--  func (limit connectionLimit) release()
+--
+-- NOTES: This file caps the rate connections are admitted at across the whole
+--        server. It models an admission controller sitting in front of the
+--        server: connections over the rate wait their turn in a bounded queue,
+--        and connections that would overflow the queue are turned away. It
+--        also caps the connections served at once with -max-connections,
+--        connections over the cap are turned away without waiting.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// closeReasonAdmission the CloseReason of a connection turned away by the cap.
+const closeReasonAdmission = "admission-rejected"
+
+// closeReasonBusy the CloseReason of a connection turned away by -max-connections.
+const closeReasonBusy = "server-busy"
+
+// defaultBusyMessage the message sent to a connection turned away by -max-connections.
+const defaultBusyMessage = "server busy"
+
+// admissionBucket a leaky bucket shared by the workers.
+type admissionBucket struct {
+	mutex     sync.Mutex
+	interval  time.Duration // the time between admitted connections
+	queueSize int           // the most connections waiting to be admitted
+	next      time.Time     // when the next connection can be admitted
+}
+
+// connectionLimit a semaphore with a slot for each connection that can be
+// served at once, shared by the workers.
+type connectionLimit chan bool
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newAdmissionBucket
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newAdmissionBucket(perSecond float64, queueSize int) *admissionBucket
+--  perSecond:		the most connections admitted each second.
+--  queueSize:		the most connections waiting to be admitted at once.
+--
+-- RETURNS:     *admissionBucket an empty bucket, nil if perSecond is 0.
+------------------------------------------------------------------------------*/
+func newAdmissionBucket(perSecond float64, queueSize int) *admissionBucket {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &admissionBucket{interval: time.Duration(float64(time.Second) / perSecond),
+		queueSize: queueSize}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    admit
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (bucket *admissionBucket) admit(now time.Time) (time.Duration, bool)
+--       now:		when the connection was accepted.
+--
+-- RETURNS:     time.Duration how long the connection must wait to be admitted.
+--              bool          false if the queue is full and the connection
+--                            should be rejected.
+--
+-- NOTES:			Each admitted connection takes the next free slot, so the
+--						number of connections waiting is how many slots are booked
+--						ahead of now. A nil bucket admits everything straight away.
+------------------------------------------------------------------------------*/
+func (bucket *admissionBucket) admit(now time.Time) (time.Duration, bool) {
+	if bucket == nil {
+		return 0, true
+	}
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	slot := bucket.next
+	if slot.Before(now) {
+		slot = now
+	}
+	wait := slot.Sub(now)
+	if wait > 0 && int((wait-1)/bucket.interval) >= bucket.queueSize {
+		return 0, false
+	}
+	bucket.next = slot.Add(bucket.interval)
+
+	return wait, true
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newConnectionLimit
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newConnectionLimit(connections int) connectionLimit
+-- connections:	the most connections served at once.
+--
+-- RETURNS:     connectionLimit a limit with every slot free, nil if connections
+--              is 0.
+------------------------------------------------------------------------------*/
+func newConnectionLimit(connections int) connectionLimit {
+	if connections <= 0 {
+		return nil
+	}
+
+	return make(connectionLimit, connections)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    acquire
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (limit connectionLimit) acquire() bool
+--
+-- RETURNS:     bool true if the connection was given a slot, to be handed
+--              back with release, false if every slot is taken.
+--
+-- NOTES:			Never blocks. The workers take the slots themselves when they
+--						accept, rather than asking the observer, so two workers
+--						accepting at once can't both be given the last slot. A nil
+--						limit always gives a slot.
+------------------------------------------------------------------------------*/
+func (limit connectionLimit) acquire() bool {
+	if limit == nil {
+		return true
+	}
+	select {
+	case limit <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    release
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (limit connectionLimit) release()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Hands back a slot given by acquire. Does nothing on a nil limit.
+------------------------------------------------------------------------------*/
+func (limit connectionLimit) release() {
+	if limit == nil {
+		return
+	}
+
+	<-limit
+}
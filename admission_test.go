@@ -0,0 +1,164 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 admission_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestAdmissionBucketQueues(t *testing.T)
+--  func TestAdmitConnectionOverflow(t *testing.T)
+--  func TestRefuseRateFraction(t *testing.T)
+--  func TestMaxConnectionsOverTCP(t *testing.T)
+--
+-- NOTES: Tests for the caps on admitting connections. Connections are
+--        admitted at chosen times rather than accepted, so nothing waits,
+--        except for -max-connections which is tested over TCP.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestAdmissionBucketQueues checks that a burst over the cap waits its turn
+// until the queue is full, the rest of the burst being turned away.
+func TestAdmissionBucketQueues(t *testing.T) {
+	bucket := newAdmissionBucket(10, 3)
+	now := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+
+	for i, want := range []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond} {
+		if wait, admitted := bucket.admit(now); !admitted || wait != want {
+			t.Errorf("connection %d admitted %v after %s, want after %s", i+1, admitted, wait, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, admitted := bucket.admit(now); admitted {
+			t.Errorf("connection %d was admitted with the queue full", i+5)
+		}
+	}
+
+	if wait, admitted := bucket.admit(now.Add(time.Second)); !admitted || wait != 0 {
+		t.Errorf("a connection after the burst admitted %v after %s, want straight away", admitted, wait)
+	}
+}
+
+// TestAdmitConnectionOverflow checks that the worker is told to hold a queued
+// connection and that the overflow is closed with closeReasonAdmission.
+func TestAdmitConnectionOverflow(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{})
+	srvInfo.admission = newAdmissionBucket(10, 1)
+
+	var reasons []string
+	var waits []time.Duration
+	for i := 0; i < 3; i++ {
+		reason, wait := admitConnection(srvInfo, clientConn("192.0.2.1", 40000+i))
+		reasons, waits = append(reasons, reason), append(waits, wait)
+	}
+	if reasons[0] != "" || waits[0] != 0 || reasons[1] != "" || waits[1] != 100*time.Millisecond {
+		t.Errorf("the first connections were %q after %s, want admitted straight away then after 100ms", reasons[:2], waits[:2])
+	}
+	if reasons[2] != closeReasonAdmission {
+		t.Errorf("the overflow was %q, want %q", reasons[2], closeReasonAdmission)
+	}
+}
+
+// TestRefuseRateFraction checks that with a fixed seed about -refuse-rate of
+// the connections are refused, the same ones each run.
+func TestRefuseRateFraction(t *testing.T) {
+	const connections, rate = 2000, 0.8
+	refusedBy := func() []bool {
+		srvInfo := newTestServerInfo(serverConfig{refuseRate: rate})
+		refused := make([]bool, connections)
+		for i := range refused {
+			reason, _ := admitConnection(srvInfo, clientConn("192.0.2.1", 40000))
+			refused[i] = reason == closeReasonRefused
+		}
+		return refused
+	}
+
+	first, second := refusedBy(), refusedBy()
+	count := 0
+	for i := range first {
+		if first[i] {
+			count++
+		}
+		if first[i] != second[i] {
+			t.Fatalf("connection %d was refused %v then %v with the same seed", i, first[i], second[i])
+		}
+	}
+	if fraction := float64(count) / connections; fraction < rate-0.03 || fraction > rate+0.03 {
+		t.Errorf("%.3f of the connections were refused, want about %.1f", fraction, rate)
+	}
+}
+
+// TestMaxConnectionsOverTCP checks that with -max-connections 2 a third client
+// is sent the busy message and closed while the first two are still served,
+// and that a slot is free again once one of them has closed.
+func TestMaxConnectionsOverTCP(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+		delimiter: '\n', maxConnections: 2, busyMessage: defaultBusyMessage})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	srvInfo.connectionLimit = newConnectionLimit(2)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	for i := 0; i < 4; i++ {
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+	}
+	go observerLoop(srvInfo, nil)
+
+	var clients []net.Conn
+	var readers []*bufio.Reader
+	echoes := func(i int) bool {
+		io.WriteString(clients[i], "hello\n")
+		echo, _ := readers[i].ReadString('\n')
+		return echo == "hello\n"
+	}
+	for i := 0; i < 3; i++ {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		defer client.Close()
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		clients, readers = append(clients, client), append(readers, bufio.NewReader(client))
+		if i < 2 && !echoes(i) {
+			t.Fatalf("client %d wasn't served", i+1)
+		}
+	}
+
+	if reply, err := io.ReadAll(readers[2]); string(reply) != defaultBusyMessage+"\n" || err != nil {
+		t.Errorf("the third client was sent %q, %v, want %q and closed", reply, err, defaultBusyMessage+"\n")
+	}
+	if !echoes(0) || !echoes(1) {
+		t.Error("the first two clients weren't still served")
+	}
+	clients[0].(*net.TCPConn).CloseWrite()
+	io.ReadAll(readers[0]) // the slot is released before the server closes
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	clients, readers = append(clients, client), append(readers, bufio.NewReader(client))
+	if !echoes(3) {
+		t.Error("a client wasn't served once the first had closed")
+	}
+}
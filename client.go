@@ -0,0 +1,240 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 client.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func runClient(args []string)
+--  func runClientConnection(cfg clientConfig) (connInfo connectionInfo, latencies []time.Duration, err error)
+--  func printClientResults(out io.Writer, results []clientResult, elapsed time.Duration)
+--
+-- NOTES: This file is the load testing client started with the client
+--        subcommand, so the server can be benchmarked without an external
+--        tool. It opens -connections connections to the target at once,
+--        each sending -requests line framed messages one at a time and
+--        checking every echo matches before sending the next. The
+--        connections are accounted in a connectionInfo like the server's,
+--        then the latency percentiles of the requests and the throughput
+--        of the whole run are printed.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clientCommand the first argument that runs the load testing client.
+const clientCommand = "client"
+
+var errEchoMismatch = errors.New("the echo didn't match the request")
+
+// clientConfig the command line of the client subcommand.
+type clientConfig struct {
+	network     string        // the network to connect over
+	target      string        // the address of the server
+	connections int           // the connections opened at once
+	requests    int           // the requests sent on each connection
+	message     string        // the request sent, without its newline
+	timeout     time.Duration // how long to wait to connect and for each echo
+}
+
+// clientResult the outcome of one client connection.
+type clientResult struct {
+	connInfo  connectionInfo
+	latencies []time.Duration // how long each request took to be echoed
+	err       error           // why the connection failed, nil if every request was echoed
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    runClient
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func runClient(args []string)
+--      args:		the command line after the client subcommand.
+--
+-- RETURNS:     void, exits with status 1 if any connection failed.
+------------------------------------------------------------------------------*/
+func runClient(args []string) {
+	var cfg clientConfig
+	flags := flag.NewFlagSet(clientCommand, flag.ExitOnError)
+	flags.StringVar(&cfg.network, "network", "tcp", "network to connect over: tcp, tcp4 or tcp6")
+	flags.IntVar(&cfg.connections, "connections", 10, "connections to open at once")
+	flags.IntVar(&cfg.requests, "requests", 100, "requests to send on each connection")
+	flags.StringVar(&cfg.message, "message", "ping", "the request sent, a newline is added")
+	flags.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "how long to wait to connect and for each echo")
+	flags.Parse(args)
+
+	fail := func(message string) {
+		fmt.Fprintln(flags.Output(), message)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if flags.NArg() != 1 {
+		fail("The client needs the address of the server to connect to")
+	}
+	cfg.target = flags.Arg(0)
+	if cfg.connections < 1 {
+		fail("-connections must be at least 1")
+	}
+	if cfg.requests < 1 {
+		fail("-requests must be at least 1")
+	}
+	if cfg.message == "" || strings.Contains(cfg.message, "\n") {
+		fail("-message must be a single line that isn't empty")
+	}
+	if cfg.timeout <= 0 {
+		fail("-timeout must be positive")
+	}
+
+	results := make([]clientResult, cfg.connections)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func(result *clientResult) {
+			defer wg.Done()
+			result.connInfo, result.latencies, result.err = runClientConnection(cfg)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	printClientResults(os.Stdout, results, time.Since(start))
+	for _, result := range results {
+		if result.err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    runClientConnection
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func runClientConnection(cfg clientConfig) (connInfo connectionInfo, latencies []time.Duration, err error)
+--       cfg:		the client's command line.
+--
+-- RETURNS:     connectionInfo the accounting of the connection, HostName is
+--              the server.
+--              []time.Duration the latency of each request that was echoed.
+--              error why the connection failed, nil if every request was
+--              echoed.
+------------------------------------------------------------------------------*/
+func runClientConnection(cfg clientConfig) (connInfo connectionInfo, latencies []time.Duration, err error) {
+	connInfo = connectionInfo{HostName: cfg.target, StartTime: time.Now()}
+	defer func() {
+		connInfo.EndTime = time.Now()
+		connInfo.Duration = connInfo.EndTime.Sub(connInfo.StartTime)
+		connInfo.AmmountOfData = connInfo.BytesReceived + connInfo.BytesSent
+	}()
+
+	conn, err := net.DialTimeout(cfg.network, cfg.target, cfg.timeout)
+	if err != nil {
+		return connInfo, nil, err
+	}
+	defer conn.Close()
+	connInfo.LocalAddr = conn.LocalAddr().String()
+
+	request := cfg.message + "\n"
+	reader := bufio.NewReader(conn)
+	latencies = make([]time.Duration, 0, cfg.requests)
+	for i := 0; i < cfg.requests; i++ {
+		conn.SetDeadline(time.Now().Add(cfg.timeout))
+		sent := time.Now()
+		n, err := io.WriteString(conn, request)
+		connInfo.BytesSent += n
+		if err != nil {
+			return connInfo, latencies, err
+		}
+		echo, err := reader.ReadString('\n')
+		connInfo.BytesReceived += len(echo)
+		if err != nil {
+			return connInfo, latencies, err
+		}
+		if echo != request {
+			return connInfo, latencies, errEchoMismatch
+		}
+		latencies = append(latencies, time.Since(sent))
+		connInfo.NumberOfRequests++
+	}
+
+	return connInfo, latencies, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    printClientResults
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func printClientResults(out io.Writer, results []clientResult, elapsed time.Duration)
+--       out:		where the results are printed.
+--   results:		the outcome of every connection.
+--   elapsed:		how long the whole run took.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Failed connections are logged. Their echoed requests still
+--						count towards the latencies and throughput.
+------------------------------------------------------------------------------*/
+func printClientResults(out io.Writer, results []clientResult, elapsed time.Duration) {
+	var latencies []time.Duration
+	var succeeded, requests, bytes int
+	for _, result := range results {
+		if result.err != nil {
+			log.Println("Connection to", result.connInfo.HostName, "from", result.connInfo.LocalAddr, "failed after", result.connInfo.NumberOfRequests, "requests:", result.err)
+		} else {
+			succeeded++
+		}
+		latencies = append(latencies, result.latencies...)
+		requests += result.connInfo.NumberOfRequests
+		bytes += result.connInfo.AmmountOfData
+	}
+
+	fmt.Fprintf(out, "Connections: %d succeeded, %d failed\n", succeeded, len(results)-succeeded)
+	fmt.Fprintf(out, "Requests: %d in %s\n", requests, elapsed)
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Fprintf(out, "Latency: p50 %s, p95 %s, p99 %s, max %s\n", durationPercentile(latencies, 0.5),
+			durationPercentile(latencies, 0.95), durationPercentile(latencies, 0.99), latencies[len(latencies)-1])
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(out, "Throughput: %.1f requests/s, %.1f bytes/s\n", float64(requests)/seconds, float64(bytes)/seconds)
+	}
+}
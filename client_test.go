@@ -0,0 +1,84 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 client_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestClientAgainstServer(t *testing.T)
+--  func TestClientConnectionRefused(t *testing.T)
+--
+-- NOTES: Tests for the load testing client. The client is run against the
+--        server's workers and observer running in the test process. As the
+--        client exits when a connection fails, it runs in a child test
+--        process.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientAgainstServer checks that the client subcommand reports every
+// connection as succeeding against an in-process server, with all of their
+// requests counted.
+func TestClientAgainstServer(t *testing.T) {
+	if testChildReport() != "" {
+		srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+			delimiter: '\n', freeServerMinimum: 2})
+		srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("listen:", err)
+		}
+		// the listener is left open, closing it would shut the observer down
+		srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+		for i := 0; i < 4; i++ {
+			*srvInfo.availableServers++
+			spawnWorker(srvInfo)
+		}
+		go observerLoop(srvInfo, nil)
+
+		runClient([]string{"-connections", "5", "-requests", "10", listener.Addr().String()})
+		os.Exit(0)
+	}
+
+	output, status, _ := runTestChild(t, "TestClientAgainstServer")
+	if status != 0 {
+		t.Fatalf("the client exited with %d, want 0, output %q", status, output)
+	}
+	for _, want := range []string{"Connections: 5 succeeded, 0 failed\n", "Requests: 50 in ", "Latency: p50 ", "Throughput: "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("the client's output %q is missing %q", output, want)
+		}
+	}
+}
+
+// TestClientConnectionRefused checks that a connection the server refuses is
+// reported as failed without any requests.
+func TestClientConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	target := listener.Addr().String()
+	listener.Close()
+
+	connInfo, latencies, err := runClientConnection(clientConfig{network: "tcp", target: target,
+		requests: 3, message: "ping", timeout: 5 * time.Second})
+	if err == nil || connInfo.NumberOfRequests != 0 || len(latencies) != 0 {
+		t.Errorf("the refused connection made %d requests and returned %v, want none and an error",
+			connInfo.NumberOfRequests, err)
+	}
+}
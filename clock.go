@@ -0,0 +1,423 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 clock.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func (realClock) Now() time.Time
+--  func (realClock) Sleep(d time.Duration)
+--  func (realClock) After(d time.Duration) <-chan time.Time
+--  func (realClock) NewTicker(d time.Duration) clockTicker
+--  func (ticker realTicker) Chan() <-chan time.Time
+--  func newFakeClock(now time.Time) *fakeClock
+--  func (clock *fakeClock) Now() time.Time
+--  func (clock *fakeClock) Sleep(d time.Duration)
+--  func (clock *fakeClock) After(d time.Duration) <-chan time.Time
+--  func (clock *fakeClock) NewTicker(d time.Duration) clockTicker
+--  func (clock *fakeClock) wait(d time.Duration, period time.Duration) *fakeTicker
+--  func (clock *fakeClock) Advance(d time.Duration)
+--  func (clock *fakeClock) fire()
+--  func (ticker *fakeTicker) Chan() <-chan time.Time
+--  func (ticker *fakeTicker) Stop()
+--
+-- NOTES: This file holds the clock the server reads the time from. The server
+--        uses realClock, fakeClock only moves when it is advanced so that
+--        windows, timeouts and rates can be driven without waiting on them.
+--        Socket deadlines are left on the real clock since the kernel
+--        enforces them.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock the source of the time for everything the server measures or waits on.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) clockTicker
+}
+
+// clockTicker a ticker created by a Clock.
+type clockTicker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// realClock the Clock backed by the time package.
+type realClock struct{}
+
+// realTicker a time.Ticker.
+type realTicker struct {
+	*time.Ticker
+}
+
+// fakeClock a Clock that only moves when Advance is called.
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*fakeTicker
+}
+
+// fakeTicker a timer or ticker waiting on a fakeClock.
+type fakeTicker struct {
+	clock  *fakeClock
+	when   time.Time     // when the ticker next fires
+	period time.Duration // the time between ticks, 0 for a timer that fires once
+	c      chan time.Time
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Now
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (realClock) Now() time.Time
+--
+-- RETURNS:     time.Time the current time.
+------------------------------------------------------------------------------*/
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Sleep
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (realClock) Sleep(d time.Duration)
+--         d:		how long to sleep.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    After
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (realClock) After(d time.Duration) <-chan time.Time
+--         d:		how long to wait.
+--
+-- RETURNS:     <-chan time.Time receives the time once d has passed.
+------------------------------------------------------------------------------*/
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    NewTicker
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (realClock) NewTicker(d time.Duration) clockTicker
+--         d:		the time between ticks.
+--
+-- RETURNS:     clockTicker a running time.Ticker.
+------------------------------------------------------------------------------*/
+func (realClock) NewTicker(d time.Duration) clockTicker {
+	return realTicker{time.NewTicker(d)}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Chan
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (ticker realTicker) Chan() <-chan time.Time
+--
+-- RETURNS:     <-chan time.Time receives the time of each tick.
+------------------------------------------------------------------------------*/
+func (ticker realTicker) Chan() <-chan time.Time {
+	return ticker.C
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newFakeClock
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newFakeClock(now time.Time) *fakeClock
+--       now:		the time the clock starts at.
+--
+-- RETURNS:     *fakeClock a clock stopped at now.
+------------------------------------------------------------------------------*/
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Now
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) Now() time.Time
+--
+-- RETURNS:     time.Time the time the clock has been advanced to.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Sleep
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) Sleep(d time.Duration)
+--         d:		how long to sleep.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Blocks until the clock is advanced by at least d.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) Sleep(d time.Duration) {
+	<-clock.After(d)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    After
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) After(d time.Duration) <-chan time.Time
+--         d:		how long to wait.
+--
+-- RETURNS:     <-chan time.Time receives the clock's time once it has been
+--              advanced by at least d.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	return clock.wait(d, 0).c
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    NewTicker
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) NewTicker(d time.Duration) clockTicker
+--         d:		the time between ticks.
+--
+-- RETURNS:     clockTicker a ticker that ticks as the clock is advanced.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) NewTicker(d time.Duration) clockTicker {
+	return clock.wait(d, d)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    wait
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) wait(d time.Duration, period time.Duration) *fakeTicker
+--         d:		how long until the first tick.
+--    period:		the time between later ticks, 0 for none.
+--
+-- RETURNS:     *fakeTicker the waiter added to the clock.
+--
+-- NOTES:			A waiter that is already due fires straight away.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) wait(d time.Duration, period time.Duration) *fakeTicker {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	ticker := &fakeTicker{clock: clock, when: clock.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	clock.waiters = append(clock.waiters, ticker)
+	clock.fire()
+
+	return ticker
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Advance
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) Advance(d time.Duration)
+--         d:		how far to move the clock forward.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Fires every timer and ticker that is due. Like time.Ticker a
+--						ticker that isn't read drops the ticks it missed.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+	clock.fire()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    fire
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (clock *fakeClock) fire()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Must be called with the clock's mutex held.
+------------------------------------------------------------------------------*/
+func (clock *fakeClock) fire() {
+	waiting := clock.waiters[:0]
+	for _, ticker := range clock.waiters {
+		if ticker.when.After(clock.now) {
+			waiting = append(waiting, ticker)
+			continue
+		}
+		select {
+		case ticker.c <- clock.now:
+		default:
+		}
+		if ticker.period > 0 {
+			for !ticker.when.After(clock.now) {
+				ticker.when = ticker.when.Add(ticker.period)
+			}
+			waiting = append(waiting, ticker)
+		}
+	}
+	clock.waiters = waiting
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Chan
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (ticker *fakeTicker) Chan() <-chan time.Time
+--
+-- RETURNS:     <-chan time.Time receives the clock's time on each tick.
+------------------------------------------------------------------------------*/
+func (ticker *fakeTicker) Chan() <-chan time.Time {
+	return ticker.c
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Stop
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (ticker *fakeTicker) Stop()
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (ticker *fakeTicker) Stop() {
+	clock := ticker.clock
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	for i, waiter := range clock.waiters {
+		if waiter == ticker {
+			clock.waiters = append(clock.waiters[:i], clock.waiters[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 clock_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func waitForWaiters(clock *fakeClock, waiters int)
+--  func TestFakeClockAfter(t *testing.T)
+--  func TestFakeClockTicker(t *testing.T)
+--  func TestIdleTimeoutOnFakeClock(t *testing.T)
+--
+-- NOTES: Tests for the fakeClock the other tests drive time with, and for an
+--        idle timeout measured on it.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitForWaiters returns once at least waiters timers, tickers or sleeps are
+// waiting on the clock, for a test to know another go routine has got as far
+// as waiting before it advances the clock.
+func waitForWaiters(clock *fakeClock, waiters int) {
+	for {
+		clock.mutex.Lock()
+		waiting := len(clock.waiters)
+		clock.mutex.Unlock()
+		if waiting >= waiters {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// TestFakeClockAfter checks that a wait only ends once the clock has been
+// advanced past it, with the time it was advanced to.
+func TestFakeClockAfter(t *testing.T) {
+	start := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	after := clock.After(time.Minute)
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-after:
+		t.Fatal("the wait ended a second early")
+	default:
+	}
+	clock.Advance(time.Second)
+	select {
+	case now := <-after:
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Errorf("the wait ended at %s, want %s", now, start.Add(time.Minute))
+		}
+	default:
+		t.Fatal("the wait didn't end once the clock reached it")
+	}
+
+	if now := <-clock.After(0); !now.Equal(clock.Now()) {
+		t.Errorf("a wait of 0 ended at %s, want straight away", now)
+	}
+}
+
+// TestFakeClockTicker checks that a ticker ticks once per advance that passes
+// a tick, and not at all once stopped.
+func TestFakeClockTicker(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+	ticker := clock.NewTicker(time.Second)
+
+	ticks := 0
+	for i := 0; i < 10; i++ {
+		clock.Advance(500 * time.Millisecond)
+		select {
+		case <-ticker.Chan():
+			ticks++
+		default:
+		}
+	}
+	if ticks != 5 {
+		t.Errorf("%d ticks in 5 seconds, want 5", ticks)
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	select {
+	case <-ticker.Chan():
+		t.Error("a stopped ticker ticked")
+	default:
+	}
+}
+
+// TestIdleTimeoutOnFakeClock checks that a client that sends nothing is
+// closed for being idle, with its duration measured on the server's clock
+// rather than the time the socket deadline took.
+func TestIdleTimeoutOnFakeClock(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', idleTimeout: time.Millisecond})
+	_, served := serveTestConnection(t, srvInfo)
+
+	if connInfo := <-served; connInfo.CloseReason != closeReasonIdle || connInfo.Duration != 0 {
+		t.Errorf("closed with %q after %s, want %q after 0s on the fake clock",
+			connInfo.CloseReason, connInfo.Duration, closeReasonIdle)
+	}
+}
@@ -0,0 +1,156 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 closebatch.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newCloseBatcher(size int) *closeBatcher
+--  func (batcher *closeBatcher) add(connInfo connectionInfo) []connectionInfo
+--  func (batcher *closeBatcher) take() []connectionInfo
+--  func batchFilled(batch []connectionInfo) time.Time
+--
+-- NOTES: This file collects finished connections from the workers so they can
+--        be sent to the observer several at a time, reducing the traffic on
+--        the observer's channels when connections are short lived.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// closeBatchFlush how often the observer collects a batch that hasn't filled.
+const closeBatchFlush = time.Second
+
+// closeBatcher the finished connections not yet sent to the observer.
+type closeBatcher struct {
+	mutex   sync.Mutex
+	size    int
+	pending []connectionInfo
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newCloseBatcher
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newCloseBatcher(size int) *closeBatcher
+--      size:		how many connections are sent to the observer at once.
+--
+-- RETURNS:     *closeBatcher an empty batcher, nil if size is 1 or less.
+------------------------------------------------------------------------------*/
+func newCloseBatcher(size int) *closeBatcher {
+	if size <= 1 {
+		return nil
+	}
+
+	return &closeBatcher{size: size, pending: make([]connectionInfo, 0, size)}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    add
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (batcher *closeBatcher) add(connInfo connectionInfo) []connectionInfo
+--  connInfo:		a connection a worker has finished with.
+--
+-- RETURNS:     []connectionInfo the batch to send to the observer once it is
+--              full, otherwise nil.
+------------------------------------------------------------------------------*/
+func (batcher *closeBatcher) add(connInfo connectionInfo) []connectionInfo {
+	batcher.mutex.Lock()
+	defer batcher.mutex.Unlock()
+
+	batcher.pending = append(batcher.pending, connInfo)
+	if len(batcher.pending) < batcher.size {
+		return nil
+	}
+	batch := batcher.pending
+	batcher.pending = make([]connectionInfo, 0, batcher.size)
+
+	return batch
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    take
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (batcher *closeBatcher) take() []connectionInfo
+--
+-- RETURNS:     []connectionInfo the connections waiting in a batch that isn't
+--              full yet.
+--
+-- NOTES:			Called by the observer so that connections aren't left waiting
+--						when few are closing, and before the report is written. A nil
+--						batcher has nothing waiting.
+------------------------------------------------------------------------------*/
+func (batcher *closeBatcher) take() []connectionInfo {
+	if batcher == nil {
+		return nil
+	}
+	batcher.mutex.Lock()
+	defer batcher.mutex.Unlock()
+
+	batch := batcher.pending
+	batcher.pending = make([]connectionInfo, 0, batcher.size)
+
+	return batch
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    batchFilled
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func batchFilled(batch []connectionInfo) time.Time
+--     batch:		a full batch a worker sent to the observer.
+--
+-- RETURNS:     time.Time when the last connection in the batch closed.
+--
+-- NOTES:			A worker sends a batch as soon as the connection it finished
+--						fills it, so this is when the batch was handed to the observer.
+------------------------------------------------------------------------------*/
+func batchFilled(batch []connectionInfo) time.Time {
+	var filled time.Time
+	for _, connInfo := range batch {
+		if connInfo.EndTime.After(filled) {
+			filled = connInfo.EndTime
+		}
+	}
+
+	return filled
+}
@@ -0,0 +1,134 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 closebatch_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func sendClosedConnections(srvInfo serverInfo, connections int) int
+--  func TestBatchedClosesRecorded(t *testing.T)
+--  func TestBatchedWorkerAvailable(t *testing.T)
+--  func BenchmarkSendClosed(b *testing.B)
+--
+-- NOTES: Tests for sending finished connections to the observer in batches.
+--        The test reads the observer's channels itself, standing in for the
+--        observer's go routine.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// sendClosedConnections sends connections finished connections as workers
+// would, returning how many channel sends it took to reach the observer.
+func sendClosedConnections(srvInfo serverInfo, connections int) int {
+	sends := make(chan int)
+	go func() {
+		count := 0
+		for received := 0; received < connections; count++ {
+			select {
+			case <-srvInfo.connectInfo:
+				received++
+			case batch := <-srvInfo.connectInfoBatch:
+				received += len(batch)
+			}
+		}
+		sends <- count
+	}()
+	for i := 0; i < connections; i++ {
+		sendClosed(srvInfo, connectionInfo{HostName: "192.0.2.1:40000"})
+	}
+	if batch := srvInfo.closeBatch.take(); len(batch) > 0 {
+		srvInfo.connectInfoBatch <- batch
+	}
+
+	return <-sends
+}
+
+// TestBatchedClosesRecorded checks that every connection sent in a batch, and
+// those left in a batch that didn't fill, is recorded by the observer.
+func TestBatchedClosesRecorded(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{})
+	srvInfo.closeBatch = newCloseBatcher(4)
+	srvInfo.connectInfoBatch = make(chan []connectionInfo, 3)
+	stats := newServerStats("")
+	for i := 0; i < 10; i++ {
+		stats.connectionOpened()
+		sendClosed(srvInfo, connectionInfo{HostName: fmt.Sprintf("192.0.2.1:%d", 40000+i)})
+	}
+
+	if len(srvInfo.connectInfoBatch) != 2 {
+		t.Fatalf("%d batches sent for 10 connections, want 2", len(srvInfo.connectInfoBatch))
+	}
+	for len(srvInfo.connectInfoBatch) > 0 {
+		batch := <-srvInfo.connectInfoBatch
+		connectionsClosed(srvInfo, stats, batchFilled(batch), batch...)
+	}
+	connectionsClosed(srvInfo, stats, srvInfo.clock.Now(), srvInfo.closeBatch.take()...)
+
+	if summary := stats.summary(); summary.TotalConnections != 10 || stats.connectionsMade.Len() != 10 {
+		t.Errorf("%d connections recorded and %d listed, want 10", summary.TotalConnections, stats.connectionsMade.Len())
+	}
+	for i, e := 0, stats.connectionsMade.Front(); e != nil; i, e = i+1, e.Next() {
+		if want := fmt.Sprintf("192.0.2.1:%d", 40000+i); e.Value.(connectionInfo).HostName != want {
+			t.Errorf("connection %d was %s, want %s", i, e.Value.(connectionInfo).HostName, want)
+		}
+	}
+}
+
+// TestBatchedWorkerAvailable checks that a worker whose connection is waiting
+// in a batch is available again straight away, and that the wait for the batch
+// to be flushed doesn't count as observer lag.
+func TestBatchedWorkerAvailable(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, maxObserverLag: time.Second})
+	srvInfo.closeBatch = newCloseBatcher(4)
+	srvInfo.serverConnection = make(chan int, 3)
+	clock := srvInfo.clock.(*fakeClock)
+	stats := newServerStats("")
+	for i := 0; i < 3; i++ {
+		stats.connectionOpened()
+		sendClosed(srvInfo, connectionInfo{HostName: fmt.Sprintf("192.0.2.1:%d", 40000+i), EndTime: clock.Now()})
+	}
+
+	for len(srvInfo.serverConnection) > 0 {
+		if event := <-srvInfo.serverConnection; event != finishedConnectionConst {
+			t.Fatalf("a batching worker sent %d, want finishedConnectionConst", event)
+		}
+		finishedConnection(srvInfo)
+	}
+	if *srvInfo.availableServers != 3 {
+		t.Errorf("%d workers available before their batch was flushed, want 3", *srvInfo.availableServers)
+	}
+	clock.Advance(closeBatchFlush + time.Second)
+	connectionsClosed(srvInfo, stats, clock.Now(), srvInfo.closeBatch.take()...)
+
+	if summary := stats.summary(); summary.DroppedConnections != 0 || summary.MaxObserverLag != 0 {
+		t.Errorf("%d connections dropped with a %s lag after the flush, want none", summary.DroppedConnections, summary.MaxObserverLag)
+	}
+	if *srvInfo.availableServers != 3 {
+		t.Errorf("%d workers available after the flush, want 3", *srvInfo.availableServers)
+	}
+}
+
+// BenchmarkSendClosed reports the channel sends each finished connection takes
+// to reach the observer, alone and in batches.
+func BenchmarkSendClosed(b *testing.B) {
+	for _, size := range []int{1, 16} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			srvInfo := newTestServerInfo(serverConfig{})
+			srvInfo.closeBatch = newCloseBatcher(size)
+			srvInfo.connectInfoBatch = make(chan []connectionInfo)
+			sends := sendClosedConnections(srvInfo, b.N)
+			b.ReportMetric(float64(sends)/float64(b.N), "sends/conn")
+		})
+	}
+}
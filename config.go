@@ -0,0 +1,563 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 config.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func parseConfig() serverConfig
+--  func usageFatal(message string)
+--
+-- NOTES: This file reads the command line into the configuration used by the
+--        rest of the server.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type serverConfig struct {
+	address                 string        // the address the server listens on
+	network                 string        // tcp, tcp4, tcp6 or unix, what the address is
+	mode                    string        // thread or epoll, how connections are served
+	proto                   string        // tcp or udp, whether streams or datagrams are echoed
+	framing                 string        // how requests are delimited on the wire
+	maxLine                 int           // the largest request payload accepted, 0 for no limit
+	echoPrefix              string        // written before each echoed payload
+	echoSuffix              string        // written after each echoed payload
+	retention               time.Duration // how long finished connections are listed in detail, 0 for ever
+	dedupWindow             time.Duration // how long requests are remembered to detect retries, 0 for never
+	dedupSize               int           // the most requests remembered per connection
+	maxAcceptErrors         int64         // accept errors tolerated before shutting down, 0 for no limit
+	responseChunks          int           // how many writes each response is split across
+	chunkDelay              time.Duration // the pause between the writes of a chunked response
+	tlsCert                 string        // the certificate for TLS, TLS is off if empty
+	tlsKey                  string        // the private key for tlsCert
+	throughputSample        time.Duration // how often the bytes transferred are sampled, 0 for never
+	trace                   string        // the file a runtime trace is written to, empty for none
+	coalesceWindow          time.Duration // how long to wait for more requests to answer in one write, 0 to answer each request alone
+	workerFloor             int           // workers kept waiting on Accept in the lazy pool, 0 for the pre-spawned pool
+	workerCap               int           // the most workers in the lazy pool, 0 for no limit
+	countReads              bool          // count the reads that return data on each connection
+	delay                   time.Duration // the fixed or mean response delay, the most for uniform delays
+	delayMin                time.Duration // the least delay for uniform delays
+	delayDist               string        // the distribution delays are drawn from
+	seed                    int64         // the seed for the shared random number generator, 0 to pick one
+	workerSpawnRate         float64       // the most workers spawned per second for new connections, 0 for no limit
+	shutdownTimeout         time.Duration // how long to wait for connections to finish after a signal, 0 to exit straight away
+	ack                     bool          // answer each request with an ACK instead of echoing it
+	maxConnectionsPerSecond float64       // the most connections admitted each second, 0 for no cap
+	connQueueSize           int           // the most connections waiting on the admission cap
+	maxConnections          int           // the most connections served at once, 0 for no cap
+	busyMessage             string        // sent to connections turned away by -max-connections, empty for none
+	synLatency              bool          // estimate the SYN to accept latency of each connection
+	maxBytesPerConn         int           // close a connection once it has received this many bytes, 0 for no limit
+	reportFormat            string        // the name of the ReportFormatter the report is written with
+	logLevel                string        // the least severe level logged: debug, info, warn or error
+	logFormat               string        // how log lines are written: text or json
+	closeBatch              int           // how many finished connections are sent to the observer at once
+	probeAddr               string        // the address the health probe is served on, empty for none
+	probeTimeout            time.Duration // how long the health probe waits for its echo
+	drainOrder              string        // whether the oldest or newest connections are closed first when a drain ends
+	nonce                   bool          // add the server time and a sequence number to each echo
+	refuseRate              float64       // the chance each connection is closed as soon as it is accepted
+	maxInflight             int           // the most requests read but not answered on a connection, 0 for no limit
+	reportCompress          bool          // gzip the report
+	reportFile              string        // the file the report is written to, "-" for stdout, empty to name it after the time
+	acceptYield             int           // accepts between each worker yielding the processor, 0 to never yield
+	debugTee                bool          // copy each echoed payload to stderr
+	debugTeeLength          int           // the most bytes of each payload copied by the debug tee, 0 for all
+	controlAddr             string        // the address the control endpoint is served on, empty for none
+	peerCred                bool          // record the credentials of the process on the other end of a Unix socket
+	connectionLabels        bool          // read labels from the first request of each connection
+	reportGroupBy           string        // the label the report totals connections by
+	reportFilter            string        // key=value, only connections with this label are listed in the report
+	selfCheck               bool          // check each echo matches its request before it is sent
+	acceptDrain             time.Duration // how long to keep accepting queued connections after a signal
+	otlpEndpoint            string        // the OTLP/HTTP metrics URL to push to, empty for none
+	otlpInterval            time.Duration // how often metrics are pushed to -otlp-endpoint
+	tfo                     bool          // enable TCP Fast Open on the listener
+	reportInterval          time.Duration // how often a report is written while the server runs, 0 for only on exit
+	snapshotInterval        time.Duration // how often a line of running totals is written, 0 for never
+	snapshotFile            string        // the file the snapshot lines are written to, empty for stdout
+	reportMaxSize           int64         // rotate the -snapshot-file once it would grow past this many bytes, 0 for never
+	reportKeep              int           // the number of rotated -snapshot-files kept
+	resetOnReport           bool          // clear the statistics after each periodic report
+	adminAddr               string        // the address the admin protocol is served on, empty for none
+	authToken               string        // the token admin clients must send before any command
+	tlsStats                bool          // record the negotiated TLS version and cipher suite of each connection
+	minRequest              int           // the smallest request payload accepted, 0 for no limit
+	minRequestReply         string        // the reply sent before closing a connection for an undersized request
+	startingClients         int           // the workers spawned when the server starts
+	freeServerMinimum       int           // spawn a worker for each connection once fewer than this are waiting on Accept
+	handshakeTimeout        time.Duration // how long a TLS client has to finish its handshake, 0 for no limit
+	holdOpen                time.Duration // how long to keep a connection open after the client finishes, 0 to close straight away
+	fairSlots               int           // the most requests processed at once when sharing by -fair-label, 0 for no limit
+	fairLabel               string        // the label whose values share the -fair-slots
+	fairWeights             tagWeights    // the weight of each -fair-label value
+	failAfter               int           // reset a connection once it has transferred this many bytes, 0 for never
+	idleTimeout             time.Duration // how long a client has to send each request or take each response, 0 for no limit
+	keepAlive               bool          // send TCP keep-alive probes on accepted connections
+	keepAlivePeriod         time.Duration // the time between keep-alive probes, 0 for the default
+	allowCIDRs              cidrList      // the only networks clients are served from, empty for any
+	denyCIDRs               cidrList      // networks clients are never served from
+	rateLimit               float64       // the most connections each client IP can open a second, 0 for no limit
+	rateLimitMessage        string        // sent to connections over -rate-limit, empty for none
+	proxyProtocol           bool          // read a PROXY protocol v1 header at the start of each connection
+	proxyHeaderTimeout      time.Duration // how long a connection has to send its PROXY protocol header
+	liveAddr                string        // the address the liveness check is served on, empty for none
+	readyAddr               string        // the address the readiness check is served on, empty for none
+	closeMessage            string        // sent to connections turned away once -accept-drain ends, empty for none
+	latencyHDR              string        // the file the request latency histogram is written to, empty for none
+	metricsAddr             string        // the address Prometheus metrics are served on, empty for none
+	delimiter               byte          // the byte each request ends with in line framing
+	maxObserverLag          time.Duration // drop connection statistics while the observer is this far behind, 0 for never
+	summaryFD               int           // the inherited file descriptor the exit summary is written to, 0 for none
+	pipeline                pipeline      // the stages each request goes through before it is echoed, nil for none
+	stuckThreshold          time.Duration // how long a request can go without progress before the stacks are dumped, 0 for never
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parseConfig
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the address, starting workers and free worker minimum are flags
+--              October 15, 2026 - added -delimiter
+--              October 15, 2026 - added -pipeline
+--              October 15, 2026 - added -network
+--              October 15, 2026 - added -mode
+--              October 15, 2026 - added -proto
+--              October 15, 2026 - added -report-file
+--              October 15, 2026 - added -snapshot-interval and -snapshot-file
+--              October 15, 2026 - added -keepalive and -keepalive-period
+--              October 15, 2026 - added -log-level and -log-format
+--              October 15, 2026 - added -allow-cidr and -deny-cidr
+--              October 15, 2026 - added -rate-limit and -rate-limit-message
+--              October 15, 2026 - added -proxy-protocol
+--              October 15, 2026 - added -proxy-header-timeout
+--              October 15, 2026 - checks -worker-floor and -worker-cap
+--              October 15, 2026 - rejects -max-inflight without -coalesce-window
+--              October 15, 2026 - added -report-max-size and -report-keep
+--              October 15, 2026 - every flag error exits through usageFatal, -fair-weights is read here
+--              October 15, 2026 - rejects a -dedup-size under 1 with -dedup-window
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parseConfig() serverConfig
+--
+-- RETURNS:     serverConfig the configuration given on the command line
+--
+-- NOTES:			Exits the program if the command line is not valid.
+------------------------------------------------------------------------------*/
+func parseConfig() serverConfig {
+	var cfg serverConfig
+
+	flag.StringVar(&cfg.framing, "framing", framingLine, "request framing: line, header or length")
+	delimiter := flag.String("delimiter", `\n`, "the byte each request ends with in line framing, a literal byte or an escape such as \\r or \\0")
+	flag.IntVar(&cfg.maxLine, "max-line", defaultMaxLine, "maximum request payload in bytes, larger requests close the connection (0 for no limit)")
+	flag.StringVar(&cfg.echoPrefix, "echo-prefix", "", "text written before each echoed payload")
+	flag.StringVar(&cfg.echoSuffix, "echo-suffix", "", "text written after each echoed payload")
+	flag.DurationVar(&cfg.retention, "retention", 0, "how long finished connections are kept in detail (0 keeps them all)")
+	flag.DurationVar(&cfg.dedupWindow, "dedup-window", 0, "answer requests repeated within this window with "+dedupMarker+" (0 disables)")
+	flag.IntVar(&cfg.dedupSize, "dedup-size", 16, "the most requests remembered per connection for -dedup-window")
+	flag.Int64Var(&cfg.maxAcceptErrors, "max-accept-errors", 0, "shut down with a failure once this many accept errors occur (0 for no limit)")
+	flag.IntVar(&cfg.responseChunks, "response-chunks", 1, "split each response across this many writes")
+	flag.DurationVar(&cfg.chunkDelay, "chunk-delay", 0, "pause between the writes of a chunked response")
+	flag.StringVar(&cfg.tlsCert, "tls-cert", "", "PEM certificate file, enables TLS with -tls-key")
+	flag.StringVar(&cfg.tlsKey, "tls-key", "", "PEM private key file for -tls-cert")
+	flag.DurationVar(&cfg.throughputSample, "throughput-sample", 0, "sample the bytes transferred at this interval for the report (0 disables)")
+	flag.StringVar(&cfg.trace, "trace", "", "write a runtime trace to this file")
+	flag.DurationVar(&cfg.coalesceWindow, "coalesce-window", 0, "answer requests arriving within this window in a single write (0 disables)")
+	flag.IntVar(&cfg.workerFloor, "worker-floor", 0, "use a lazy worker pool keeping this many workers waiting to accept (0 uses the pre-spawned pool)")
+	flag.IntVar(&cfg.workerCap, "worker-cap", 0, "the most workers the lazy pool will grow to (0 for no limit)")
+	flag.BoolVar(&cfg.countReads, "count-reads", false, "record how many reads returned data on each connection")
+	flag.DurationVar(&cfg.delay, "delay", 0, "delay each response, the mean for exponential and the maximum for uniform delays (0 disables)")
+	flag.DurationVar(&cfg.delayMin, "delay-min", 0, "the least delay for -delay-dist=uniform")
+	flag.StringVar(&cfg.delayDist, "delay-dist", delayFixed, "response delay distribution: fixed, uniform or exponential")
+	flag.Int64Var(&cfg.seed, "seed", 0, "seed for random behaviour so runs can be reproduced (0 picks one)")
+	flag.Float64Var(&cfg.workerSpawnRate, "worker-spawn-rate", 0, "the most workers spawned per second as connections arrive (0 for no limit)")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "on a signal wait this long for connections to finish, a second signal exits straight away (0 exits straight away)")
+	flag.BoolVar(&cfg.ack, "ack", false, "answer each request with \"ACK <n>\" instead of echoing it, and oversized requests with \"NACK <n>\"")
+	flag.Float64Var(&cfg.maxConnectionsPerSecond, "max-connections-per-second", 0, "admit at most this many connections each second across the server (0 for no cap)")
+	flag.IntVar(&cfg.connQueueSize, "conn-queue-size", 0, "connections allowed to wait for -max-connections-per-second, the rest are rejected")
+	flag.IntVar(&cfg.maxConnections, "max-connections", 0, "serve at most this many connections at once, closing the rest as soon as they are accepted (0 for no cap)")
+	flag.StringVar(&cfg.busyMessage, "busy-message", defaultBusyMessage, "the message sent, in the active framing, to connections turned away by -max-connections (empty for none)")
+	flag.BoolVar(&cfg.synLatency, "syn-latency", false, "estimate how long each connection waited between its SYN and Accept (Linux only)")
+	flag.IntVar(&cfg.maxBytesPerConn, "max-bytes-per-conn", 0, "close a connection once this many bytes have been received on it (0 for no limit)")
+	flag.StringVar(&cfg.reportFormat, "report-format", "xlsx", "the format of the report written on exit: xlsx, text, json or csv")
+	flag.StringVar(&cfg.logLevel, "log-level", logLevelInfo, "the least severe messages logged: debug, info, warn or error")
+	flag.StringVar(&cfg.logFormat, "log-format", logFormatText, "how log lines are written: text or json")
+	flag.IntVar(&cfg.closeBatch, "close-batch", 1, "send finished connections to the observer in batches of this many")
+	flag.StringVar(&cfg.probeAddr, "probe-addr", "", "serve a health check on this address that round trips a request through the echo listener")
+	flag.DurationVar(&cfg.probeTimeout, "probe-timeout", 2*time.Second, "how long the health probe waits for its echo before reporting unhealthy")
+	flag.StringVar(&cfg.drainOrder, "drain-order", drainFIFO, "close the oldest (fifo) or newest (lifo) connections first when a drain is cut short")
+	flag.BoolVar(&cfg.nonce, "nonce", false, "add the server time in unix nanoseconds and a sequence number to each echo, for measuring clock skew")
+	flag.Float64Var(&cfg.refuseRate, "refuse-rate", 0, "the probability each connection is closed as soon as it is accepted, drawn using -seed")
+	flag.IntVar(&cfg.maxInflight, "max-inflight", 0, "the most requests on a connection read but not yet answered, needs -coalesce-window as otherwise each request is answered before the next is read (0 for no limit)")
+	flag.BoolVar(&cfg.reportCompress, "report-compress", false, "gzip the report, adding .gz to its name")
+	flag.StringVar(&cfg.reportFile, "report-file", "", "write the report to this file, replacing it, or to stdout with - (default: a file named after the time the report was written)")
+	flag.IntVar(&cfg.acceptYield, "accept-yield", 0, "have each worker yield the processor every this many accepts, trading throughput for fairness (0 never yields)")
+	flag.BoolVar(&cfg.debugTee, "debug-tee", false, "write a copy of each echoed payload to stderr")
+	flag.IntVar(&cfg.debugTeeLength, "debug-tee-length", 64, "the most bytes of each payload written by -debug-tee (0 for all)")
+	flag.StringVar(&cfg.controlAddr, "control-addr", "", "serve the control endpoint on this address, POST /workers?n=N resizes the worker pool")
+	flag.BoolVar(&cfg.peerCred, "peer-cred", false, "record the uid, gid and pid of clients connecting over a Unix socket (Linux only)")
+	flag.BoolVar(&cfg.connectionLabels, "connection-labels", false, "treat a first request of \"key=value ...\" as labels for the connection")
+	flag.StringVar(&cfg.reportGroupBy, "report-group-by", "", "total the connections in the report by this label (needs -connection-labels)")
+	flag.StringVar(&cfg.reportFilter, "report-filter", "", "only list connections with this key=value label in the report (needs -connection-labels)")
+	flag.BoolVar(&cfg.selfCheck, "self-check", false, "check each echo carries the request it answers, logging and counting mismatches")
+	flag.DurationVar(&cfg.acceptDrain, "accept-drain", 0, "on a signal keep accepting and serving queued connections for this long before closing the listener (needs -shutdown-timeout)")
+	flag.StringVar(&cfg.otlpEndpoint, "otlp-endpoint", "", "push metrics to this OTLP/HTTP collector URL, e.g. http://localhost:4318/v1/metrics")
+	flag.DurationVar(&cfg.otlpInterval, "otlp-interval", 10*time.Second, "how often to push metrics to -otlp-endpoint")
+	flag.BoolVar(&cfg.tfo, "tfo", false, "enable TCP Fast Open on the listener and record which connections used it (Linux only)")
+	flag.DurationVar(&cfg.reportInterval, "report-interval", 0, "also write a report this often while the server runs (0 only writes one on exit)")
+	flag.DurationVar(&cfg.snapshotInterval, "snapshot-interval", 0, "write a timestamped line of the current connections, totals and peak this often (0 for never)")
+	flag.StringVar(&cfg.snapshotFile, "snapshot-file", "", "write the -snapshot-interval lines to this file, replacing it, instead of stdout")
+	flag.Int64Var(&cfg.reportMaxSize, "report-max-size", 0, "rotate the -snapshot-file once it would grow past this many bytes, renaming it FILE.1 (0 never rotates)")
+	flag.IntVar(&cfg.reportKeep, "report-keep", 5, "the number of rotated -snapshot-files kept, FILE.1 being the newest")
+	flag.BoolVar(&cfg.resetOnReport, "reset-on-report", false, "clear the statistics after each -report-interval report so each report covers one interval")
+	flag.StringVar(&cfg.adminAddr, "admin-addr", "", "serve a line based admin protocol on this address (stats, pause, resume, workers N, reset, shutdown)")
+	flag.StringVar(&cfg.authToken, "auth-token", "", "require admin clients to send \"auth <token>\" before any command")
+	flag.BoolVar(&cfg.tlsStats, "tls-stats", false, "record the negotiated TLS version and cipher suite of each connection and report their distribution")
+	flag.IntVar(&cfg.minRequest, "min-request", 0, "minimum request payload in bytes, smaller requests close the connection (0 for no limit)")
+	flag.StringVar(&cfg.minRequestReply, "min-request-reply", "", "a line written to the client before closing it for a request under -min-request")
+	flag.StringVar(&cfg.address, "listen", defaultListenAddress, "the address to listen on, an address given after the flags overrides it")
+	flag.StringVar(&cfg.network, "network", "tcp", "the network to listen on: tcp, tcp4, tcp6 or unix (the address is then a socket path)")
+	flag.StringVar(&cfg.mode, "mode", modeThread, "how connections are served: thread, a go routine each, or epoll, one event loop for all of them (Linux only)")
+	flag.StringVar(&cfg.proto, "proto", protoTCP, "tcp to echo streams, or udp to echo datagrams on the matching UDP network")
+	flag.IntVar(&cfg.startingClients, "starting-clients", defaultStartingClients, "the workers spawned when the server starts, unless -worker-floor is set")
+	flag.IntVar(&cfg.freeServerMinimum, "free-server-minimum", defaultFreeServerMinimum, "spawn a worker for each connection once fewer than this many are waiting on Accept")
+	flag.DurationVar(&cfg.handshakeTimeout, "handshake-timeout", 0, "close TLS connections that haven't finished the handshake within this long (0 for no limit)")
+	flag.DurationVar(&cfg.holdOpen, "hold-open", 0, "keep the server side of a connection open and silent this long after the client half-closes it (0 closes straight away)")
+	flag.IntVar(&cfg.fairSlots, "fair-slots", 0, "process at most this many requests at once, shared between the values of -fair-label by -fair-weights (0 for no limit)")
+	flag.StringVar(&cfg.fairLabel, "fair-label", "", "the connection label whose values share -fair-slots (needs -connection-labels)")
+	fairWeights := flag.String("fair-weights", "", "the share of -fair-slots each -fair-label value gets, as value=weight,... (unlisted values have weight 1)")
+	flag.IntVar(&cfg.failAfter, "fail-after", 0, "reset a connection once it has transferred this many bytes in either direction, to test mid-transfer failures (0 for never)")
+	flag.DurationVar(&cfg.idleTimeout, "idle-timeout", 0, "close a connection that takes longer than this to send a request or read a response (0 for no limit)")
+	flag.BoolVar(&cfg.keepAlive, "keepalive", true, "send TCP keep-alive probes on accepted connections so dead peers are noticed")
+	flag.DurationVar(&cfg.keepAlivePeriod, "keepalive-period", 0, "the time between TCP keep-alive probes (0 for the default)")
+	flag.Var(&cfg.allowCIDRs, "allow-cidr", "only serve clients from this network, e.g. 10.0.0.0/8 (repeatable)")
+	flag.Var(&cfg.denyCIDRs, "deny-cidr", "close connections from this network as soon as they are accepted, overrides -allow-cidr (repeatable)")
+	flag.Float64Var(&cfg.rateLimit, "rate-limit", 0, "close connections from a client IP opening more than this many a second (0 for no limit)")
+	flag.BoolVar(&cfg.proxyProtocol, "proxy-protocol", false, "expect a PROXY protocol v1 header on each connection and report the client it names")
+	flag.DurationVar(&cfg.proxyHeaderTimeout, "proxy-header-timeout", 5*time.Second, "close connections that haven't sent their -proxy-protocol header within this long")
+	flag.StringVar(&cfg.rateLimitMessage, "rate-limit-message", "", "the message sent, in the active framing, to connections closed by -rate-limit (empty for none)")
+	flag.StringVar(&cfg.liveAddr, "live-addr", "", "serve a liveness check on this address, 200 while the observer is responsive")
+	flag.StringVar(&cfg.readyAddr, "ready-addr", "", "serve a readiness check on this address, 503 while paused or draining")
+	flag.StringVar(&cfg.closeMessage, "close-message", defaultCloseMessage, "the message sent, in the active framing, to connections turned away once -accept-drain ends (empty for none)")
+	flag.StringVar(&cfg.latencyHDR, "latency-hdr", "", "record the latency of every request and write the histogram to this file as an HdrHistogram log on exit")
+	flag.StringVar(&cfg.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on /metrics at this address")
+	flag.DurationVar(&cfg.maxObserverLag, "max-observer-lag", 0, "only count finished connections, dropping their statistics, while the observer is this far behind them (0 for never, batched connections wait up to 1s anyway)")
+	flag.IntVar(&cfg.summaryFD, "summary-fd", 0, "write a one line JSON summary to this inherited file descriptor on exit (0 for none)")
+	flag.DurationVar(&cfg.stuckThreshold, "stuck-threshold", 0, "dump every go routine's stack to stuck-<time>.txt when a request makes no progress reading or writing for this long (0 disables)")
+	pipelineSpec := flag.String("pipeline", "", "transform requests before echoing them with these stages, in order: decompress, upper, lower, reverse, pad=N")
+	flag.Parse()
+
+	if flag.NArg() > 1 { // validate args
+		usageFatal("Too many args, the only one is the address to listen on")
+	}
+	if flag.NArg() == 1 {
+		cfg.address = flag.Arg(0)
+	}
+
+	if cfg.startingClients < 1 {
+		usageFatal("-starting-clients must be at least 1")
+	}
+
+	if cfg.freeServerMinimum < 0 {
+		usageFatal("-free-server-minimum must not be negative")
+	}
+
+	if cfg.maxConnections < 0 {
+		usageFatal("-max-connections must not be negative")
+	}
+
+	switch cfg.network {
+	case "tcp", "tcp4", "tcp6":
+	case networkUnix:
+		if cfg.address == defaultListenAddress {
+			usageFatal("-network unix needs the path of the socket to listen on")
+		}
+		if cfg.tfo {
+			usageFatal("-tfo needs a TCP -network")
+		}
+		if len(cfg.allowCIDRs) > 0 || len(cfg.denyCIDRs) > 0 {
+			usageFatal("-allow-cidr and -deny-cidr need a TCP -network")
+		}
+		if cfg.rateLimit > 0 {
+			usageFatal("-rate-limit needs a TCP -network")
+		}
+		if cfg.probeAddr != "" {
+			usageFatal("-probe-addr needs a TCP -network, it can't tell its connections apart on a Unix socket")
+		}
+	default:
+		usageFatal("Unknown network: " + cfg.network)
+	}
+
+	switch cfg.mode {
+	case modeThread:
+	case modeEpoll:
+		if !epollSupported {
+			usageFatal("-mode epoll is only supported on Linux")
+		}
+		if cfg.tlsCert != "" || cfg.tlsKey != "" {
+			usageFatal("-mode epoll can't serve TLS")
+		}
+		if cfg.coalesceWindow > 0 || cfg.holdOpen > 0 || cfg.idleTimeout > 0 || cfg.fairSlots > 0 || cfg.responseChunks > 1 {
+			usageFatal("-mode epoll can't be used with -coalesce-window, -hold-open, -idle-timeout, -fair-slots or -response-chunks")
+		}
+		if cfg.proxyProtocol {
+			usageFatal("-mode epoll can't be used with -proxy-protocol")
+		}
+	default:
+		usageFatal("Unknown mode: " + cfg.mode)
+	}
+
+	switch cfg.proto {
+	case protoTCP:
+	case protoUDP:
+		if cfg.network == networkUnix {
+			usageFatal("-proto udp needs a TCP -network")
+		}
+		if cfg.mode == modeEpoll || cfg.tlsCert != "" || cfg.tlsKey != "" {
+			usageFatal("-proto udp can't be used with -mode epoll or TLS")
+		}
+		if cfg.tfo || cfg.probeAddr != "" || cfg.acceptDrain > 0 {
+			usageFatal("-proto udp can't be used with -tfo, -probe-addr or -accept-drain")
+		}
+		if len(cfg.allowCIDRs) > 0 || len(cfg.denyCIDRs) > 0 || cfg.rateLimit > 0 || cfg.proxyProtocol {
+			usageFatal("-proto udp can't be used with -allow-cidr, -deny-cidr, -rate-limit or -proxy-protocol")
+		}
+	default:
+		usageFatal("Unknown protocol: " + cfg.proto)
+	}
+
+	switch cfg.framing {
+	case framingLine, framingHeader, framingLength:
+	default:
+		usageFatal("Unknown framing: " + cfg.framing)
+	}
+	var err error
+	if cfg.delimiter, err = parseDelimiter(*delimiter); err != nil {
+		usageFatal("-delimiter " + strconv.Quote(*delimiter) + ": " + err.Error())
+	}
+	if cfg.pipeline, err = parsePipeline(*pipelineSpec); err != nil {
+		usageFatal("-pipeline " + strconv.Quote(*pipelineSpec) + ": " + err.Error())
+	}
+	if cfg.fairWeights, err = parseWeights(*fairWeights); err != nil {
+		usageFatal("-fair-weights " + strconv.Quote(*fairWeights) + ": " + err.Error())
+	}
+
+	if _, ok := reportFormatters[cfg.reportFormat]; !ok {
+		usageFatal("Unknown report format: " + cfg.reportFormat)
+	}
+	switch cfg.logLevel {
+	case logLevelDebug, logLevelInfo, logLevelWarn, logLevelError:
+	default:
+		usageFatal("Unknown log level: " + cfg.logLevel)
+	}
+	if cfg.logFormat != logFormatText && cfg.logFormat != logFormatJSON {
+		usageFatal("Unknown log format: " + cfg.logFormat)
+	}
+
+	switch cfg.drainOrder {
+	case drainFIFO, drainLIFO:
+	default:
+		usageFatal("Unknown drain order: " + cfg.drainOrder)
+	}
+
+	switch cfg.delayDist {
+	case delayFixed, delayUniform, delayExponential:
+	default:
+		usageFatal("Unknown delay distribution: " + cfg.delayDist)
+	}
+	if cfg.delayMin > cfg.delay {
+		usageFatal("-delay-min must not be more than -delay")
+	}
+	if cfg.dedupWindow > 0 && cfg.dedupSize < 1 {
+		usageFatal("-dedup-size must be at least 1 with -dedup-window")
+	}
+
+	if cfg.maxInflight < 0 {
+		usageFatal("-max-inflight must not be negative")
+	}
+	if cfg.maxInflight > 0 && cfg.coalesceWindow <= 0 {
+		usageFatal("-max-inflight needs -coalesce-window, without it only one request is ever in flight")
+	}
+	if cfg.workerFloor < 0 {
+		usageFatal("-worker-floor must not be negative")
+	}
+	if cfg.workerCap < 0 {
+		usageFatal("-worker-cap must not be negative")
+	}
+	if cfg.workerCap > 0 && cfg.workerCap < cfg.workerFloor {
+		usageFatal("-worker-cap must be at least -worker-floor")
+	}
+	if cfg.closeBatch > 1 && cfg.workerFloor > 0 {
+		usageFatal("-close-batch can't be used with the lazy pool of -worker-floor")
+	}
+
+	if cfg.refuseRate < 0 || cfg.refuseRate > 1 {
+		usageFatal("-refuse-rate must be between 0 and 1")
+	}
+
+	if cfg.acceptDrain > 0 && cfg.shutdownTimeout <= 0 {
+		usageFatal("-accept-drain needs -shutdown-timeout")
+	}
+
+	if cfg.otlpEndpoint != "" && cfg.otlpInterval <= 0 {
+		usageFatal("-otlp-interval must be positive")
+	}
+
+	if cfg.workerSpawnRate < 0 {
+		usageFatal("-worker-spawn-rate must not be negative")
+	}
+
+	if cfg.reportFilter != "" && !strings.Contains(cfg.reportFilter, "=") {
+		usageFatal("-report-filter must be a key=value label")
+	}
+
+	if (cfg.reportGroupBy != "" || cfg.reportFilter != "") && !cfg.connectionLabels {
+		log.Println("-report-group-by and -report-filter need -connection-labels, no connection will have labels")
+	}
+
+	if cfg.peerCred && !peerCredSupported {
+		log.Println("-peer-cred is only supported on Linux, PeerCred will be empty")
+	}
+
+	if cfg.minRequest < 0 || (cfg.maxLine > 0 && cfg.minRequest > cfg.maxLine) {
+		usageFatal("-min-request must be between 0 and -max-line")
+	}
+
+	if cfg.minRequestReply != "" && cfg.minRequest <= 0 {
+		usageFatal("-min-request-reply needs -min-request")
+	}
+
+	if cfg.snapshotInterval < 0 {
+		usageFatal("-snapshot-interval must not be negative")
+	}
+	if cfg.snapshotFile != "" && cfg.snapshotInterval == 0 {
+		usageFatal("-snapshot-file needs -snapshot-interval")
+	}
+
+	if cfg.reportMaxSize < 0 {
+		usageFatal("-report-max-size must not be negative")
+	}
+	if cfg.reportMaxSize > 0 && cfg.snapshotFile == "" {
+		usageFatal("-report-max-size needs -snapshot-file, the reports themselves are replaced rather than grown")
+	}
+	if cfg.reportKeep < 0 {
+		usageFatal("-report-keep must not be negative")
+	}
+
+	if cfg.resetOnReport && cfg.reportInterval <= 0 {
+		usageFatal("-reset-on-report needs -report-interval")
+	}
+
+	if cfg.tfo && !tfoSupported {
+		log.Println("-tfo is only supported on Linux, the listener won't use TCP Fast Open")
+	}
+
+	if cfg.fairSlots < 0 {
+		usageFatal("-fair-slots must not be negative")
+	}
+
+	if cfg.fairSlots > 0 && cfg.fairLabel == "" {
+		usageFatal("-fair-slots needs -fair-label")
+	}
+
+	if cfg.fairLabel != "" && !cfg.connectionLabels {
+		log.Println("-fair-label needs -connection-labels, every connection will share one tag")
+	}
+
+	if cfg.summaryFD < 0 {
+		usageFatal("-summary-fd must not be negative")
+	}
+
+	if cfg.maxObserverLag < 0 {
+		usageFatal("-max-observer-lag must not be negative")
+	}
+
+	if cfg.proxyProtocol && (cfg.tlsCert != "" || cfg.tlsKey != "") {
+		usageFatal("-proxy-protocol can't be used with TLS, the header would be read as the handshake")
+	}
+	if cfg.proxyProtocol && cfg.proxyHeaderTimeout <= 0 {
+		usageFatal("-proxy-header-timeout must be positive")
+	}
+	if cfg.rateLimit < 0 {
+		usageFatal("-rate-limit must not be negative")
+	}
+	if cfg.keepAlivePeriod < 0 {
+		usageFatal("-keepalive-period must not be negative")
+	}
+	if cfg.idleTimeout < 0 {
+		usageFatal("-idle-timeout must not be negative")
+	}
+
+	if cfg.failAfter < 0 {
+		usageFatal("-fail-after must not be negative")
+	}
+
+	if cfg.holdOpen < 0 {
+		usageFatal("-hold-open must not be negative")
+	}
+
+	if cfg.handshakeTimeout < 0 {
+		usageFatal("-handshake-timeout must not be negative")
+	}
+
+	if cfg.handshakeTimeout > 0 && cfg.tlsCert == "" {
+		log.Println("-handshake-timeout needs -tls-cert, connections won't use TLS")
+	}
+
+	if cfg.tlsStats && cfg.tlsCert == "" {
+		log.Println("-tls-stats needs -tls-cert, connections won't use TLS")
+	}
+
+	if cfg.synLatency && !synLatencySupported {
+		log.Println("-syn-latency is only supported on Linux, SynToAccept will be 0")
+	}
+
+	if cfg.seed == 0 {
+		cfg.seed = time.Now().UnixNano()
+	}
+
+	return cfg
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    usageFatal
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func usageFatal(message string)
+--   message:		what was wrong with the command line.
+--
+-- RETURNS:     never, exits with the status the flag package uses for bad flags.
+------------------------------------------------------------------------------*/
+func usageFatal(message string) {
+	fmt.Fprintln(flag.CommandLine.Output(), message)
+	flag.Usage()
+	os.Exit(2)
+}
@@ -0,0 +1,96 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 config_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestParseConfigFlagErrors(t *testing.T)
+--  func TestParseConfigListen(t *testing.T)
+--
+-- NOTES: Tests for reading the command line. parseConfig exits on a bad flag
+--        so it is run in a child test process given the flags to read.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestParseConfigFlagErrors checks that bad flags are reported with the usage
+// and the status the flag package exits with.
+func TestParseConfigFlagErrors(t *testing.T) {
+	if args := os.Getenv("PARSE_CONFIG_ARGS"); args != "" {
+		os.Args = append([]string{"scalableserver"}, strings.Split(args, " ")...)
+		parseConfig()
+		os.Exit(0)
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"-framing bogus", "Unknown framing: bogus"},
+		{"-report-format bogus", "Unknown report format: bogus"},
+		{"-refuse-rate 2", "-refuse-rate must be between 0 and 1"},
+		{"-fair-weights gold=x", "-fair-weights \"gold=x\""},
+		{"-rate-limit -1", "-rate-limit must not be negative"},
+		{"-report-max-size 1000", "-report-max-size needs -snapshot-file"},
+		{"-hold-open -1s", "-hold-open must not be negative"},
+		{"-dedup-window 1s -dedup-size 0", "-dedup-size must be at least 1 with -dedup-window"},
+		{"-starting-clients 0", "-starting-clients must be at least 1"},
+		{"-free-server-minimum -1", "-free-server-minimum must not be negative"},
+		{":7000 :7001", "Too many args"},
+	}
+	for _, test := range tests {
+		child := exec.Command(os.Args[0], "-test.run=^TestParseConfigFlagErrors$")
+		child.Env = append(os.Environ(), "PARSE_CONFIG_ARGS="+test.args)
+		output, err := child.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+			t.Errorf("%s exited with %v, want status 2", test.args, err)
+		}
+		if !strings.Contains(string(output), test.want) || !strings.Contains(string(output), "Usage of") {
+			t.Errorf("%s printed %q, want %q and the usage", test.args, output, test.want)
+		}
+	}
+}
+
+// TestParseConfigListen checks the listen address and the pool's sizes are
+// read from their flags, with an address after the flags overriding -listen.
+func TestParseConfigListen(t *testing.T) {
+	if args := os.Getenv("PARSE_CONFIG_ARGS"); args != "" {
+		os.Args = append([]string{"scalableserver"}, strings.Fields(args)...)
+		cfg := parseConfig()
+		fmt.Println(cfg.address, cfg.startingClients, cfg.freeServerMinimum)
+		os.Exit(0)
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{" ", fmt.Sprintln(defaultListenAddress, defaultStartingClients, defaultFreeServerMinimum)},
+		{"-listen :7000 -starting-clients 3 -free-server-minimum 0", ":7000 3 0\n"},
+		{"-listen :7000 127.0.0.1:7001", fmt.Sprintln("127.0.0.1:7001", defaultStartingClients, defaultFreeServerMinimum)},
+	}
+	for _, test := range tests {
+		child := exec.Command(os.Args[0], "-test.run=^TestParseConfigListen$")
+		child.Env = append(os.Environ(), "PARSE_CONFIG_ARGS="+test.args)
+		output, err := child.Output()
+		if err != nil || string(output) != test.want {
+			t.Errorf("%q read %q, %v, want %q", test.args, output, err, test.want)
+		}
+	}
+}
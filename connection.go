@@ -0,0 +1,269 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 connection.go
+--
+-- REVISIONS: 	October 15, 2026 - Connections can be failed after -fail-after bytes
+--              October 15, 2026 - Reads and writes are progress for the stuck watchdog
+--              October 15, 2026 - Added keep-alive configuration
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func (c *countingConn) Read(b []byte) (int, error)
+--  func (c *countingConn) Write(b []byte) (int, error)
+--  func (c *countingConn) remaining() int
+--  func (c *countingConn) NetConn() net.Conn
+--  func abortOnClose(conn net.Conn)
+--  func setKeepAlive(conn net.Conn, enabled bool, period time.Duration)
+--
+-- NOTES: This file holds the state kept for each client connection, and wraps
+--        the connections so the bytes moving over them are recorded in the
+--        connection's connectionInfo. With -fail-after the wrapper also
+--        injects a failure once the connection has transferred that many
+--        bytes, as if the connection had been cut mid-transfer.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// closeReasonInjectedFailure the CloseReason of a connection failed by -fail-after.
+const closeReasonInjectedFailure = "injected-failure"
+
+var errInjectedFailure = errors.New("injected failure after -fail-after bytes")
+
+// connectionState the state kept for a connection between requests.
+type connectionState struct {
+	cfg      *serverConfig    // the server configuration
+	dedup    *dedupCache      // recent requests, nil if deduplication is off
+	rng      *lockedRand      // the random number generator shared by all workers
+	nonces   *int64           // the nonces issued by the server, updated atomically
+	clock    Clock            // the clock requests are timed by
+	fair     *fairScheduler   // the processing slots shared between tags, nil if there are none
+	latency  *latencyRecorder // the latency of every request, nil if it isn't recorded
+	progress *connProgress    // the connection's progress, nil if -stuck-threshold isn't set
+	logger   *slog.Logger     // the server's log
+}
+
+// countingConn a net.Conn that records the bytes read and written on it.
+type countingConn struct {
+	net.Conn
+	connInfo    *connectionInfo
+	transferred *int64        // the server wide byte count, updated atomically
+	countReads  bool          // record the reads that returned data in ReadCalls
+	failAfter   int           // the bytes transferred before the connection fails, 0 for never
+	progress    *connProgress // the connection's progress for the stuck watchdog, nil if it isn't watched
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Read
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - fails once -fail-after bytes are transferred
+--              October 15, 2026 - records progress for the stuck watchdog
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *countingConn) Read(b []byte) (int, error)
+--         b:		the buffer to read into.
+--
+-- RETURNS:     int   the number of bytes read.
+--              error any error from the underlying connection.
+--
+-- NOTES:			Each read that returns data is roughly one segment from the
+--						client, so ReadCalls shows how fragmented its writes were.
+--						With -fail-after no more is read than the connection has
+--						left, after that every read fails.
+------------------------------------------------------------------------------*/
+func (c *countingConn) Read(b []byte) (int, error) {
+	if remaining := c.remaining(); remaining == 0 {
+		return 0, errInjectedFailure
+	} else if remaining > 0 && len(b) > remaining {
+		b = b[:remaining]
+	}
+	n, err := c.Conn.Read(b)
+	c.connInfo.BytesReceived += n
+	atomic.AddInt64(c.transferred, int64(n))
+	if n > 0 {
+		c.progress.advance()
+	}
+	if c.countReads && n > 0 {
+		c.connInfo.ReadCalls++
+	}
+
+	return n, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Write
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - fails once -fail-after bytes are transferred
+--              October 15, 2026 - records progress for the stuck watchdog
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *countingConn) Write(b []byte) (int, error)
+--         b:		the data to write.
+--
+-- RETURNS:     int   the number of bytes written.
+--              error any error from the underlying connection.
+--
+-- NOTES:			With -fail-after only what the connection has left is written,
+--						the rest of b fails with errInjectedFailure.
+------------------------------------------------------------------------------*/
+func (c *countingConn) Write(b []byte) (int, error) {
+	remaining := c.remaining()
+	if remaining == 0 {
+		return 0, errInjectedFailure
+	}
+	truncated := remaining > 0 && len(b) > remaining
+	if truncated {
+		b = b[:remaining]
+	}
+	n, err := c.Conn.Write(b)
+	c.connInfo.BytesSent += n
+	atomic.AddInt64(c.transferred, int64(n))
+	if n > 0 {
+		c.progress.advance()
+	}
+	if truncated && err == nil {
+		err = errInjectedFailure
+	}
+
+	return n, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    remaining
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *countingConn) remaining() int
+--
+-- RETURNS:     int the bytes the connection can transfer before it fails, -1
+--              if -fail-after isn't set.
+------------------------------------------------------------------------------*/
+func (c *countingConn) remaining() int {
+	if c.failAfter <= 0 {
+		return -1
+	}
+
+	return max(c.failAfter-c.connInfo.BytesReceived-c.connInfo.BytesSent, 0)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    NetConn
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *countingConn) NetConn() net.Conn
+--
+-- RETURNS:     net.Conn the connection being counted.
+------------------------------------------------------------------------------*/
+func (c *countingConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    abortOnClose
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func abortOnClose(conn net.Conn)
+--      conn:		a connection a worker has accepted, possibly wrapped.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Sets the linger time of the TCP connection underneath conn to 0
+--						so closing it sends a reset instead of a graceful FIN.
+------------------------------------------------------------------------------*/
+func abortOnClose(conn net.Conn) {
+	for {
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+			return
+		}
+		wrapped, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return
+		}
+		conn = wrapped.NetConn()
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    setKeepAlive
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func setKeepAlive(conn net.Conn, enabled bool, period time.Duration)
+--      conn:		a connection that has just been accepted, possibly wrapped.
+--   enabled:		the -keepalive.
+--    period:		the -keepalive-period, 0 to leave the default.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Keep-alive lets a worker find out its client died behind a NAT
+--						instead of blocking on it forever. Connections that aren't TCP,
+--						like Unix sockets, are left alone.
+------------------------------------------------------------------------------*/
+func setKeepAlive(conn net.Conn, enabled bool, period time.Duration) {
+	for {
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			if err := tcpConn.SetKeepAlive(enabled); err != nil {
+				log.Println("Unable to set keep-alive:", err)
+			} else if enabled && period > 0 {
+				if err := tcpConn.SetKeepAlivePeriod(period); err != nil {
+					log.Println("Unable to set the keep-alive period:", err)
+				}
+			}
+			return
+		}
+		wrapped, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return
+		}
+		conn = wrapped.NetConn()
+	}
+}
@@ -0,0 +1,98 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 connection_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestReadCallsCounted(t *testing.T)
+--  func TestFailAfterBytes(t *testing.T)
+--  func TestPipelinedLinesInOneWrite(t *testing.T)
+--
+-- NOTES: Tests for the wrappers around each client connection. Connections
+--        are served over a net.Pipe, which hands each write from the client
+--        to the server's next read on its own.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"io"
+	"testing"
+)
+
+// TestReadCallsCounted checks that each fragment the client sends is counted
+// as a read that returned data.
+func TestReadCallsCounted(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', countReads: true})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	for _, request := range [][]string{{"hel", "lo\n"}, {"a", "b", "c\n"}, {"whole\n"}} {
+		for _, fragment := range request {
+			io.WriteString(client, fragment)
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal("read:", err)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.ReadCalls != 6 {
+		t.Errorf("%d reads counted for 6 fragments", connInfo.ReadCalls)
+	}
+}
+
+// TestFailAfterBytes checks that a connection fails once it has transferred
+// -fail-after bytes, received and sent together, with the failure recorded.
+func TestFailAfterBytes(t *testing.T) {
+	const line = "0123456789\n"
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', failAfter: 30})
+	client, served := serveTestConnection(t, srvInfo)
+	go func() {
+		for i := 0; i < 5; i++ {
+			if _, err := io.WriteString(client, line); err != nil {
+				return
+			}
+		}
+	}()
+
+	received, err := io.ReadAll(client)
+	if err != nil || string(received) != line {
+		t.Errorf("the client got %q, %v before the failure, want one echo", received, err)
+	}
+	connInfo := <-served
+	if connInfo.CloseReason != closeReasonInjectedFailure {
+		t.Errorf("the connection closed for %q, want %q", connInfo.CloseReason, closeReasonInjectedFailure)
+	}
+	if connInfo.BytesReceived != 19 || connInfo.BytesSent != 11 {
+		t.Errorf("%d bytes received and %d sent, want 19 and 11, 30 in all",
+			connInfo.BytesReceived, connInfo.BytesSent)
+	}
+}
+
+// TestPipelinedLinesInOneWrite checks that lines sent in a single write are
+// each echoed and counted, none lost in the reader's buffer.
+func TestPipelinedLinesInOneWrite(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	client, served := serveTestConnection(t, srvInfo)
+	go io.WriteString(client, "a\nb\nc\n")
+
+	reader := bufio.NewReader(client)
+	for _, want := range []string{"a\n", "b\n", "c\n"} {
+		if echo, err := reader.ReadString('\n'); echo != want {
+			t.Fatalf("the echo was %q, %v, want %q", echo, err, want)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.NumberOfRequests != 3 {
+		t.Errorf("%d requests counted, want 3", connInfo.NumberOfRequests)
+	}
+}
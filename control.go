@@ -0,0 +1,158 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 control.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startControlServer(srvInfo serverInfo)
+--  func handleWorkers(srvInfo serverInfo, w http.ResponseWriter, r *http.Request)
+--  func handleStats(srvInfo serverInfo, w http.ResponseWriter, r *http.Request)
+--
+-- NOTES: This file serves the control endpoint used to tune the server while
+--        it runs. The handlers never touch the observer's state, they send
+--        their request to the observer and wait for its reply.
+--
+--        GET  /workers     reports the number of workers.
+--        POST /workers?n=N grows or shrinks the pool towards N workers.
+--        GET  /stats       dumps the running totals as plain text.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// workerResize asks the observer to resize the worker pool.
+type workerResize struct {
+	target int      // the number of workers wanted, -1 to only report the pool
+	reply  chan int // receives the number of workers once the request is handled
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startControlServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - serves /stats
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startControlServer(srvInfo serverInfo)
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing if -control-addr isn't set. Exits the program if
+--						the address can't be listened on.
+------------------------------------------------------------------------------*/
+func startControlServer(srvInfo serverInfo) {
+	if srvInfo.config.controlAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", srvInfo.config.controlAddr)
+	if err != nil {
+		log.Fatalln("Unable to listen for control requests:", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/workers", func(w http.ResponseWriter, r *http.Request) {
+		handleWorkers(srvInfo, w, r)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		handleStats(srvInfo, w, r)
+	})
+	go http.Serve(listener, mux)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    handleWorkers
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func handleWorkers(srvInfo serverInfo, w http.ResponseWriter, r *http.Request)
+--   srvInfo:		information about the overall server
+--         w:		the response to the control client.
+--         r:		the control request.
+--
+-- RETURNS:     void
+--
+-- NOTES:			The reply is the number of workers when the request was
+--						handled. Growing the pool is limited by -worker-spawn-rate and
+--						workers are only retired as they finish a connection, so the
+--						pool can take a while to reach the target.
+------------------------------------------------------------------------------*/
+func handleWorkers(srvInfo serverInfo, w http.ResponseWriter, r *http.Request) {
+	resize := workerResize{target: -1, reply: make(chan int, 1)}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		target, err := strconv.Atoi(r.URL.Query().Get("n"))
+		if err != nil || target < 1 {
+			http.Error(w, "n must be a positive number of workers", http.StatusBadRequest)
+			return
+		}
+		resize.target = target
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	srvInfo.workerResize <- resize
+	fmt.Fprintln(w, "workers:", <-resize.reply)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    handleStats
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func handleStats(srvInfo serverInfo, w http.ResponseWriter, r *http.Request)
+--	 srvInfo:		information about the overall server
+--         w:		where the totals are written.
+--         r:		the request, only GET is allowed.
+--
+-- RETURNS:     void
+--
+-- NOTES:			The connection and request totals come from a snapshot taken
+--						by the observer, so requests are counted once their
+--						connection finishes. The bytes are counted as they are
+--						transferred, including on the connections still open.
+------------------------------------------------------------------------------*/
+func handleStats(srvInfo serverInfo, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := readStats(srvInfo)
+	fmt.Fprintln(w, "total connections:", snapshot.TotalConnections+snapshot.CurrentConnections)
+	fmt.Fprintln(w, "current connections:", snapshot.CurrentConnections)
+	fmt.Fprintln(w, "total bytes:", atomic.LoadInt64(srvInfo.bytesTransferred))
+	fmt.Fprintln(w, "total requests:", snapshot.TotalRequests)
+}
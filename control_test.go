@@ -0,0 +1,128 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 control_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func controlWorkers(t *testing.T, srvInfo serverInfo, method string, target string) int
+--  func TestResizeConverges(t *testing.T)
+--  func TestStatsMidRun(t *testing.T)
+--
+-- NOTES: Tests for the control endpoint. Requests are handled with an
+--        httptest.ResponseRecorder, by an observer running in the background
+--        on a fakeClock. The workers it spawns accept from a pipeListener.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// controlWorkers sends a request to /workers, returning the number of workers
+// the control endpoint reports.
+func controlWorkers(t *testing.T, srvInfo serverInfo, method string, target string) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handleWorkers(srvInfo, recorder, httptest.NewRequest(method, "/workers?n="+target, nil))
+	var workers int
+	if _, err := fmt.Sscanf(recorder.Body.String(), "workers: %d", &workers); err != nil {
+		t.Fatalf("%s /workers?n=%s returned %d %q", method, target, recorder.Code, recorder.Body.String())
+	}
+
+	return workers
+}
+
+// TestResizeConverges checks that the pool grows to the requested size at
+// -worker-spawn-rate, that idle workers retire to shrink it, and that the
+// workers left still serve every connection.
+func TestResizeConverges(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, workerSpawnRate: 10,
+		framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	srvInfo.workerResize = make(chan workerResize)
+	listener := pipeListener{conns: make(chan net.Conn)}
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	clock := srvInfo.clock.(*fakeClock)
+	go observerLoop(srvInfo, nil)
+
+	if workers := controlWorkers(t, srvInfo, http.MethodGet, ""); workers != 0 {
+		t.Fatalf("%d workers before the resize, want 0", workers)
+	}
+	if workers := controlWorkers(t, srvInfo, http.MethodPost, "5"); workers != 1 {
+		t.Errorf("%d workers straight after growing to 5, want 1 held to the spawn rate", workers)
+	}
+	for i := 0; controlWorkers(t, srvInfo, http.MethodGet, "") < 5; i++ {
+		if i == 100 {
+			t.Fatal("the pool never grew to 5 workers")
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	controlWorkers(t, srvInfo, http.MethodPost, "2")
+	for deadline := time.Now().Add(5 * time.Second); controlWorkers(t, srvInfo, http.MethodGet, "") != 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("the idle pool never shrank to 2 workers")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// some of the connections are accepted by the retired workers' Accepts
+	for i := 0; i < 5; i++ {
+		server, client := net.Pipe()
+		listener.conns <- server
+		go io.WriteString(client, "hello\n")
+		if echo, err := bufio.NewReader(client).ReadString('\n'); err != nil || echo != "hello\n" {
+			t.Fatalf("echo on connection %d was %q, %v", i, echo, err)
+		}
+		client.Close()
+	}
+	if workers := controlWorkers(t, srvInfo, http.MethodGet, ""); workers != 2 {
+		t.Errorf("%d workers after serving connections, want 2", workers)
+	}
+}
+
+// TestStatsMidRun checks that GET /stats reports the observer's totals while
+// a connection is still open, and that other methods aren't allowed.
+func TestStatsMidRun(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{})
+	go observerLoop(srvInfo, nil)
+	srvInfo.serverConnection <- newConnectionConst
+	srvInfo.serverConnection <- newConnectionConst
+	srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 2}
+	atomic.StoreInt64(srvInfo.bytesTransferred, 20)
+
+	recorder := httptest.NewRecorder()
+	handleStats(srvInfo, recorder, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var total, current, bytes, requests int
+	if _, err := fmt.Sscanf(recorder.Body.String(),
+		"total connections: %d\ncurrent connections: %d\ntotal bytes: %d\ntotal requests: %d\n",
+		&total, &current, &bytes, &requests); err != nil {
+		t.Fatalf("GET /stats returned %d %q: %v", recorder.Code, recorder.Body.String(), err)
+	}
+	if total != 2 || current != 1 || bytes != 20 || requests != 2 {
+		t.Errorf("%d total connections, %d current, %d bytes and %d requests, want 2, 1, 20 and 2",
+			total, current, bytes, requests)
+	}
+
+	recorder = httptest.NewRecorder()
+	handleStats(srvInfo, recorder, httptest.NewRequest(http.MethodPost, "/stats", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /stats returned %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
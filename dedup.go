@@ -0,0 +1,112 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 dedup.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newDedupCache(window time.Duration, size int) *dedupCache
+--  func (cache *dedupCache) duplicate(payload []byte, now time.Time) bool
+--
+-- NOTES: This file caches recent requests on a connection so a client retrying
+--        a request can be answered without processing it again.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// dedupMarker the response sent in place of the echo for a repeated request.
+const dedupMarker = "DUPLICATE"
+
+// dedupCache the most recent requests seen on a connection, oldest first.
+type dedupCache struct {
+	window  time.Duration
+	size    int
+	entries []dedupEntry
+}
+
+type dedupEntry struct {
+	sum  [sha256.Size]byte
+	seen time.Time
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newDedupCache
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newDedupCache(window time.Duration, size int) *dedupCache
+--    window:		how long a request is remembered for.
+--      size:		the most requests remembered at once.
+--
+-- RETURNS:     *dedupCache an empty cache, nil if window is 0.
+------------------------------------------------------------------------------*/
+func newDedupCache(window time.Duration, size int) *dedupCache {
+	if window <= 0 || size <= 0 {
+		return nil
+	}
+
+	return &dedupCache{window: window, size: size}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    duplicate
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (cache *dedupCache) duplicate(payload []byte, now time.Time) bool
+--   payload:		the request.
+--       now:		when the request arrived.
+--
+-- RETURNS:     bool true if the same request was seen within the window.
+--
+-- NOTES:			Requests that aren't duplicates are remembered, dropping the
+--						oldest request if the cache is full. A nil cache never finds
+--						a duplicate.
+------------------------------------------------------------------------------*/
+func (cache *dedupCache) duplicate(payload []byte, now time.Time) bool {
+	if cache == nil {
+		return false
+	}
+
+	expired := 0
+	for expired < len(cache.entries) && now.Sub(cache.entries[expired].seen) > cache.window {
+		expired++
+	}
+	cache.entries = cache.entries[expired:]
+
+	sum := sha256.Sum256(payload)
+	for _, entry := range cache.entries {
+		if entry.sum == sum {
+			return true
+		}
+	}
+
+	if len(cache.entries) >= cache.size {
+		cache.entries = cache.entries[1:]
+	}
+	cache.entries = append(cache.entries, dedupEntry{sum: sum, seen: now})
+
+	return false
+}
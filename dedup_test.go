@@ -0,0 +1,74 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 dedup_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestDedupRetryFromCache(t *testing.T)
+--  func TestDedupCacheBounded(t *testing.T)
+--
+-- NOTES: Tests for answering retried requests from the dedup cache. The
+--        window is timed on a fakeClock.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestDedupRetryFromCache checks that a request repeated within the window is
+// answered with the marker and counted, and echoed again once it has passed.
+func TestDedupRetryFromCache(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		dedupWindow: time.Minute, dedupSize: 8})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	for i, test := range []struct {
+		request string
+		advance time.Duration
+		want    string
+	}{
+		{"retry\n", 0, "retry\n"},
+		{"retry\n", 0, dedupMarker + "\n"},
+		{"other\n", 0, "other\n"},
+		{"retry\n", 2 * time.Minute, "retry\n"},
+	} {
+		srvInfo.clock.(*fakeClock).Advance(test.advance)
+		io.WriteString(client, test.request)
+		if response, err := reader.ReadString('\n'); err != nil || response != test.want {
+			t.Fatalf("response %d was %q, %v, want %q", i+1, response, err, test.want)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.DedupHits != 1 {
+		t.Errorf("%d dedup hits, want 1", connInfo.DedupHits)
+	}
+}
+
+// TestDedupCacheBounded checks that the cache forgets its oldest request once
+// it holds -dedup-size of them.
+func TestDedupCacheBounded(t *testing.T) {
+	cache := newDedupCache(time.Minute, 2)
+	now := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+	for _, request := range []string{"a", "b", "c"} {
+		cache.duplicate([]byte(request), now)
+	}
+	if !cache.duplicate([]byte("c"), now) {
+		t.Error("the newest request wasn't remembered")
+	}
+	if cache.duplicate([]byte("a"), now) {
+		t.Error("the oldest request was still remembered past -dedup-size")
+	}
+}
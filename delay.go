@@ -0,0 +1,62 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 delay.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func responseDelay(cfg *serverConfig, rng *lockedRand) time.Duration
+--
+-- NOTES: This file simulates backend latency by delaying each response by an
+--        amount drawn from a configurable distribution.
+--
+--        fixed:       every response is delayed by -delay.
+--        uniform:     delays are spread evenly between -delay-min and -delay.
+--        exponential: delays are exponentially distributed with a mean of
+--                     -delay.
+------------------------------------------------------------------------------*/
+package main
+
+import "time"
+
+const delayFixed = "fixed"
+const delayUniform = "uniform"
+const delayExponential = "exponential"
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    responseDelay
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func responseDelay(cfg *serverConfig, rng *lockedRand) time.Duration
+--       cfg:		the server configuration.
+--       rng:		the shared random number generator.
+--
+-- RETURNS:     time.Duration how long to wait before the next response.
+------------------------------------------------------------------------------*/
+func responseDelay(cfg *serverConfig, rng *lockedRand) time.Duration {
+	if cfg.delay <= 0 {
+		return 0
+	}
+
+	switch cfg.delayDist {
+	case delayUniform:
+		return cfg.delayMin + time.Duration(rng.Float64()*float64(cfg.delay-cfg.delayMin))
+	case delayExponential:
+		return time.Duration(rng.ExpFloat64() * float64(cfg.delay))
+	}
+
+	return cfg.delay
+}
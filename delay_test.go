@@ -0,0 +1,103 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 delay_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestResponseDelayExponential(t *testing.T)
+--  func TestResponseDelayUniform(t *testing.T)
+--  func TestPacingDeviation(t *testing.T)
+--
+-- NOTES: Tests for the response delay distributions. Delays are sampled from
+--        a seeded generator, and slept on a fakeClock, so the results don't
+--        vary from run to run.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestResponseDelayExponential checks that exponential delays have a mean of
+// -delay and that about 1/e of them are longer than it.
+func TestResponseDelayExponential(t *testing.T) {
+	const samples = 20000
+	cfg := &serverConfig{delay: 10 * time.Millisecond, delayDist: delayExponential}
+	rng := newLockedRand(1)
+
+	var total time.Duration
+	longer := 0
+	for i := 0; i < samples; i++ {
+		delay := responseDelay(cfg, rng)
+		if delay < 0 {
+			t.Fatalf("a delay of %s", delay)
+		}
+		total += delay
+		if delay > cfg.delay {
+			longer++
+		}
+	}
+
+	if mean := total / samples; math.Abs(float64(mean-cfg.delay)) > 0.05*float64(cfg.delay) {
+		t.Errorf("the mean delay was %s, want about %s", mean, cfg.delay)
+	}
+	if fraction := float64(longer) / samples; math.Abs(fraction-1/math.E) > 0.02 {
+		t.Errorf("%.3f of the delays were longer than the mean, want about %.3f", fraction, 1/math.E)
+	}
+}
+
+// TestResponseDelayUniform checks that uniform delays stay between -delay-min
+// and -delay.
+func TestResponseDelayUniform(t *testing.T) {
+	cfg := &serverConfig{delay: 10 * time.Millisecond, delayMin: 5 * time.Millisecond, delayDist: delayUniform}
+	rng := newLockedRand(1)
+	for i := 0; i < 1000; i++ {
+		if delay := responseDelay(cfg, rng); delay < cfg.delayMin || delay > cfg.delay {
+			t.Fatalf("a delay of %s, want between %s and %s", delay, cfg.delayMin, cfg.delay)
+		}
+	}
+}
+
+// TestPacingDeviation checks that how far each delayed response was sent off
+// its delay is recorded per connection and summarized in the report.
+func TestPacingDeviation(t *testing.T) {
+	const delay = 10 * time.Millisecond
+	state := newTestConnectionState(&serverConfig{framing: framingLine, delimiter: '\n',
+		delay: delay, delayDist: delayFixed})
+	clock := state.clock.(*fakeClock)
+
+	var connInfo connectionInfo
+	for _, late := range []time.Duration{0, 2 * time.Millisecond, time.Millisecond} {
+		done := make(chan bool)
+		go func() {
+			processRequest([]byte("hello\n"), &connInfo, state)
+			close(done)
+		}()
+		waitForWaiters(clock, 1)
+		clock.Advance(delay + late)
+		<-done
+	}
+	if connInfo.DelayedRequests != 3 || connInfo.PacingDeviation != 3*time.Millisecond ||
+		connInfo.MaxPacingDeviation != 2*time.Millisecond {
+		t.Errorf("%d delayed requests off by %s in all and %s at most, want 3, 3ms and 2ms",
+			connInfo.DelayedRequests, connInfo.PacingDeviation, connInfo.MaxPacingDeviation)
+	}
+
+	stats := newServerStats("")
+	stats.connectionOpened()
+	stats.connectionClosed(connInfo)
+	if summary := stats.summary(); summary.MeanPacingDeviation != time.Millisecond ||
+		summary.MaxPacingDeviation != 2*time.Millisecond {
+		t.Errorf("the report's mean deviation was %s and its maximum %s, want 1ms and 2ms",
+			summary.MeanPacingDeviation, summary.MaxPacingDeviation)
+	}
+}
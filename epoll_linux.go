@@ -0,0 +1,887 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 epoll_linux.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startEpollLoop(srvInfo serverInfo)
+--  func (loop *epollLoop) accept()
+--  func (loop *epollLoop) admit(conn net.Conn) *epollConn
+--  func (loop *epollLoop) wake()
+--  func (loop *epollLoop) run()
+--  func (loop *epollLoop) woken()
+--  func (loop *epollLoop) register(conn *epollConn)
+--  func (loop *epollLoop) readable(conn *epollConn)
+--  func (loop *epollLoop) serve(conn *epollConn)
+--  func (loop *epollLoop) flush(conn *epollConn)
+--  func (loop *epollLoop) close(conn *epollConn, err error)
+--  func epollCloseReason(connInfo *connectionInfo, err error, logger *slog.Logger) string
+--  func newFDConn(conn net.Conn) (*fdConn, error)
+--  func (c *fdConn) Read(b []byte) (int, error)
+--  func (c *fdConn) Write(b []byte) (int, error)
+--  func (c *fdConn) Close() error
+--  func (c *fdConn) closeFD()
+--  func (c *fdConn) LocalAddr() net.Addr
+--  func (c *fdConn) RemoteAddr() net.Addr
+--  func (c *fdConn) SetDeadline(t time.Time) error
+--  func (c *fdConn) SetReadDeadline(t time.Time) error
+--  func (c *fdConn) SetWriteDeadline(t time.Time) error
+--
+-- NOTES: This file is the -mode epoll version of the scalable server. One go
+--        routine accepts connections, admits them the same way the workers
+--        do and hands their sockets to the event loop. The event loop waits
+--        on a single epoll instance for every connection and reads, answers
+--        and writes to whichever are ready, so all of them are served by one
+--        go routine. Finished connections are sent to the observer like the
+--        workers' are.
+--
+--        Each socket is taken from the net.Conn Accept returned, so Go's own
+--        poller no longer waits on it. Requests go through the same framing
+--        and processRequest as in thread mode, but anything that sleeps
+--        while answering, such as -delay, holds up every connection.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// epollSupported whether -mode epoll can be used on this platform.
+const epollSupported = true
+
+// epollMaxEvents the most events taken from the epoll instance at once.
+const epollMaxEvents = 128
+
+// epollReadSize the most bytes read from a connection each time it is ready.
+const epollReadSize = 64 << 10
+
+var errByteCap = errors.New("connection reached -max-bytes-per-conn")
+var errServePanic = errors.New("panic serving the connection")
+var errNoDeadlines = errors.New("-mode epoll connections have no deadlines")
+
+// epollLoop the connections served by the event loop. Only the loop's go
+// routine touches conns and buffer.
+type epollLoop struct {
+	srvInfo serverInfo
+	epfd    int                // the epoll instance
+	wakeFD  int                // an eventfd in the epoll instance, written to by wake
+	added   chan *epollConn    // admitted connections waiting to be registered
+	conns   map[int]*epollConn // the connections being served, by socket
+	buffer  []byte             // each read is made into this
+}
+
+// epollConn a connection served by the event loop.
+type epollConn struct {
+	socket     *fdConn
+	registered *registeredConn
+	counted    *countingConn // reads and writes go through this so they are counted
+	connInfo   connectionInfo
+	state      connectionState
+	pending    []byte      // bytes read that don't make a whole request yet
+	output     []byte      // responses not written yet
+	received   []time.Time // when the requests answered in output were read
+	events     uint32      // the events the loop is waiting for on the socket
+	closing    bool        // close the connection once output is written
+	closeErr   error       // why the connection is closing
+}
+
+// fdConn a net.Conn over a non-blocking socket. Reads and writes return
+// unix.EAGAIN instead of waiting, the event loop does the waiting.
+type fdConn struct {
+	fd     int
+	local  net.Addr
+	remote net.Addr
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startEpollLoop
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startEpollLoop(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Starts the go routines accepting connections and running the
+--						event loop. Exits the program if the epoll instance can't be
+--						created.
+------------------------------------------------------------------------------*/
+func startEpollLoop(srvInfo serverInfo) {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		log.Fatalln("Unable to create the epoll instance:", err)
+	}
+	wakeFD, err := unix.Eventfd(0, unix.EFD_NONBLOCK|unix.EFD_CLOEXEC)
+	if err == nil {
+		err = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, wakeFD, &unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(wakeFD)})
+	}
+	if err != nil {
+		log.Fatalln("Unable to create the event loop's eventfd:", err)
+	}
+
+	loop := &epollLoop{srvInfo: srvInfo, epfd: epfd, wakeFD: wakeFD, added: make(chan *epollConn, epollMaxEvents),
+		conns: make(map[int]*epollConn), buffer: make([]byte, epollReadSize)}
+	context.AfterFunc(srvInfo.ctx, loop.wake)
+	go loop.accept()
+	go loop.run()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    accept
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - sets keep-alive on accepted connections
+--              October 15, 2026 - closes connections -allow-cidr and -deny-cidr filter out
+--              October 15, 2026 - closes connections over -rate-limit
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) accept()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Accepts connections and hands the admitted ones to the loop
+--						until the listener is closed or the server stops serving
+--						connections. Accept errors are handled like the workers'.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) accept() {
+	srvInfo := loop.srvInfo
+	var backoff time.Duration
+	for srvInfo.ctx.Err() == nil {
+		srvInfo.acceptGate.wait()
+		conn, err := srvInfo.listener.Accept()
+		if err != nil {
+			var retry bool
+			if backoff, retry = acceptFailed(srvInfo, err, backoff); !retry {
+				return
+			}
+			continue
+		}
+		backoff = 0
+		if turnedAway(srvInfo, conn) {
+			conn.Close()
+			continue
+		}
+		setKeepAlive(conn, srvInfo.config.keepAlive, srvInfo.config.keepAlivePeriod)
+
+		if admitted := loop.admit(conn); admitted != nil {
+			loop.added <- admitted
+			loop.wake()
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    admit
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - logs through the leveled logger
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) admit(conn net.Conn) *epollConn
+--      conn:		a connection that has just been accepted.
+--
+-- RETURNS:     *epollConn the connection, ready for the loop to serve. nil if
+--              it was turned away.
+--
+-- NOTES:			Anything that needs the net.Conn is read before its socket is
+--						taken over. A connection turned away is closed and sent to
+--						the observer here.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) admit(conn net.Conn) *epollConn {
+	srvInfo := loop.srvInfo
+	cfg := srvInfo.config
+	var synLatency time.Duration
+	if cfg.synLatency {
+		synLatency = synToAccept(conn)
+	}
+	fastOpen := cfg.tfo && fastOpened(conn)
+	var peerCred string
+	if cfg.peerCred {
+		peerCred = peerCredentials(conn)
+	}
+	socket, err := newFDConn(conn)
+	if err != nil {
+		srvInfo.logger.Warn("Unable to take over the socket", "host", conn.RemoteAddr(), "err", err)
+		return nil
+	}
+	registered := srvInfo.connections.add(socket)
+	srvInfo.serverConnection <- newConnectionConst
+
+	reason, wait := admitConnection(srvInfo, registered)
+	if reason != "" {
+		connInfo := unservedConnection(socket, 0, reason, srvInfo.clock.Now())
+		connInfo.SynToAccept = synLatency
+		connInfo.FastOpen = fastOpen
+		socket.closeFD()
+		srvInfo.connections.remove(registered)
+		sendClosed(srvInfo, connInfo)
+		return nil
+	}
+	srvInfo.clock.Sleep(wait)
+
+	admitted := &epollConn{socket: socket, registered: registered}
+	admitted.connInfo = connectionInfo{HostName: socket.RemoteAddr().String(), LocalAddr: socket.LocalAddr().String(),
+		PeerCred: peerCred, FastOpen: fastOpen, SynToAccept: synLatency, AdmissionWait: wait,
+		StartTime: srvInfo.clock.Now()}
+	progress := srvInfo.watchdog.watch(admitted.connInfo.HostName)
+	admitted.counted = &countingConn{Conn: registered, connInfo: &admitted.connInfo, transferred: srvInfo.bytesTransferred,
+		countReads: cfg.countReads, failAfter: cfg.failAfter, progress: progress}
+	admitted.state = connectionState{cfg: cfg, dedup: newDedupCache(cfg.dedupWindow, cfg.dedupSize), rng: srvInfo.rng,
+		nonces: srvInfo.nonces, clock: srvInfo.clock, latency: srvInfo.latency, progress: progress, logger: srvInfo.logger}
+
+	return admitted
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    wake
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) wake()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Makes the loop check for added connections and whether the
+--						server has stopped. Safe to call from any go routine.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) wake() {
+	var one [8]byte
+	binary.NativeEndian.PutUint64(one[:], 1)
+	unix.Write(loop.wakeFD, one[:])
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    run
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) run()
+--
+-- RETURNS:     void
+--
+-- NOTES:			The event loop. The sockets are level triggered and each ready
+--						socket gets one read per wait, so a busy client can't starve
+--						the others. A connection with responses waiting to be written
+--						isn't read from until they are.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) run() {
+	events := make([]unix.EpollEvent, epollMaxEvents)
+	for {
+		n, err := unix.EpollWait(loop.epfd, events, -1)
+		if err == unix.EINTR {
+			continue
+		} else if err != nil {
+			log.Fatalln("epoll_wait failed:", err)
+		}
+
+		for _, event := range events[:n] {
+			fd := int(event.Fd)
+			if fd == loop.wakeFD {
+				loop.woken()
+				continue
+			}
+			conn, ok := loop.conns[fd]
+			if !ok {
+				continue
+			}
+			if event.Events&(unix.EPOLLOUT|unix.EPOLLHUP|unix.EPOLLERR) != 0 && len(conn.output) > 0 {
+				loop.flush(conn)
+			}
+			if loop.conns[fd] == conn && conn.events&unix.EPOLLIN != 0 &&
+				event.Events&(unix.EPOLLIN|unix.EPOLLHUP|unix.EPOLLERR) != 0 {
+				loop.readable(conn)
+			}
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    woken
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) woken()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Registers the added connections. Once the server has stopped
+--						serving connections every connection is closed.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) woken() {
+	var count [8]byte
+	unix.Read(loop.wakeFD, count[:])
+
+	for drained := false; !drained; {
+		select {
+		case conn := <-loop.added:
+			loop.register(conn)
+		default:
+			drained = true
+		}
+	}
+
+	if err := loop.srvInfo.ctx.Err(); err != nil {
+		for _, conn := range loop.conns {
+			loop.close(conn, err)
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    register
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) register(conn *epollConn)
+--      conn:		an admitted connection.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) register(conn *epollConn) {
+	fd := conn.socket.fd
+	err := unix.EpollCtl(loop.epfd, unix.EPOLL_CTL_ADD, fd, &unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(fd)})
+	if err != nil {
+		loop.close(conn, err)
+		return
+	}
+
+	conn.events = unix.EPOLLIN
+	loop.conns[fd] = conn
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readable
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) readable(conn *epollConn)
+--      conn:		a connection the loop was told is ready to read.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Whole requests that arrived before the client closed the
+--						connection are still answered.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) readable(conn *epollConn) {
+	n, err := conn.counted.Read(loop.buffer)
+	if errors.Is(err, unix.EAGAIN) {
+		return
+	}
+	if n > 0 {
+		conn.pending = append(conn.pending, loop.buffer[:n]...)
+		loop.serve(conn)
+	}
+	if err != nil && !conn.closing {
+		conn.closing, conn.closeErr = true, err
+	}
+
+	loop.flush(conn)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    serve
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - logs panics through the leveled logger
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) serve(conn *epollConn)
+--      conn:		a connection that has just been read from.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Answers every whole request read so far, adding the responses
+--						to the connection's output. The framing reads from what is
+--						pending, a request cut short by the end of it is left until
+--						more arrives. A request the framing rejects is answered like
+--						in thread mode and the connection closes. A panic only closes
+--						the connection.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) serve(conn *epollConn) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			loop.srvInfo.logger.Error("Recovered from a panic", "host", conn.connInfo.HostName, "panic", recovered,
+				"stack", string(debug.Stack()))
+			conn.closing, conn.closeErr = true, errServePanic
+		}
+	}()
+
+	cfg := conn.state.cfg
+	source := bytes.NewReader(conn.pending)
+	reader := bufio.NewReader(source)
+	consumed := 0
+	for !conn.closing {
+		data, err := readRequest(reader, cfg)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			if nack := nackResponse(err, conn.connInfo.NumberOfRequests+1, cfg); nack != nil {
+				conn.output = append(conn.output, nack...)
+			}
+			conn.closing, conn.closeErr = true, err
+			break
+		}
+		consumed = len(conn.pending) - source.Len() - reader.Buffered()
+
+		if conn.received == nil {
+			conn.state.progress.begin()
+		}
+		conn.received = append(conn.received, conn.state.clock.Now())
+		conn.output = append(conn.output, frameResponse(processRequest(data, &conn.connInfo, &conn.state), cfg)...)
+		if limit := cfg.maxBytesPerConn; limit > 0 && conn.connInfo.BytesReceived >= limit {
+			conn.closing, conn.closeErr = true, errByteCap
+		}
+	}
+	conn.pending = append(conn.pending[:0], conn.pending[consumed:]...)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    flush
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) flush(conn *epollConn)
+--      conn:		a connection that may have output to write.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Writes as much of the output as the socket takes. The loop
+--						waits for the socket to be writable while any is left, and
+--						for it to be readable again once it is all written. A
+--						closing connection is closed once its output is written.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) flush(conn *epollConn) {
+	for len(conn.output) > 0 {
+		n, err := conn.counted.Write(conn.output)
+		conn.output = conn.output[n:]
+		if errors.Is(err, unix.EAGAIN) {
+			break
+		} else if err != nil {
+			loop.close(conn, err)
+			return
+		}
+	}
+
+	if len(conn.output) == 0 {
+		conn.output = nil
+		if conn.received != nil {
+			conn.state.latency.record(conn.received, conn.state.clock.Now())
+			conn.received = nil
+			conn.state.progress.end()
+		}
+		if conn.closing {
+			loop.close(conn, conn.closeErr)
+			return
+		}
+	}
+
+	events := uint32(unix.EPOLLIN)
+	if len(conn.output) > 0 {
+		events = unix.EPOLLOUT
+	}
+	if events != conn.events {
+		conn.events = events
+		err := unix.EpollCtl(loop.epfd, unix.EPOLL_CTL_MOD, conn.socket.fd, &unix.EpollEvent{Events: events, Fd: int32(conn.socket.fd)})
+		if err != nil {
+			loop.close(conn, err)
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    close
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (loop *epollLoop) close(conn *epollConn, err error)
+--      conn:		the connection to close.
+--       err:		why it is closing, io.EOF if the client closed it.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Closing the socket takes it out of the epoll instance. The
+--						connection is sent to the observer like a worker's.
+------------------------------------------------------------------------------*/
+func (loop *epollLoop) close(conn *epollConn, err error) {
+	srvInfo := loop.srvInfo
+	connInfo := &conn.connInfo
+	connInfo.CloseReason = epollCloseReason(connInfo, err, loop.srvInfo.logger)
+	if err == errInjectedFailure {
+		unix.SetsockoptLinger(conn.socket.fd, unix.SOL_SOCKET, unix.SO_LINGER, &unix.Linger{Onoff: 1, Linger: 0})
+	}
+	connInfo.Stalls = conn.state.progress.stalls()
+	if connInfo.BytesReceived > 0 {
+		connInfo.ResponseRatio = float64(connInfo.BytesSent) / float64(connInfo.BytesReceived)
+	}
+	connInfo.EndTime = srvInfo.clock.Now()
+	connInfo.Duration = connInfo.EndTime.Sub(connInfo.StartTime)
+
+	delete(loop.conns, conn.socket.fd)
+	conn.socket.closeFD()
+	srvInfo.connections.remove(conn.registered)
+	srvInfo.connectionLimit.release()
+	srvInfo.watchdog.unwatch(conn.state.progress)
+	sendClosed(srvInfo, *connInfo)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    epollCloseReason
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - logs through the leveled logger, EOF at debug
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func epollCloseReason(connInfo *connectionInfo, err error, logger *slog.Logger) string
+--  connInfo:		the connection that is closing.
+--       err:		why it is closing.
+--    logger:		the server's log.
+--
+-- RETURNS:     string the CloseReason thread mode would give the connection.
+------------------------------------------------------------------------------*/
+func epollCloseReason(connInfo *connectionInfo, err error, logger *slog.Logger) string {
+	switch {
+	case err == io.EOF:
+		logger.Debug("Client closed the connection", "host", connInfo.HostName)
+		return ""
+	case errors.Is(err, errClosedByShutdown) || errors.Is(err, context.Canceled):
+		return closeReasonShutdown
+	case err == errByteCap:
+		return closeReasonByteCap
+	case err == errRequestTooSmall:
+		return closeReasonUndersized
+	case err == errRequestTooLarge:
+		logger.Warn("Closing the connection", "host", connInfo.HostName, "err", err)
+		return closeReasonOversized
+	case err == errInjectedFailure:
+		return closeReasonInjectedFailure
+	case err == errServePanic:
+		return closeReasonPanic
+	}
+	logger.Warn("Connection failed", "host", connInfo.HostName, "err", err)
+
+	return "error"
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newFDConn
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newFDConn(conn net.Conn) (*fdConn, error)
+--      conn:		a TCP or Unix connection from Accept.
+--
+-- RETURNS:     *fdConn a duplicate of the connection's socket.
+--              error any error duplicating it.
+--
+-- NOTES:			conn is always closed. The duplicate is non-blocking as it
+--						shares the socket's flags with conn.
+------------------------------------------------------------------------------*/
+func newFDConn(conn net.Conn) (*fdConn, error) {
+	defer conn.Close()
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, errors.New("the connection has no socket")
+	}
+	raw, err := sysConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	socket := &fdConn{local: conn.LocalAddr(), remote: conn.RemoteAddr()}
+	controlErr := raw.Control(func(fd uintptr) {
+		socket.fd, err = unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+	})
+	if controlErr != nil {
+		return nil, controlErr
+	} else if err != nil {
+		return nil, err
+	}
+
+	return socket, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Read
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) Read(b []byte) (int, error)
+--         b:		where the data read is put.
+--
+-- RETURNS:     int the bytes read.
+--              error unix.EAGAIN if there is nothing to read, io.EOF once the
+--              client has closed the connection.
+------------------------------------------------------------------------------*/
+func (c *fdConn) Read(b []byte) (int, error) {
+	n, err := unix.Read(c.fd, b)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Write
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) Write(b []byte) (int, error)
+--         b:		the data to write.
+--
+-- RETURNS:     int the bytes written, which may be fewer than len(b).
+--              error unix.EAGAIN if the socket can't take any more.
+------------------------------------------------------------------------------*/
+func (c *fdConn) Write(b []byte) (int, error) {
+	n, err := unix.Write(c.fd, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Close
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) Close() error
+--
+-- RETURNS:     error any error shutting the socket down.
+--
+-- NOTES:			Only shuts the socket down, the loop sees the connection end
+--						and closes it with closeFD. That way the shutdown can close
+--						connections without the loop's socket being reused under it.
+------------------------------------------------------------------------------*/
+func (c *fdConn) Close() error {
+	return unix.Shutdown(c.fd, unix.SHUT_RDWR)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    closeFD
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) closeFD()
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (c *fdConn) closeFD() {
+	unix.Close(c.fd)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    LocalAddr
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) LocalAddr() net.Addr
+--
+-- RETURNS:     net.Addr the address the connection was accepted on.
+------------------------------------------------------------------------------*/
+func (c *fdConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    RemoteAddr
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) RemoteAddr() net.Addr
+--
+-- RETURNS:     net.Addr the client's address.
+------------------------------------------------------------------------------*/
+func (c *fdConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    SetDeadline
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) SetDeadline(t time.Time) error
+--
+-- RETURNS:     error always errNoDeadlines, nothing on the socket waits.
+------------------------------------------------------------------------------*/
+func (c *fdConn) SetDeadline(t time.Time) error {
+	return errNoDeadlines
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    SetReadDeadline
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) SetReadDeadline(t time.Time) error
+--
+-- RETURNS:     error always errNoDeadlines.
+------------------------------------------------------------------------------*/
+func (c *fdConn) SetReadDeadline(t time.Time) error {
+	return errNoDeadlines
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    SetWriteDeadline
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (c *fdConn) SetWriteDeadline(t time.Time) error
+--
+-- RETURNS:     error always errNoDeadlines.
+------------------------------------------------------------------------------*/
+func (c *fdConn) SetWriteDeadline(t time.Time) error {
+	return errNoDeadlines
+}
@@ -0,0 +1,88 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 epoll_linux_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestEpollConcurrentConnections(t *testing.T)
+--
+-- NOTES: Tests for the -mode epoll event loop, which is only built on Linux.
+--        The loop and the observer run in their own go routines and the
+--        clients connect over TCP.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEpollConcurrentConnections checks that the event loop echoes several
+// clients at once, including pipelined requests and a line larger than one
+// read, and that their connections reach the observer.
+func TestEpollConcurrentConnections(t *testing.T) {
+	const clients = 20
+	srvInfo := newTestServerInfo(serverConfig{mode: modeEpoll, proto: protoTCP, framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	startEpollLoop(srvInfo)
+	go observerLoop(srvInfo, nil)
+
+	large := strings.Repeat("x", 4*epollReadSize) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Error("dial:", err)
+				return
+			}
+			defer client.Close()
+			client.SetDeadline(time.Now().Add(5 * time.Second))
+			reader := bufio.NewReader(client)
+			for _, request := range []string{"hello\n", "one\ntwo\n", large} {
+				go io.WriteString(client, request)
+				for _, want := range strings.SplitAfter(request, "\n")[:strings.Count(request, "\n")] {
+					if echo, err := reader.ReadString('\n'); echo != want {
+						t.Errorf("the echo was %d bytes, %v, want %d", len(echo), err, len(want))
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := readStats(srvInfo)
+	for deadline := time.Now().Add(5 * time.Second); snapshot.TotalConnections < clients && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		snapshot = readStats(srvInfo)
+	}
+	if snapshot.TotalConnections != clients || snapshot.TotalRequests != 4*clients {
+		t.Errorf("%d connections and %d requests recorded, want %d and %d",
+			snapshot.TotalConnections, snapshot.TotalRequests, clients, 4*clients)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 epoll_other.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startEpollLoop(srvInfo serverInfo)
+--
+-- NOTES: The epoll loop is only built on Linux, parseConfig refuses -mode
+--        epoll elsewhere.
+------------------------------------------------------------------------------*/
+package main
+
+import "log"
+
+// epollSupported whether -mode epoll can be used on this platform.
+const epollSupported = false
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startEpollLoop
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startEpollLoop(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     never, exits the program.
+------------------------------------------------------------------------------*/
+func startEpollLoop(srvInfo serverInfo) {
+	log.Fatalln("-mode epoll is only supported on Linux")
+}
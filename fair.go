@@ -0,0 +1,222 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 fair.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func parseWeights(weights string) (map[string]float64, error)
+--  func newFairScheduler(slots int, weights map[string]float64) *fairScheduler
+--  func (scheduler *fairScheduler) acquire(tag string, clock Clock) time.Duration
+--  func (scheduler *fairScheduler) release()
+--  func (scheduler *fairScheduler) grant()
+--
+-- NOTES: This file shares the server's processing between the values of a
+--        connection label so that QoS classes can be simulated. Only
+--        -fair-slots requests are processed at once. When more are waiting
+--        the slots are handed out by stride scheduling: each tag is charged
+--        1/weight for every slot it is given and the waiting tag that has
+--        been charged least goes next, so under contention a tag with
+--        weight 3 is given three slots for every one a tag with weight 1 is.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultWeight the weight of a tag -fair-weights doesn't list.
+const defaultWeight = 1
+
+// tagWeights the weight of each -fair-label value, parsed from -fair-weights.
+type tagWeights map[string]float64
+
+// fairScheduler the processing slots shared by every worker.
+type fairScheduler struct {
+	mutex   sync.Mutex
+	free    int                // slots not in use
+	weights map[string]float64 // the weight of each listed tag
+	pass    map[string]float64 // what each tag has been charged for its slots
+	virtual float64            // the charge of the last tag given a slot
+	waiting []fairWaiter       // requests waiting for a slot, oldest first
+}
+
+// fairWaiter a request waiting for a slot.
+type fairWaiter struct {
+	tag     string
+	granted chan bool // closed once the request has been given a slot
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parseWeights
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parseWeights(weights string) (map[string]float64, error)
+--   weights:		"tag=weight,tag=weight", as given to -fair-weights.
+--
+-- RETURNS:     map[string]float64 the weight of each tag.
+--              error any entry that isn't a tag with a positive weight.
+------------------------------------------------------------------------------*/
+func parseWeights(weights string) (map[string]float64, error) {
+	parsed := make(map[string]float64)
+	if weights == "" {
+		return parsed, nil
+	}
+	for _, entry := range strings.Split(weights, ",") {
+		tag, value, found := strings.Cut(entry, "=")
+		weight, err := strconv.ParseFloat(value, 64)
+		if !found || !validLabelText(tag, maxLabelValue) || err != nil || weight <= 0 {
+			return nil, errors.New("invalid weight " + strconv.Quote(entry))
+		}
+		parsed[tag] = weight
+	}
+
+	return parsed, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newFairScheduler
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newFairScheduler(slots int, weights map[string]float64) *fairScheduler
+--     slots:		the most requests processed at once.
+--   weights:		the weight of each tag, unlisted tags have defaultWeight.
+--
+-- RETURNS:     *fairScheduler a scheduler with every slot free, nil if slots
+--              is 0.
+------------------------------------------------------------------------------*/
+func newFairScheduler(slots int, weights map[string]float64) *fairScheduler {
+	if slots <= 0 {
+		return nil
+	}
+
+	return &fairScheduler{free: slots, weights: weights, pass: make(map[string]float64)}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    acquire
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (scheduler *fairScheduler) acquire(tag string, clock Clock) time.Duration
+--       tag:		the value of the -fair-label label on the connection.
+--     clock:		the clock the wait is timed by.
+--
+-- RETURNS:     time.Duration how long the request waited for its slot.
+--
+-- NOTES:			Blocks until the request is given a slot, which must be handed
+--						back with release. A tag that starts waiting is charged from
+--						the current virtual time so an idle tag can't save up slots.
+------------------------------------------------------------------------------*/
+func (scheduler *fairScheduler) acquire(tag string, clock Clock) time.Duration {
+	start := clock.Now()
+	waiter := fairWaiter{tag: tag, granted: make(chan bool)}
+
+	scheduler.mutex.Lock()
+	if scheduler.pass[tag] < scheduler.virtual {
+		scheduler.pass[tag] = scheduler.virtual
+	}
+	scheduler.waiting = append(scheduler.waiting, waiter)
+	scheduler.grant()
+	scheduler.mutex.Unlock()
+
+	<-waiter.granted
+
+	return clock.Now().Sub(start)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    release
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (scheduler *fairScheduler) release()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Hands back a slot and gives it to the next waiting request.
+------------------------------------------------------------------------------*/
+func (scheduler *fairScheduler) release() {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.free++
+	scheduler.grant()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    grant
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (scheduler *fairScheduler) grant()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Must be called with the scheduler's mutex held. Gives the free
+--						slots to the waiting requests whose tags have been charged
+--						least, the oldest request first between equal charges.
+------------------------------------------------------------------------------*/
+func (scheduler *fairScheduler) grant() {
+	for scheduler.free > 0 && len(scheduler.waiting) > 0 {
+		next := 0
+		for i, waiter := range scheduler.waiting {
+			if scheduler.pass[waiter.tag] < scheduler.pass[scheduler.waiting[next].tag] {
+				next = i
+			}
+		}
+		waiter := scheduler.waiting[next]
+		scheduler.waiting = append(scheduler.waiting[:next], scheduler.waiting[next+1:]...)
+
+		weight, ok := scheduler.weights[waiter.tag]
+		if !ok {
+			weight = defaultWeight
+		}
+		scheduler.virtual = scheduler.pass[waiter.tag]
+		scheduler.pass[waiter.tag] += 1 / weight
+		scheduler.free--
+		close(waiter.granted)
+	}
+}
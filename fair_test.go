@@ -0,0 +1,96 @@
+/*
+------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 fair_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestFairSchedulerWeights(t *testing.T)
+--  func TestParseWeightsInvalid(t *testing.T)
+--
+-- NOTES: Tests for sharing the processing slots between tags. The requests
+--        waiting for a slot are queued up before any is handed out, so the
+--        order they are granted in depends only on the weights.
+------------------------------------------------------------------------------
+*/
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestFairSchedulerWeights checks that under contention for a single slot
+// each tag is granted slots in proportion to its weight.
+func TestFairSchedulerWeights(t *testing.T) {
+	const queued, granted = 30, 20
+	tests := []struct {
+		weights     string
+		gold, slack int // the slots gold should get of the first granted, and by how many it may be off
+	}{
+		{"gold=3,bronze=1", 15, 1},
+		{"gold=1", 10, 1},
+		{"bronze=4", 4, 1},
+	}
+	for _, test := range tests {
+		weights, err := parseWeights(test.weights)
+		if err != nil {
+			t.Fatal("parseWeights:", err)
+		}
+		clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+		scheduler := newFairScheduler(1, weights)
+		scheduler.acquire("", clock) // the slot is busy while the requests queue
+
+		grants := make(chan string)
+		for i := 0; i < queued; i++ {
+			for _, tag := range []string{"gold", "bronze"} {
+				go func() {
+					scheduler.acquire(tag, clock)
+					grants <- tag
+				}()
+			}
+		}
+		for {
+			scheduler.mutex.Lock()
+			waiting := len(scheduler.waiting)
+			scheduler.mutex.Unlock()
+			if waiting == 2*queued {
+				break
+			}
+			runtime.Gosched()
+		}
+
+		gold := 0
+		for i := 0; i < granted; i++ {
+			scheduler.release()
+			if <-grants == "gold" {
+				gold++
+			}
+		}
+		for i := granted; i < 2*queued; i++ {
+			scheduler.release()
+			<-grants
+		}
+		if gold < test.gold-test.slack || gold > test.gold+test.slack {
+			t.Errorf("with %s gold got %d of %d slots, want %d", test.weights, gold, granted, test.gold)
+		}
+	}
+}
+
+// TestParseWeightsInvalid checks that weights that aren't a positive number
+// for a valid label value are rejected.
+func TestParseWeightsInvalid(t *testing.T) {
+	for _, weights := range []string{"gold", "gold=", "gold=0", "gold=-1", "gold=x", "=1", "gold=1,"} {
+		if _, err := parseWeights(weights); err == nil {
+			t.Errorf("parseWeights(%q) succeeded", weights)
+		}
+	}
+}
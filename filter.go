@@ -0,0 +1,169 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 filter.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func (list *cidrList) String() string
+--  func (list *cidrList) Set(value string) error
+--  func newIPFilter(allow cidrList, deny cidrList) *ipFilter
+--  func (filter *ipFilter) permits(addr net.Addr) bool
+--
+-- NOTES: This file restricts which clients can connect with -allow-cidr and
+--        -deny-cidr, for benchmarking somewhere only some hosts should reach
+--        the server. Both flags can be given more than once. A client
+--        matching a denied network is closed as soon as it is accepted, as
+--        is one outside every allowed network if any are given. Filtered
+--        connections are logged but never reach the observer, so they
+--        don't take a worker or show up in the report.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// cidrList the networks given to a repeatable CIDR flag.
+type cidrList []*net.IPNet
+
+// ipFilter the networks clients are allowed and denied from.
+type ipFilter struct {
+	allow cidrList // empty to allow every client that isn't denied
+	deny  cidrList
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    String
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (list *cidrList) String() string
+--
+-- RETURNS:     string the networks, comma separated.
+--
+-- NOTES:			Part of flag.Value.
+------------------------------------------------------------------------------*/
+func (list *cidrList) String() string {
+	if list == nil {
+		return ""
+	}
+	networks := make([]string, 0, len(*list))
+	for _, network := range *list {
+		networks = append(networks, network.String())
+	}
+
+	return strings.Join(networks, ",")
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Set
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (list *cidrList) Set(value string) error
+--     value:		a network in CIDR notation, e.g. 10.0.0.0/8.
+--
+-- RETURNS:     error any error parsing value.
+--
+-- NOTES:			Part of flag.Value, called once for each time the flag is given.
+------------------------------------------------------------------------------*/
+func (list *cidrList) Set(value string) error {
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return err
+	}
+	*list = append(*list, network)
+
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newIPFilter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newIPFilter(allow cidrList, deny cidrList) *ipFilter
+--     allow:		the -allow-cidr networks.
+--      deny:		the -deny-cidr networks.
+--
+-- RETURNS:     *ipFilter the filter, nil if neither list has a network.
+------------------------------------------------------------------------------*/
+func newIPFilter(allow cidrList, deny cidrList) *ipFilter {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	return &ipFilter{allow: allow, deny: deny}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    permits
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (filter *ipFilter) permits(addr net.Addr) bool
+--      addr:		the remote address of a connection that was just accepted.
+--
+-- RETURNS:     bool whether the client may be served. true on a nil filter.
+--
+-- NOTES:			Denying takes precedence over allowing. An address without an
+--						IP can't be filtered and is permitted, the flags are only
+--						accepted with a TCP -network.
+------------------------------------------------------------------------------*/
+func (filter *ipFilter) permits(addr net.Addr) bool {
+	if filter == nil {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return true
+	}
+
+	for _, network := range filter.deny {
+		if network.Contains(tcpAddr.IP) {
+			return false
+		}
+	}
+	if len(filter.allow) == 0 {
+		return true
+	}
+	for _, network := range filter.allow {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 filter_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestIPFilterPermits(t *testing.T)
+--  func TestDeniedLoopbackClosed(t *testing.T)
+--
+-- NOTES: Tests for the -allow-cidr and -deny-cidr filter, on addresses and on
+--        a worker accepting over TCP.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestIPFilterPermits checks that only allowed addresses are permitted, that
+// denying takes precedence over allowing and that no filter permits anything.
+func TestIPFilterPermits(t *testing.T) {
+	var allow, deny cidrList
+	allow.Set("192.0.2.0/24")
+	deny.Set("192.0.2.128/25")
+	filter := newIPFilter(allow, deny)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.1", true},
+		{"192.0.2.200", false},
+		{"198.51.100.1", false},
+	}
+	for _, test := range tests {
+		if got := filter.permits(tcpAddr(test.ip, 40000)); got != test.want {
+			t.Errorf("permits(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+	if !newIPFilter(nil, nil).permits(tcpAddr("198.51.100.1", 40000)) {
+		t.Error("a server without a filter turned a client away")
+	}
+}
+
+// TestDeniedLoopbackClosed checks that with loopback denied a local client is
+// closed without being echoed, and without the observer being told of the
+// connection.
+func TestDeniedLoopbackClosed(t *testing.T) {
+	var deny cidrList
+	deny.Set("127.0.0.0/8")
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	srvInfo.ipFilter = newIPFilter(nil, deny)
+	srvInfo.serverConnection = make(chan int, 1)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	go worker(srvInfo.ctx, srvInfo, 1)
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(client, "hello\n")
+	if echo, err := io.ReadAll(client); len(echo) != 0 || err != nil && !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("the denied client read %q, %v, want to be closed without an echo", echo, err)
+	}
+	if len(srvInfo.serverConnection) != 0 {
+		t.Error("the observer was told of the denied connection")
+	}
+}
@@ -0,0 +1,360 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 formatter.go
+--
+-- REVISIONS: 	October 15, 2026 - Connections are written one at a time
+--              October 15, 2026 - The label groups follow the summary
+--              October 15, 2026 - The top clients follow the summary
+--              October 15, 2026 - The lifetime histogram follows the summary
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func registerReportFormatter(name string, extension string, formatter ReportFormatter)
+--  func (textFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--  func (jsonFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--  func (csvFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--  func textTable(w io.Writer, rows interface{})
+--  func csvTable(writer *csv.Writer, rows interface{})
+--  func reflectFields(i interface{}) ([]string, []string)
+--
+-- NOTES: This file holds the formats the report can be written in. A new
+--        format is added by registering a ReportFormatter under the name
+--        given to -report-format. Formatters should write each connection as
+--        they walk the list so a long run doesn't need a second copy of every
+--        connection in memory.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"container/list"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ReportFormatter writes the report in a particular format. connections is a
+// list of connectionInfo.
+type ReportFormatter interface {
+	Format(w io.Writer, connections *list.List, summary reportSummary) error
+}
+
+// reportFormat a registered ReportFormatter and the extension of its files.
+type reportFormat struct {
+	formatter ReportFormatter
+	extension string
+}
+
+// reportFormatters the formats -report-format can pick from, by name.
+var reportFormatters = map[string]reportFormat{
+	"xlsx": {xlsxFormatter{}, "xlsx"},
+	"text": {textFormatter{}, "txt"},
+	"json": {jsonFormatter{}, "json"},
+	"csv":  {csvFormatter{}, "csv"},
+}
+
+// textChunkRows the rows the text report aligns at a time.
+const textChunkRows = 1024
+
+// textFormatter writes the report as aligned plain text.
+type textFormatter struct{}
+
+// jsonFormatter writes the report as a single JSON object.
+type jsonFormatter struct{}
+
+// csvFormatter writes a row per connection followed by the summary.
+type csvFormatter struct{}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    registerReportFormatter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func registerReportFormatter(name string, extension string, formatter ReportFormatter)
+--      name:		the name used to pick the format with -report-format.
+-- extension:		the extension given to the report file.
+-- formatter:		writes the report.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Must be called before the command line is parsed. Registering
+--						an existing name replaces it.
+------------------------------------------------------------------------------*/
+func registerReportFormatter(name string, extension string, formatter ReportFormatter) {
+	reportFormatters[name] = reportFormat{formatter: formatter, extension: extension}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Format
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - lists the label groups
+--              October 15, 2026 - lists the top clients and lifetime histogram
+--              October 15, 2026 - lists the TLS distribution
+--              October 15, 2026 - lists the response ratios
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (textFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--         w:		where the report is written.
+-- connections:	A list of connectionInfo to be reported.
+--   summary:		the totals across every connection.
+--
+-- RETURNS:     error any error writing the report.
+--
+-- NOTES:			Columns are aligned textChunkRows rows at a time, the header is
+--						repeated at the start of each chunk. The lifetime histogram,
+--						top clients and label groups are listed as tables after the
+--						summary.
+------------------------------------------------------------------------------*/
+func (textFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	rows := 0
+	for e := connections.Front(); e != nil; e = e.Next() {
+		names, values := reflectFields(e.Value)
+		if rows%textChunkRows == 0 {
+			if err := table.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintln(table, strings.Join(names, "\t"))
+		}
+		fmt.Fprintln(table, strings.Join(values, "\t"))
+		rows++
+	}
+	if rows > 0 {
+		fmt.Fprintln(table)
+	}
+
+	names, values := reflectFields(summary)
+	for i := range names {
+		fmt.Fprintf(table, "%s:\t%s\n", names[i], values[i])
+	}
+	textTable(table, summary.LifetimeHistogram)
+	textTable(table, summary.ResponseRatios)
+	textTable(table, summary.TLSDistribution)
+	textTable(table, summary.TopClients)
+	textTable(table, summary.LabelGroups)
+
+	return table.Flush()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Format
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (jsonFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--         w:		where the report is written.
+-- connections:	A list of connectionInfo to be reported.
+--   summary:		the totals across every connection.
+--
+-- RETURNS:     error any error writing the report.
+--
+-- NOTES:			Durations are written in nanoseconds. The object is written a
+--						connection at a time rather than being marshalled whole.
+------------------------------------------------------------------------------*/
+func (jsonFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error {
+	io.WriteString(w, "{\n  \"Connections\": [")
+	separator := "\n    "
+	for e := connections.Front(); e != nil; e = e.Next() {
+		connInfo, err := json.MarshalIndent(e.Value, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, separator)
+		if _, err = w.Write(connInfo); err != nil {
+			return err
+		}
+		separator = ",\n    "
+	}
+
+	totals, err := json.MarshalIndent(summary, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, "\n  ],\n  \"Summary\": ")
+	w.Write(totals)
+	_, err = io.WriteString(w, "\n}\n")
+
+	return err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Format
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - lists the label groups
+--              October 15, 2026 - lists the top clients and lifetime histogram
+--              October 15, 2026 - lists the TLS distribution
+--              October 15, 2026 - lists the response ratios
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (csvFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--         w:		where the report is written.
+-- connections:	A list of connectionInfo to be reported.
+--   summary:		the totals across every connection.
+--
+-- RETURNS:     error any error writing the report.
+--
+-- NOTES:			The summary follows the connections after an empty row, with
+--						a name and value on each row. The lifetime histogram, top
+--						clients and label groups follow the summary, each after
+--						another empty row.
+------------------------------------------------------------------------------*/
+func (csvFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error {
+	writer := csv.NewWriter(w)
+	for e := connections.Front(); e != nil; e = e.Next() {
+		names, values := reflectFields(e.Value)
+		if e == connections.Front() {
+			writer.Write(names)
+		}
+		writer.Write(values)
+	}
+	if connections.Len() > 0 {
+		writer.Write([]string{})
+	}
+
+	names, values := reflectFields(summary)
+	for i := range names {
+		writer.Write([]string{names[i], values[i]})
+	}
+	csvTable(writer, summary.LifetimeHistogram)
+	csvTable(writer, summary.ResponseRatios)
+	csvTable(writer, summary.TLSDistribution)
+	csvTable(writer, summary.TopClients)
+	csvTable(writer, summary.LabelGroups)
+	writer.Flush()
+
+	return writer.Error()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    textTable
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func textTable(w io.Writer, rows interface{})
+--         w:		the tabwriter the report is being written to.
+--      rows:		a slice of structures to list.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Nothing is written for an empty slice, otherwise the rows
+--						follow an empty line and a header.
+------------------------------------------------------------------------------*/
+func textTable(w io.Writer, rows interface{}) {
+	slice := reflect.ValueOf(rows)
+	for i := 0; i < slice.Len(); i++ {
+		names, values := reflectFields(slice.Index(i).Interface())
+		if i == 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, strings.Join(names, "\t"))
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    csvTable
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func csvTable(writer *csv.Writer, rows interface{})
+--    writer:		the writer the report is being written to.
+--      rows:		a slice of structures to list.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Nothing is written for an empty slice, otherwise the rows
+--						follow an empty row and a header.
+------------------------------------------------------------------------------*/
+func csvTable(writer *csv.Writer, rows interface{}) {
+	slice := reflect.ValueOf(rows)
+	for i := 0; i < slice.Len(); i++ {
+		names, values := reflectFields(slice.Index(i).Interface())
+		if i == 0 {
+			writer.Write([]string{})
+			writer.Write(names)
+		}
+		writer.Write(values)
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    reflectFields
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func reflectFields(i interface{}) ([]string, []string)
+--         i:		the structure to be reported.
+--
+-- RETURNS:     []string the names of i's fields.
+--              []string the values of i's fields.
+--
+-- NOTES:			Like generateRow this uses reflect so new fields are reported
+--						without changing the formatters. Slices are skipped and times
+--						are written without their monotonic clock reading.
+------------------------------------------------------------------------------*/
+func reflectFields(i interface{}) ([]string, []string) {
+	fields := reflect.ValueOf(i)
+	var names, values []string
+	for i := 0; i < fields.NumField(); i++ {
+		if fields.Field(i).Kind() == reflect.Slice {
+			continue
+		}
+		names = append(names, fields.Type().Field(i).Name)
+		if t, ok := fields.Field(i).Interface().(time.Time); ok {
+			values = append(values, t.Format(time.RFC3339Nano))
+		} else {
+			values = append(values, fmt.Sprint(fields.Field(i).Interface()))
+		}
+	}
+
+	return names, values
+}
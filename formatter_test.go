@@ -0,0 +1,321 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 formatter_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func (formatter *recordingFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--  func TestCustomFormatterInvoked(t *testing.T)
+--  func TestCompressedReportMatches(t *testing.T)
+--  func (watcher *heapWatcher) Write(b []byte) (int, error)
+--  func TestLargeReportBounded(t *testing.T)
+--  func validReport(r io.Reader, format string, records int) error
+--  func TestJSONReportRoundTrip(t *testing.T)
+--  func TestCSVReportRoundTrip(t *testing.T)
+--
+-- NOTES: Tests for the report formatters. Reports are written to a buffer
+--        rather than a file.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// recordingFormatter a ReportFormatter that records what it was given.
+type recordingFormatter struct {
+	hosts   []string
+	summary reportSummary
+}
+
+// Format records the connections and summary, writing a line for the report.
+func (formatter *recordingFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error {
+	for e := connections.Front(); e != nil; e = e.Next() {
+		formatter.hosts = append(formatter.hosts, e.Value.(connectionInfo).HostName)
+	}
+	formatter.summary = summary
+	_, err := io.WriteString(w, "recorded\n")
+
+	return err
+}
+
+// TestCustomFormatterInvoked checks that a registered formatter is picked by
+// its name and given every connection and the summary.
+func TestCustomFormatterInvoked(t *testing.T) {
+	formatter := &recordingFormatter{}
+	registerReportFormatter("recording", "rec", formatter)
+	t.Cleanup(func() { delete(reportFormatters, "recording") })
+
+	connections := list.New()
+	connections.PushBack(connectionInfo{HostName: "192.0.2.1:40000"})
+	connections.PushBack(connectionInfo{HostName: "192.0.2.2:40000"})
+	var report bytes.Buffer
+	if err := generateReport(&report, connections, reportSummary{GrandTotalConnections: 2}, "recording", false); err != nil {
+		t.Fatal("generateReport:", err)
+	}
+
+	if len(formatter.hosts) != 2 || formatter.hosts[0] != "192.0.2.1:40000" || formatter.hosts[1] != "192.0.2.2:40000" {
+		t.Errorf("the formatter was given %q", formatter.hosts)
+	}
+	if formatter.summary.GrandTotalConnections != 2 {
+		t.Errorf("the formatter was given a summary of %d connections, want 2", formatter.summary.GrandTotalConnections)
+	}
+	if report.String() != "recorded\n" {
+		t.Errorf("the report was %q, want what the formatter wrote", report.String())
+	}
+}
+
+// TestCompressedReportMatches checks that a gzipped report decompresses to the
+// report written without -report-compress.
+func TestCompressedReportMatches(t *testing.T) {
+	connections := list.New()
+	connections.PushBack(connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 3, BytesReceived: 15})
+	summary := reportSummary{GrandTotalConnections: 1}
+	for _, format := range []string{"text", "json", "csv"} {
+		var plain, compressed bytes.Buffer
+		if err := generateReport(&plain, connections, summary, format, false); err != nil {
+			t.Fatal("generateReport:", err)
+		}
+		if err := generateReport(&compressed, connections, summary, format, true); err != nil {
+			t.Fatal("generateReport compressed:", err)
+		}
+
+		reader, err := gzip.NewReader(&compressed)
+		if err != nil {
+			t.Fatalf("the %s report isn't gzipped: %v", format, err)
+		}
+		decompressed, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("the %s report is truncated: %v", format, err)
+		}
+		if !bytes.Equal(decompressed, plain.Bytes()) {
+			t.Errorf("the compressed %s report was %q, want %q", format, decompressed, plain.Bytes())
+		}
+	}
+}
+
+// heapWatcher a writer that discards the report, measuring the live heap every
+// so many writes.
+type heapWatcher struct {
+	w       io.Writer
+	writes  int
+	written int
+	peak    uint64
+}
+
+// Write passes b on, collecting garbage and measuring the heap every 64 writes.
+func (watcher *heapWatcher) Write(b []byte) (int, error) {
+	watcher.writes++
+	watcher.written += len(b)
+	if watcher.writes%64 == 0 {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		watcher.peak = max(watcher.peak, stats.HeapAlloc)
+	}
+
+	return watcher.w.Write(b)
+}
+
+// TestLargeReportBounded checks that a report of many connections is written
+// without the heap growing with the size of the report, and is still valid.
+func TestLargeReportBounded(t *testing.T) {
+	const records, growth = 20000, 4 << 20
+	connections := list.New()
+	for i := 0; i < records; i++ {
+		connections.PushBack(connectionInfo{HostName: fmt.Sprintf("192.0.2.%d:%d", i%250+1, 40000+i%20000),
+			LocalAddr: "127.0.0.1:7000", NumberOfRequests: i, BytesReceived: 10 * i, BytesSent: 10 * i,
+			CloseReason: closeReasonIdle})
+	}
+	summary := reportSummary{GrandTotalConnections: records}
+
+	for _, format := range []string{"text", "json", "csv"} {
+		read, write := io.Pipe()
+		valid := make(chan error, 1)
+		go func() {
+			valid <- validReport(read, format, records)
+			io.Copy(io.Discard, read)
+		}()
+
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		watcher := &heapWatcher{w: write, peak: stats.HeapAlloc}
+		err := generateReport(watcher, connections, summary, format, false)
+		write.Close()
+		if err != nil {
+			t.Fatalf("the %s report failed: %v", format, err)
+		}
+
+		if grew := watcher.peak - stats.HeapAlloc; grew > growth {
+			t.Errorf("the heap grew by %d bytes writing a %d byte %s report, want at most %d",
+				grew, watcher.written, format, growth)
+		}
+		if err := <-valid; err != nil {
+			t.Errorf("the %s report isn't valid: %v", format, err)
+		}
+	}
+}
+
+// validReport reads a report from r a piece at a time, checking it is well
+// formed and lists records connections.
+func validReport(r io.Reader, format string, records int) error {
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(r)
+		objects := 0
+		for depth := 0; ; {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			switch token {
+			case json.Delim('{'):
+				if depth == 2 {
+					objects++
+				}
+				depth++
+			case json.Delim('['):
+				depth++
+			case json.Delim('}'), json.Delim(']'):
+				depth--
+			}
+		}
+		if objects < records {
+			return fmt.Errorf("%d connections listed, want %d", objects, records)
+		}
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		rows := 0
+		for {
+			if _, err := reader.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			rows++
+		}
+		if rows <= records {
+			return fmt.Errorf("%d rows, want more than the %d connections", rows, records)
+		}
+	default:
+		scanner := bufio.NewScanner(r)
+		lines := 0
+		for scanner.Scan() {
+			lines++
+		}
+		if lines <= records {
+			return fmt.Errorf("%d lines, want more than the %d connections", lines, records)
+		}
+	}
+
+	return nil
+}
+
+// TestJSONReportRoundTrip checks that the connections and summary in a JSON
+// report unmarshal back to what was reported.
+func TestJSONReportRoundTrip(t *testing.T) {
+	start := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+	want := []connectionInfo{
+		{HostName: "192.0.2.1:40000", LocalAddr: "127.0.0.1:7000", NumberOfRequests: 3, BytesReceived: 15,
+			BytesSent: 15, ResponseRatio: 1, ConnectionsAtClose: 2, WorkerID: 4, Duration: 1500 * time.Millisecond,
+			CloseReason: closeReasonIdle, Labels: map[string]string{"region": "east"},
+			StartTime: start, EndTime: start.Add(1500 * time.Millisecond)},
+		{HostName: "192.0.2.2:40001", TLSVersion: "TLS 1.3", DelayedRequests: 1,
+			ResponseDelay: 10 * time.Millisecond, StartTime: start, EndTime: start.Add(time.Second)},
+	}
+	connections := list.New()
+	for _, connInfo := range want {
+		connections.PushBack(connInfo)
+	}
+	summary := reportSummary{GeneratedAt: start.Add(time.Minute), TotalConnections: 2, PeakConnections: 2,
+		GrandTotalConnections: 2, TotalRequests: 4}
+
+	var report bytes.Buffer
+	if err := generateReport(&report, connections, summary, "json", false); err != nil {
+		t.Fatal("generateReport:", err)
+	}
+	var parsed struct {
+		Connections []connectionInfo
+		Summary     reportSummary
+	}
+	if err := json.Unmarshal(report.Bytes(), &parsed); err != nil {
+		t.Fatalf("the report doesn't unmarshal: %v\n%s", err, report.Bytes())
+	}
+	if !reflect.DeepEqual(parsed.Connections, want) {
+		t.Errorf("the connections unmarshalled to %+v, want %+v", parsed.Connections, want)
+	}
+	if !reflect.DeepEqual(parsed.Summary, summary) {
+		t.Errorf("the summary unmarshalled to %+v, want %+v", parsed.Summary, summary)
+	}
+}
+
+// TestCSVReportRoundTrip checks that a CSV report has a row for each
+// connection under its header, and that the numeric columns parse back to what
+// was reported.
+func TestCSVReportRoundTrip(t *testing.T) {
+	connections := list.New()
+	for i := 1; i <= 3; i++ {
+		connections.PushBack(connectionInfo{HostName: fmt.Sprintf("192.0.2.%d:40000", i), AmmountOfData: 100 * i,
+			NumberOfRequests: i, ConnectionsAtClose: 4 - i, Duration: time.Duration(i) * 1500 * time.Millisecond})
+	}
+	var report bytes.Buffer
+	if err := generateReport(&report, connections, reportSummary{TotalConnections: 3}, "csv", false); err != nil {
+		t.Fatal("generateReport:", err)
+	}
+
+	reader := csv.NewReader(&report)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal("the report isn't valid CSV:", err)
+	}
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	var rows [][]string
+	for _, record := range records[1:] {
+		if len(record) == len(records[0]) {
+			rows = append(rows, record)
+		}
+	}
+	if len(rows) != connections.Len() {
+		t.Fatalf("%d rows for %d connections", len(rows), connections.Len())
+	}
+
+	for e, row := connections.Front(), 0; e != nil; e, row = e.Next(), row+1 {
+		want := e.Value.(connectionInfo)
+		var got connectionInfo
+		got.HostName = rows[row][column["HostName"]]
+		got.AmmountOfData, _ = strconv.Atoi(rows[row][column["AmmountOfData"]])
+		got.NumberOfRequests, _ = strconv.Atoi(rows[row][column["NumberOfRequests"]])
+		got.ConnectionsAtClose, _ = strconv.Atoi(rows[row][column["ConnectionsAtClose"]])
+		got.Duration, _ = time.ParseDuration(rows[row][column["Duration"]])
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d parsed to %+v, want %+v", row, got, want)
+		}
+	}
+}
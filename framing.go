@@ -0,0 +1,587 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 framing.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func readRequest(reader *bufio.Reader, cfg *serverConfig) ([]byte, error)
+--  func readLine(reader *bufio.Reader, delimiter byte, maxLine int) ([]byte, error)
+--  func parseDelimiter(value string) (byte, error)
+--  func readHeaderRequest(reader *bufio.Reader, maxLine int) ([]byte, error)
+--  func readLengthRequest(reader *bufio.Reader, maxLine int) ([]byte, error)
+--  func waitForRequest(ctx context.Context, conn net.Conn, reader *bufio.Reader, state *connectionState, end time.Time, readDeadline time.Time) bool
+--  func requestBuffered(reader *bufio.Reader, cfg *serverConfig) bool
+--  func frameResponse(payload []byte, cfg *serverConfig) []byte
+--  func closeMessage(message string, cfg *serverConfig) []byte
+--  func writeResponse(ctx context.Context, conn net.Conn, response []byte, state *connectionState) error
+--  func writeChunked(ctx context.Context, conn net.Conn, response []byte, chunks int, delay time.Duration, clock Clock) error
+--  func writeFull(conn net.Conn, data []byte) error
+--
+-- NOTES: This file splits the incoming byte stream into requests and frames
+--        the responses written back to the client.
+--
+--        line:   each request is terminated by -delimiter, a newline by
+--                default, which is echoed back as part of the payload.
+--        header: each request is "Content-Length: N\n\n" followed by N bytes,
+--                the response is framed with the same header.
+--        length: each request is a 4 byte big endian length followed by
+--                that many bytes, so payloads can be any binary data. The
+--                response is framed with the same prefix.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const framingLine = "line"
+const framingHeader = "header"
+const framingLength = "length"
+
+// lengthPrefixSize the size of the length prefix in length framing.
+const lengthPrefixSize = 4
+
+// maxLengthPayload the largest payload accepted in length framing when -max-line
+// is 0, so a bad prefix can't make the server allocate 4GB.
+const maxLengthPayload = 16 << 20
+
+// defaultMaxLine the largest request payload accepted unless -max-line says
+// otherwise, so a client that never sends a delimiter can't exhaust memory.
+const defaultMaxLine = 1 << 20
+
+// maxHeaderLength the longest header line accepted in header framing.
+const maxHeaderLength = 64
+
+var errRequestTooLarge = errors.New("request exceeds the maximum size")
+var errRequestTooSmall = errors.New("request is below the minimum size")
+var errMalformedHeader = errors.New("malformed request header")
+var errInvalidDelimiter = errors.New("the delimiter must be a single byte")
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readRequest
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - requests under -min-request are rejected
+--              October 15, 2026 - lines end with -delimiter
+--              October 15, 2026 - added length framing
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readRequest(reader *bufio.Reader, cfg *serverConfig) ([]byte, error)
+--    reader:		the buffered connection to read from.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the payload of the request.
+--              error  any error reading the request.
+--
+-- NOTES:			Reads one request using the configured framing. A complete
+--						request with a payload under -min-request, not counting the
+--						delimiter, results in errRequestTooSmall.
+------------------------------------------------------------------------------*/
+func readRequest(reader *bufio.Reader, cfg *serverConfig) ([]byte, error) {
+	var data []byte
+	var err error
+	var length int
+	if cfg.framing == framingHeader {
+		data, err = readHeaderRequest(reader, cfg.maxLine)
+		length = len(data)
+	} else if cfg.framing == framingLength {
+		data, err = readLengthRequest(reader, cfg.maxLine)
+		length = len(data)
+	} else {
+		data, err = readLine(reader, cfg.delimiter, cfg.maxLine)
+		length = len(data) - 1 // don't count the delimiter
+	}
+	if err == nil && length < cfg.minRequest {
+		return nil, errRequestTooSmall
+	}
+
+	return data, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readLine
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - lines can end with any delimiter
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readLine(reader *bufio.Reader, delimiter byte, maxLine int) ([]byte, error)
+--    reader:		the buffered connection to read from.
+-- delimiter:		the byte that ends the line.
+--   maxLine:		the longest line accepted, not counting the delimiter. 0
+--						for no limit.
+--
+-- RETURNS:     []byte the line including its delimiter.
+--              error  any error reading the line.
+--
+-- NOTES:			Unlike ReadBytes this stops reading as soon as the line is too
+--						long so a client can't make the server buffer without bound.
+------------------------------------------------------------------------------*/
+func readLine(reader *bufio.Reader, delimiter byte, maxLine int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice(delimiter)
+		line = append(line, chunk...)
+
+		length := len(line)
+		if err == nil {
+			length-- // don't count the delimiter
+		}
+		if maxLine > 0 && length > maxLine {
+			return nil, errRequestTooLarge
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parseDelimiter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parseDelimiter(value string) (byte, error)
+--     value:		the delimiter as given to -delimiter.
+--
+-- RETURNS:     byte  the delimiter.
+--              error errInvalidDelimiter if value isn't a single byte.
+--
+-- NOTES:			Accepts a literal byte or a Go escape such as \n, \r, \t,
+--						\x00 or \000. \0 is accepted as NUL since that's how it is
+--						usually written.
+------------------------------------------------------------------------------*/
+func parseDelimiter(value string) (byte, error) {
+	if value == `\0` {
+		return 0, nil
+	}
+	if len(value) == 1 {
+		return value[0], nil
+	}
+	delimiter, multibyte, rest, err := strconv.UnquoteChar(value, '\'')
+	if err != nil || multibyte || rest != "" || delimiter > 0xff {
+		return 0, errInvalidDelimiter
+	}
+
+	return byte(delimiter), nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readHeaderRequest
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readHeaderRequest(reader *bufio.Reader, maxLine int) ([]byte, error)
+--    reader:		the buffered connection to read from.
+--   maxLine:		the largest Content-Length accepted. 0 for no limit.
+--
+-- RETURNS:     []byte the body of the request.
+--              error  any error reading the request.
+--
+-- NOTES:			The declared length is checked before the body is allocated.
+--						A body shorter than the declared length results in
+--						io.ErrUnexpectedEOF.
+------------------------------------------------------------------------------*/
+func readHeaderRequest(reader *bufio.Reader, maxLine int) ([]byte, error) {
+	header, err := readLine(reader, '\n', maxHeaderLength)
+	if err != nil {
+		if err == errRequestTooLarge {
+			return nil, errMalformedHeader
+		}
+		return nil, err
+	}
+
+	name, value, found := strings.Cut(strings.TrimRight(string(header), "\r\n"), ":")
+	if !found || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+		return nil, errMalformedHeader
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || length < 0 {
+		return nil, errMalformedHeader
+	}
+	if maxLine > 0 && length > maxLine {
+		return nil, errRequestTooLarge
+	}
+
+	separator, err := readLine(reader, '\n', maxHeaderLength)
+	if err == errRequestTooLarge || (err == nil && strings.TrimRight(string(separator), "\r\n") != "") {
+		return nil, errMalformedHeader
+	} else if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, length)
+	if _, err = io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readLengthRequest
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readLengthRequest(reader *bufio.Reader, maxLine int) ([]byte, error)
+--    reader:		the buffered connection to read from.
+--   maxLine:		the largest length accepted. 0 for maxLengthPayload.
+--
+-- RETURNS:     []byte the body of the request.
+--              error  any error reading the request.
+--
+-- NOTES:			The declared length is checked before the body is allocated.
+--						A prefix or body cut short results in io.ErrUnexpectedEOF, a
+--						connection closed between requests in io.EOF.
+------------------------------------------------------------------------------*/
+func readLengthRequest(reader *bufio.Reader, maxLine int) ([]byte, error) {
+	var prefix [lengthPrefixSize]byte
+	if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+		return nil, err
+	}
+	if maxLine <= 0 {
+		maxLine = maxLengthPayload
+	}
+	length := binary.BigEndian.Uint32(prefix[:])
+	if uint64(length) > uint64(maxLine) {
+		return nil, errRequestTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return body, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    waitForRequest
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - keeps the read deadline it found and times the window on the server clock
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func waitForRequest(ctx context.Context, conn net.Conn, reader *bufio.Reader, state *connectionState, end time.Time, readDeadline time.Time) bool
+--       ctx:		cancelled when the server stops serving connections.
+--      conn:		the connection reader is buffering.
+--    reader:		the buffered connection.
+--     state:		the state kept for the connection.
+--       end:		when to stop waiting for the request, on the server clock.
+-- readDeadline:	the read deadline conn had before the wait, the zero time for
+--						none.
+--
+-- RETURNS:     bool true if a whole request is buffered and can be read
+--              without blocking.
+--
+-- NOTES:			Only peeks at the connection, so a request that is only partly
+--						buffered by the end is left for the next read. The socket
+--						deadline is on the real clock, so the time left on the server
+--						clock is turned into one. readDeadline is put back after each
+--						peek, if ctx was cancelled meanwhile the deadline is moved to
+--						now instead so the cancellation isn't lost.
+------------------------------------------------------------------------------*/
+func waitForRequest(ctx context.Context, conn net.Conn, reader *bufio.Reader, state *connectionState, end time.Time, readDeadline time.Time) bool {
+	for !requestBuffered(reader, state.cfg) {
+		buffered := reader.Buffered()
+		remaining := end.Sub(state.clock.Now())
+		if buffered == reader.Size() || remaining <= 0 {
+			return false
+		}
+
+		conn.SetReadDeadline(time.Now().Add(remaining))
+		_, err := reader.Peek(buffered + 1)
+		conn.SetReadDeadline(readDeadline)
+		if ctx.Err() != nil {
+			conn.SetReadDeadline(time.Now())
+			return false
+		}
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    requestBuffered
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - lines end with -delimiter
+--              October 15, 2026 - added length framing
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func requestBuffered(reader *bufio.Reader, cfg *serverConfig) bool
+--    reader:		the buffered connection.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     bool true if reading a request won't block.
+--
+-- NOTES:			A malformed header counts as buffered so that reading it
+--						reports the error straight away. A length prefix counts as
+--						buffered once the whole body is, the length limit is left
+--						to readRequest.
+------------------------------------------------------------------------------*/
+func requestBuffered(reader *bufio.Reader, cfg *serverConfig) bool {
+	buffered, _ := reader.Peek(reader.Buffered())
+	if cfg.framing == framingLength {
+		return len(buffered) >= lengthPrefixSize &&
+			uint64(len(buffered)-lengthPrefixSize) >= uint64(binary.BigEndian.Uint32(buffered))
+	}
+	if cfg.framing != framingHeader {
+		return bytes.IndexByte(buffered, cfg.delimiter) >= 0
+	}
+
+	header := bytes.IndexByte(buffered, '\n')
+	if header < 0 {
+		return false
+	}
+	_, value, found := strings.Cut(string(buffered[:header]), ":")
+	length, err := strconv.Atoi(strings.TrimSpace(value))
+	if !found || err != nil {
+		return true
+	}
+	separator := bytes.IndexByte(buffered[header+1:], '\n')
+
+	return separator >= 0 && len(buffered)-(header+separator+2) >= length
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    frameResponse
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - added length framing
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func frameResponse(payload []byte, cfg *serverConfig) []byte
+--   payload:		the body of the response.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the response as it is sent on the wire.
+--
+-- NOTES:			Frames the payload using the configured framing.
+------------------------------------------------------------------------------*/
+func frameResponse(payload []byte, cfg *serverConfig) []byte {
+	if cfg.framing == framingHeader {
+		return append([]byte(fmt.Sprintf("Content-Length: %d\n\n", len(payload))), payload...)
+	}
+	if cfg.framing == framingLength {
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...)
+	}
+
+	return payload
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    closeMessage
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - ended with -delimiter in line framing
+--              October 15, 2026 - frames -busy-message as well as -close-message
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func closeMessage(message string, cfg *serverConfig) []byte
+--   message:		the message sent before closing, -close-message or
+--						-busy-message.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the message as it is sent on the wire, nil if it is
+--              empty.
+--
+-- NOTES:			In line framing the message is ended with -delimiter so it
+--						reads as a line, in header framing it is the payload of a
+--						header framed response.
+------------------------------------------------------------------------------*/
+func closeMessage(message string, cfg *serverConfig) []byte {
+	if message == "" {
+		return nil
+	}
+	if cfg.framing == framingLine {
+		return append([]byte(message), cfg.delimiter)
+	}
+
+	return frameResponse([]byte(message), cfg)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeResponse
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - short writes are retried
+--              October 15, 2026 - bounded by -idle-timeout
+--              October 15, 2026 - chunks are paced on the connection's clock
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeResponse(ctx context.Context, conn net.Conn, response []byte, state *connectionState) error
+--       ctx:		cancelled when the server closes the connections it is serving.
+--      conn:		the client to respond to.
+--  response:		one or more responses framed by frameResponse.
+--     state:		the connection's state, its configuration and clock.
+--
+-- RETURNS:     error any error writing the response.
+--
+-- NOTES:			With -idle-timeout a client that stops reading has that long
+--						to take the response.
+------------------------------------------------------------------------------*/
+func writeResponse(ctx context.Context, conn net.Conn, response []byte, state *connectionState) error {
+	cfg := state.cfg
+	if cfg.idleTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(cfg.idleTimeout))
+	}
+	if cfg.responseChunks > 1 {
+		return writeChunked(ctx, conn, response, cfg.responseChunks, cfg.chunkDelay, state.clock)
+	}
+
+	return writeFull(conn, response)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeChunked
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - short writes are retried
+--              October 15, 2026 - waits on the clock and stops when ctx is cancelled
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeChunked(ctx context.Context, conn net.Conn, response []byte, chunks int, delay time.Duration, clock Clock) error
+--       ctx:		cancelled when the server closes the connections it is serving.
+--      conn:		the client to respond to.
+--  response:		the framed response.
+--    chunks:		how many writes to split the response across.
+--     delay:		how long to wait between writes.
+--     clock:		the clock the wait is timed by.
+--
+-- RETURNS:     error any error writing the response, ctx's error if it was
+--              cancelled while waiting to write a chunk.
+--
+-- NOTES:			Used to make clients reassemble a response from several reads.
+--						A response shorter than chunks is written a byte at a time.
+------------------------------------------------------------------------------*/
+func writeChunked(ctx context.Context, conn net.Conn, response []byte, chunks int, delay time.Duration, clock Clock) error {
+	if chunks > len(response) {
+		chunks = len(response)
+	}
+
+	for i := 0; i < chunks; i++ {
+		if i > 0 && delay > 0 {
+			select {
+			case <-clock.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		start, end := len(response)*i/chunks, len(response)*(i+1)/chunks
+		if err := writeFull(conn, response[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeFull
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeFull(conn net.Conn, data []byte) error
+--      conn:		the client to write to.
+--      data:		the bytes to write.
+--
+-- RETURNS:     error any error writing, io.ErrShortWrite if conn stops
+--              accepting data without one.
+--
+-- NOTES:			A net.Conn should only write part of data along with an error,
+--						this keeps writing in case a wrapped connection doesn't.
+------------------------------------------------------------------------------*/
+func writeFull(conn net.Conn, data []byte) error {
+	for len(data) > 0 {
+		n, err := conn.Write(data)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		data = data[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,606 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 framing_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func readTimesOut(t *testing.T, conn net.Conn, within time.Duration)
+--  func (conn *writeRecorder) Write(b []byte) (int, error)
+--  func (conn *partialWriter) Write(b []byte) (int, error)
+--  func TestWaitForRequestBuffered(t *testing.T)
+--  func TestWaitForRequestWindowOnClock(t *testing.T)
+--  func TestWaitForRequestKeepsReadDeadline(t *testing.T)
+--  func TestWaitForRequestCancelled(t *testing.T)
+--  func BenchmarkCoalesceWindow(b *testing.B)
+--  func TestReadHeaderRequestValid(t *testing.T)
+--  func TestReadHeaderRequestLengthMismatch(t *testing.T)
+--  func TestReadHeaderRequestMissingSeparator(t *testing.T)
+--  func TestPipelinedHeaderRequestsOnConnection(t *testing.T)
+--  func TestWriteChunked(t *testing.T)
+--  func TestChunkDelayOnClock(t *testing.T)
+--  func TestChunkedEchoOnConnection(t *testing.T)
+--  func TestMinRequestOnConnection(t *testing.T)
+--  func TestWriteFullPartialWrites(t *testing.T)
+--  func TestPartialWriteEchoOnConnection(t *testing.T)
+--  func TestIdleTimeoutUnreadResponse(t *testing.T)
+--  func TestCloseMessageFramed(t *testing.T)
+--  func TestParseDelimiter(t *testing.T)
+--  func TestNULDelimiterOnConnection(t *testing.T)
+--  func lengthFrame(payload string) string
+--  func TestReadLengthRequest(t *testing.T)
+--  func TestLengthEchoOnConnection(t *testing.T)
+--  func TestReadLineMaxLine(t *testing.T)
+--  func TestOversizedLineOnConnection(t *testing.T)
+--
+-- NOTES: Tests for reading requests in each framing. Requests are read from
+--        a strings.Reader, or from one end of a net.Pipe when the test needs
+--        a connection. The coalesce window is timed on a fakeClock, socket
+--        deadlines are still real ones.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readTimesOut fails the test unless a read from conn times out within the
+// given time.
+func readTimesOut(t *testing.T, conn net.Conn, within time.Duration) {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if !isTimeout(err) {
+			t.Errorf("read returned %v, want a timeout", err)
+		}
+	case <-time.After(within):
+		conn.SetReadDeadline(time.Now())
+		t.Errorf("read didn't time out within %s, the read deadline was lost", within)
+	}
+}
+
+// writeRecorder a connection that records each write made to it.
+type writeRecorder struct {
+	net.Conn
+	writes []string
+}
+
+// Write records b as one write.
+func (conn *writeRecorder) Write(b []byte) (int, error) {
+	conn.writes = append(conn.writes, string(b))
+
+	return len(b), nil
+}
+
+// partialWriter a connection that writes at most max bytes at a time, and
+// fails every write once failAfter bytes have been written if it isn't 0.
+type partialWriter struct {
+	net.Conn
+	max       int
+	failAfter int
+	written   int
+}
+
+// Write writes up to max bytes of b.
+func (conn *partialWriter) Write(b []byte) (int, error) {
+	if conn.failAfter > 0 && conn.written >= conn.failAfter {
+		return 0, errors.New("write failed")
+	}
+	n, err := conn.Conn.Write(b[:min(len(b), conn.max)])
+	conn.written += n
+
+	return n, err
+}
+
+// TestWaitForRequestBuffered checks that a request sent inside the window is
+// found and one that isn't sent isn't waited on past the window.
+func TestWaitForRequestBuffered(t *testing.T) {
+	state := newTestConnectionState(&serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go io.WriteString(client, "one\ntwo\n")
+
+	reader := bufio.NewReader(server)
+	if _, err := readRequest(reader, state.cfg); err != nil {
+		t.Fatal("readRequest:", err)
+	}
+	end := state.clock.Now().Add(time.Second)
+	if !waitForRequest(context.Background(), server, reader, state, end, time.Time{}) {
+		t.Error("the second request wasn't found")
+	}
+}
+
+// TestWaitForRequestWindowOnClock checks that the window ends by the
+// connection clock rather than the wall clock.
+func TestWaitForRequestWindowOnClock(t *testing.T) {
+	state := newTestConnectionState(&serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	end := state.clock.Now().Add(time.Hour)
+	state.clock.(*fakeClock).Advance(time.Hour)
+	if waitForRequest(context.Background(), server, bufio.NewReader(server), state, end, time.Time{}) {
+		t.Error("a request was found on an idle connection")
+	}
+}
+
+// TestWaitForRequestKeepsReadDeadline checks that the -idle-timeout deadline
+// is still in place once the wait is over.
+func TestWaitForRequestKeepsReadDeadline(t *testing.T) {
+	state := newTestConnectionState(&serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	readDeadline := time.Now().Add(100 * time.Millisecond)
+	server.SetReadDeadline(readDeadline)
+	end := state.clock.Now().Add(10 * time.Millisecond)
+	if waitForRequest(context.Background(), server, bufio.NewReader(server), state, end, readDeadline) {
+		t.Fatal("a request was found on an idle connection")
+	}
+	readTimesOut(t, server, 2*time.Second)
+}
+
+// TestWaitForRequestCancelled checks that a cancellation during the wait
+// isn't wiped out by the deadline being put back.
+func TestWaitForRequestCancelled(t *testing.T) {
+	state := newTestConnectionState(&serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, func() { server.SetReadDeadline(time.Now()) })
+	defer stop()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	end := state.clock.Now().Add(time.Hour)
+	if waitForRequest(ctx, server, bufio.NewReader(server), state, end, time.Time{}) {
+		t.Fatal("a request was found on an idle connection")
+	}
+	readTimesOut(t, server, 2*time.Second)
+}
+
+// BenchmarkCoalesceWindow reports the writes each request takes when a client
+// pipelines tiny requests, with and without a coalesce window.
+func BenchmarkCoalesceWindow(b *testing.B) {
+	const requests = 64
+	for _, window := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("window=%s", window), func(b *testing.B) {
+			srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', coalesceWindow: window})
+			writes := 0
+			for i := 0; i < b.N; i++ {
+				server, client := net.Pipe()
+				go func() {
+					io.WriteString(client, strings.Repeat("hi\n", requests))
+					client.Close()
+				}()
+				conn := &writeRecorder{Conn: server}
+				if connInfo := connectionInstance(context.Background(), conn, srvInfo, 1); connInfo.NumberOfRequests != requests {
+					b.Fatalf("%d requests answered, want %d", connInfo.NumberOfRequests, requests)
+				}
+				server.Close()
+				writes += len(conn.writes)
+			}
+			b.ReportMetric(float64(writes)/float64(b.N*requests), "writes/req")
+		})
+	}
+}
+
+// TestReadHeaderRequestValid checks that requests framed with a Content-Length
+// header are read one after the other, with or without carriage returns.
+func TestReadHeaderRequestValid(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("Content-Length: 5\n\nhello" +
+		"content-length:3\r\n\r\nabc" + "Content-Length: 0\n\n"))
+	for _, want := range []string{"hello", "abc", ""} {
+		body, err := readHeaderRequest(reader, 0)
+		if err != nil || string(body) != want {
+			t.Errorf("readHeaderRequest = %q, %v, want %q", body, err, want)
+		}
+	}
+	if _, err := readHeaderRequest(reader, 0); err != io.EOF {
+		t.Errorf("readHeaderRequest after the last request = %v, want EOF", err)
+	}
+}
+
+// TestReadHeaderRequestLengthMismatch checks that a body shorter than its
+// Content-Length and a length over -max-line are errors.
+func TestReadHeaderRequestLengthMismatch(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("Content-Length: 10\n\nhello"))
+	if _, err := readHeaderRequest(reader, 0); err != io.ErrUnexpectedEOF {
+		t.Errorf("a short body returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	reader = bufio.NewReader(strings.NewReader("Content-Length: 10\n\nhelloworld"))
+	if _, err := readHeaderRequest(reader, 5); !errors.Is(err, errRequestTooLarge) {
+		t.Errorf("a length over -max-line returned %v, want %v", err, errRequestTooLarge)
+	}
+}
+
+// TestReadHeaderRequestMissingSeparator checks that a header that isn't
+// followed by a blank line, or isn't a Content-Length, is malformed.
+func TestReadHeaderRequestMissingSeparator(t *testing.T) {
+	for _, request := range []string{
+		"Content-Length: 5\nhello\nContent-Length: 5\n\nhello",
+		"Content-Length: 5\nX: 1\n\nhello",
+		"Content-Type: 5\n\nhello",
+		"Content-Length: -1\n\n",
+		"Content-Length: 5" + strings.Repeat(" ", maxHeaderLength) + "\n\nhello",
+	} {
+		if _, err := readHeaderRequest(bufio.NewReader(strings.NewReader(request)), 0); !errors.Is(err, errMalformedHeader) {
+			t.Errorf("readHeaderRequest(%q) = %v, want %v", request, err, errMalformedHeader)
+		}
+	}
+}
+
+// TestPipelinedHeaderRequestsOnConnection checks that Content-Length requests
+// sent in one write are each echoed and counted.
+func TestPipelinedHeaderRequestsOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingHeader})
+	client, served := serveTestConnection(t, srvInfo)
+	requests := "Content-Length: 3\n\nabcContent-Length: 2\n\nde"
+	go io.WriteString(client, requests)
+	echo := make([]byte, len(requests))
+	if _, err := io.ReadFull(client, echo); err != nil || string(echo) != requests {
+		t.Fatalf("the echo was %q, %v, want %q", echo, err, requests)
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.NumberOfRequests != 2 {
+		t.Errorf("%d requests counted, want 2", connInfo.NumberOfRequests)
+	}
+}
+
+// TestWriteChunked checks that a response is split across the writes asked
+// for, or a byte a write when it is shorter than that.
+func TestWriteChunked(t *testing.T) {
+	tests := []struct {
+		response string
+		chunks   int
+		want     []string
+	}{
+		{"hello world\n", 3, []string{"hell", "o wo", "rld\n"}},
+		{"hello world\n", 5, []string{"he", "ll", "o w", "or", "ld\n"}},
+		{"hi\n", 8, []string{"h", "i", "\n"}},
+	}
+	for _, test := range tests {
+		conn := &writeRecorder{}
+		if err := writeChunked(context.Background(), conn, []byte(test.response), test.chunks, 0, realClock{}); err != nil {
+			t.Fatal("writeChunked:", err)
+		}
+		if strings.Join(conn.writes, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%q in %d chunks was written as %q, want %q", test.response, test.chunks, conn.writes, test.want)
+		}
+	}
+}
+
+// TestChunkDelayOnClock checks that -chunk-delay is waited out on the clock,
+// and that cancelling ctx stops the writes between chunks.
+func TestChunkDelayOnClock(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &writeRecorder{}
+	written := make(chan error, 1)
+	go func() { written <- writeChunked(ctx, conn, []byte("hello world\n"), 3, time.Second, clock) }()
+
+	waitForWaiters(clock, 1)
+	clock.Advance(time.Second)
+	waitForWaiters(clock, 1)
+	if strings.Join(conn.writes, "|") != "hell|o wo" {
+		t.Errorf("%q written after one delay, want the first two chunks", conn.writes)
+	}
+	cancel()
+	if err := <-written; err != context.Canceled || len(conn.writes) != 2 {
+		t.Errorf("cancelled with %d chunks written returned %v, want 2 and %v", len(conn.writes), err, context.Canceled)
+	}
+}
+
+// TestChunkedEchoOnConnection checks that a client gets the whole echo of a
+// -response-chunks response over several reads, with every byte counted.
+func TestChunkedEchoOnConnection(t *testing.T) {
+	const request = "a longer request to split up\n"
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', responseChunks: 4})
+	client, served := serveTestConnection(t, srvInfo)
+	io.WriteString(client, request)
+
+	var echo []byte
+	reads := 0
+	buffer := make([]byte, 64)
+	for !strings.HasSuffix(string(echo), "\n") {
+		n, err := client.Read(buffer)
+		if err != nil {
+			t.Fatalf("read %d of the echo failed after %q: %v", reads+1, echo, err)
+		}
+		echo = append(echo, buffer[:n]...)
+		reads++
+	}
+	client.Close()
+
+	if string(echo) != request {
+		t.Errorf("the echo was %q, want %q", echo, request)
+	}
+	if reads != 4 {
+		t.Errorf("the echo took %d reads, want 4", reads)
+	}
+	if connInfo := <-served; connInfo.BytesSent != len(request) {
+		t.Errorf("%d bytes sent, want %d", connInfo.BytesSent, len(request))
+	}
+}
+
+// TestMinRequestOnConnection checks that a request of -min-request bytes is
+// echoed and a shorter one closes the connection, after -min-request-reply
+// when it is set.
+func TestMinRequestOnConnection(t *testing.T) {
+	for _, reply := range []string{"", "too short"} {
+		srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+			minRequest: 5, minRequestReply: reply})
+		client, served := serveTestConnection(t, srvInfo)
+		go io.WriteString(client, "hello\nhi\n")
+
+		received, err := io.ReadAll(client)
+		if err != nil {
+			t.Fatal("read:", err)
+		}
+		want := "hello\n"
+		if reply != "" {
+			want += reply + "\n"
+		}
+		if string(received) != want {
+			t.Errorf("with -min-request-reply %q the client got %q, want %q", reply, received, want)
+		}
+		if connInfo := <-served; connInfo.CloseReason != closeReasonUndersized {
+			t.Errorf("the connection closed for %q, want %q", connInfo.CloseReason, closeReasonUndersized)
+		}
+	}
+}
+
+// TestWriteFullPartialWrites checks that a response is written in full across
+// short writes, and that a connection which stops taking data is an error.
+func TestWriteFullPartialWrites(t *testing.T) {
+	recorder := &writeRecorder{}
+	if err := writeFull(&partialWriter{Conn: recorder, max: 5}, []byte("hello world\n")); err != nil {
+		t.Fatal("writeFull:", err)
+	}
+	if strings.Join(recorder.writes, "|") != "hello| worl|d\n" {
+		t.Errorf("the response was written as %q, want it all in writes of 5", recorder.writes)
+	}
+
+	conn := &partialWriter{Conn: &writeRecorder{}, max: 0}
+	if err := writeFull(conn, []byte("hello\n")); err != io.ErrShortWrite {
+		t.Errorf("writeFull to a connection taking nothing returned %v, want %v", err, io.ErrShortWrite)
+	}
+}
+
+// TestPartialWriteEchoOnConnection checks that a client gets the whole echo
+// from a connection that only writes a few bytes at a time, and that a write
+// error ends the connection.
+func TestPartialWriteEchoOnConnection(t *testing.T) {
+	const request = "a request longer than a write\n"
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer client.Close()
+	served := make(chan connectionInfo, 1)
+	go func() {
+		conn := &partialWriter{Conn: server, max: 4, failAfter: len(request)}
+		defer conn.Close()
+		served <- connectionInstance(context.Background(), conn, srvInfo, 1)
+	}()
+
+	reader := bufio.NewReader(client)
+	go io.WriteString(client, request)
+	if echo, err := reader.ReadString('\n'); echo != request {
+		t.Errorf("the echo was %q, %v, want %q", echo, err, request)
+	}
+	go io.WriteString(client, request)
+	select {
+	case connInfo := <-served:
+		if connInfo.CloseReason != "error" || connInfo.NumberOfRequests != 2 {
+			t.Errorf("the connection closed for %q after %d requests, want an error after 2",
+				connInfo.CloseReason, connInfo.NumberOfRequests)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the write error didn't end the connection")
+	}
+}
+
+// TestIdleTimeoutUnreadResponse checks that a client which sends a request
+// and never reads the echo is closed by the -idle-timeout write deadline.
+func TestIdleTimeoutUnreadResponse(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', idleTimeout: 10 * time.Millisecond})
+	client, served := serveTestConnection(t, srvInfo)
+	io.WriteString(client, "hello\n")
+
+	select {
+	case connInfo := <-served:
+		if connInfo.CloseReason != closeReasonIdle || connInfo.BytesSent != 0 {
+			t.Errorf("closed with %q after sending %d bytes, want %q before the echo was taken",
+				connInfo.CloseReason, connInfo.BytesSent, closeReasonIdle)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the write to a client that isn't reading never timed out")
+	}
+}
+
+// TestCloseMessageFramed checks that the close message is framed for each
+// framing, so a client reads it like any other response.
+func TestCloseMessageFramed(t *testing.T) {
+	tests := []struct {
+		cfg     serverConfig
+		wire    string
+		payload string
+	}{
+		{serverConfig{framing: framingLine, delimiter: '\n'}, "bye\n", "bye\n"},
+		{serverConfig{framing: framingLine, delimiter: 0}, "bye\x00", "bye\x00"},
+		{serverConfig{framing: framingHeader}, "Content-Length: 3\n\nbye", "bye"},
+		{serverConfig{framing: framingLength}, "\x00\x00\x00\x03bye", "bye"},
+	}
+	for _, test := range tests {
+		wire := closeMessage("bye", &test.cfg)
+		if string(wire) != test.wire {
+			t.Errorf("in %s framing the message was sent as %q, want %q", test.cfg.framing, wire, test.wire)
+			continue
+		}
+		payload, err := readRequest(bufio.NewReader(strings.NewReader(string(wire))), &test.cfg)
+		if err != nil || string(payload) != test.payload {
+			t.Errorf("in %s framing the message read back as %q, %v, want %q", test.cfg.framing, payload, err, test.payload)
+		}
+		if message := closeMessage("", &test.cfg); message != nil {
+			t.Errorf("in %s framing an empty message was sent as %q", test.cfg.framing, message)
+		}
+	}
+}
+
+// TestParseDelimiter checks the ways a delimiter can be written, and that
+// anything but a single byte is rejected.
+func TestParseDelimiter(t *testing.T) {
+	for value, want := range map[string]byte{`\n`: '\n', `\r`: '\r', `\0`: 0, `\x00`: 0, `\000`: 0, ";": ';', `\xff`: 0xff} {
+		if delimiter, err := parseDelimiter(value); err != nil || delimiter != want {
+			t.Errorf("parseDelimiter(%q) = %q, %v, want %q", value, delimiter, err, want)
+		}
+	}
+	for _, value := range []string{"", `\r\n`, "ab", "é", `\u00e9`} {
+		if _, err := parseDelimiter(value); err != errInvalidDelimiter {
+			t.Errorf("parseDelimiter(%q) returned %v, want %v", value, err, errInvalidDelimiter)
+		}
+	}
+}
+
+// TestNULDelimiterOnConnection checks that with a NUL delimiter requests are
+// split on it, newlines are just payload, and each echo keeps its NUL.
+func TestNULDelimiterOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: 0})
+	client, served := serveTestConnection(t, srvInfo)
+	go io.WriteString(client, "one\x00two\nlines\x00")
+
+	reader := bufio.NewReader(client)
+	for _, want := range []string{"one\x00", "two\nlines\x00"} {
+		if echo, err := reader.ReadString(0); echo != want {
+			t.Fatalf("the echo was %q, %v, want %q", echo, err, want)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.NumberOfRequests != 2 {
+		t.Errorf("%d requests counted, want 2", connInfo.NumberOfRequests)
+	}
+}
+
+// lengthFrame payload with its 4 byte big endian length prefix.
+func lengthFrame(payload string) string {
+	return string(binary.BigEndian.AppendUint32(nil, uint32(len(payload)))) + payload
+}
+
+// TestReadLengthRequest checks that valid and zero length frames are read,
+// binary payloads and all, and that oversized and short frames are errors.
+func TestReadLengthRequest(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader(lengthFrame("a\nb\x00c") + lengthFrame("")))
+	for _, want := range []string{"a\nb\x00c", ""} {
+		if body, err := readLengthRequest(reader, 0); err != nil || string(body) != want {
+			t.Errorf("readLengthRequest = %q, %v, want %q", body, err, want)
+		}
+	}
+	if _, err := readLengthRequest(reader, 0); err != io.EOF {
+		t.Errorf("readLengthRequest after the last frame = %v, want EOF", err)
+	}
+
+	reader = bufio.NewReader(strings.NewReader(lengthFrame("helloworld")))
+	if _, err := readLengthRequest(reader, 5); !errors.Is(err, errRequestTooLarge) {
+		t.Errorf("a length over -max-line returned %v, want %v", err, errRequestTooLarge)
+	}
+	reader = bufio.NewReader(strings.NewReader("\xff\xff\xff\xff"))
+	if _, err := readLengthRequest(reader, 0); !errors.Is(err, errRequestTooLarge) {
+		t.Errorf("a length over the largest payload returned %v, want %v", err, errRequestTooLarge)
+	}
+	for _, short := range []string{"\x00\x00", lengthFrame("hello")[:7]} {
+		if _, err := readLengthRequest(bufio.NewReader(strings.NewReader(short)), 0); err != io.ErrUnexpectedEOF {
+			t.Errorf("the short frame %q returned %v, want %v", short, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// TestLengthEchoOnConnection checks that valid and zero length frames are
+// echoed with their prefix, and that an oversized one closes the connection.
+func TestLengthEchoOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLength, maxLine: 16})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+	for _, payload := range []string{"binary\n\x00data", ""} {
+		go io.WriteString(client, lengthFrame(payload))
+		echo := make([]byte, len(lengthFrame(payload)))
+		if _, err := io.ReadFull(reader, echo); err != nil || string(echo) != lengthFrame(payload) {
+			t.Fatalf("the echo was %q, %v, want %q", echo, err, lengthFrame(payload))
+		}
+	}
+
+	go io.WriteString(client, lengthFrame(strings.Repeat("x", 17)))
+	if connInfo := <-served; connInfo.CloseReason != closeReasonOversized || connInfo.NumberOfRequests != 2 {
+		t.Errorf("closed for %q after %d requests, want %q after 2",
+			connInfo.CloseReason, connInfo.NumberOfRequests, closeReasonOversized)
+	}
+}
+
+// TestReadLineMaxLine checks that a line of -max-line bytes is read and one
+// byte longer is an error, whether or not its delimiter ever arrives.
+func TestReadLineMaxLine(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("1234\n"))
+	if line, err := readLine(reader, '\n', 4); err != nil || string(line) != "1234\n" {
+		t.Errorf("readLine = %q, %v, want the whole line", line, err)
+	}
+	for _, input := range []string{"12345\n", strings.Repeat("x", 64<<10)} {
+		reader := bufio.NewReader(strings.NewReader(input))
+		if line, err := readLine(reader, '\n', 4); err != errRequestTooLarge {
+			t.Errorf("readLine of %d bytes = %d bytes, %v, want %v", len(input), len(line), err, errRequestTooLarge)
+		}
+	}
+}
+
+// TestOversizedLineOnConnection checks that a client sending far more than
+// -max-line without a delimiter is disconnected as oversized-request, and that
+// the server only allocated around the limit to find out.
+func TestOversizedLineOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', maxLine: 4096})
+	payload := make([]byte, 8<<20)
+	for i := range payload {
+		payload[i] = 'x'
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	client, served := serveTestConnection(t, srvInfo)
+	written, err := client.Write(payload)
+	if err == nil {
+		t.Fatalf("all %d bytes were read, want the connection closed", written)
+	}
+
+	connInfo := <-served
+	runtime.ReadMemStats(&after)
+	if connInfo.CloseReason != closeReasonOversized || connInfo.NumberOfRequests != 0 {
+		t.Errorf("the connection closed as %q after %d requests, want %q after 0",
+			connInfo.CloseReason, connInfo.NumberOfRequests, closeReasonOversized)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("%d bytes allocated serving the connection, want under 1MB", allocated)
+	}
+}
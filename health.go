@@ -0,0 +1,177 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 health.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startHealthServers(srvInfo serverInfo)
+--  func startHealthServer(address string, name string, check func() error)
+--  func serverLive(srvInfo serverInfo) error
+--  func serverReady(srvInfo serverInfo) error
+--
+-- NOTES: This file serves separate liveness and readiness checks over HTTP
+--        for orchestrators. Liveness only says the process hasn't wedged, so
+--        it stays 200 while the server is paused or draining and a restart
+--        isn't triggered by a shutdown. Readiness says the server wants new
+--        connections and is 503 while paused or draining so traffic is sent
+--        elsewhere.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// livenessTimeout how long the observer has to answer the liveness check.
+const livenessTimeout = 2 * time.Second
+
+var errObserverUnresponsive = errors.New("observer did not answer in time")
+var errPaused = errors.New("accepting is paused")
+var errDraining = errors.New("server is draining")
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startHealthServers
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startHealthServers(srvInfo serverInfo)
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Serves liveness on -live-addr and readiness on -ready-addr,
+--						either is left out if its address isn't set.
+------------------------------------------------------------------------------*/
+func startHealthServers(srvInfo serverInfo) {
+	startHealthServer(srvInfo.config.liveAddr, "liveness", func() error { return serverLive(srvInfo) })
+	startHealthServer(srvInfo.config.readyAddr, "readiness", func() error { return serverReady(srvInfo) })
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startHealthServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startHealthServer(address string, name string, check func() error)
+--   address:		the address to serve the check on, empty for none.
+--      name:		what the check is called in the log.
+--     check:		returns why the check fails, nil if it passes.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Every request gets 200 if the check passes and 503 if it
+--						doesn't. Exits the program if the address can't be listened
+--						on.
+------------------------------------------------------------------------------*/
+func startHealthServer(address string, name string, check func() error) {
+	if address == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatalln("Unable to listen for "+name+" checks:", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "OK")
+	})
+	go http.Serve(listener, handler)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    serverLive
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func serverLive(srvInfo serverInfo) error
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     error errObserverUnresponsive if the observer didn't answer
+--              within livenessTimeout, nil if it did.
+--
+-- NOTES:			Asks the observer for a snapshot the way Stats does but gives
+--						up instead of blocking. The reply channel is buffered so a
+--						late answer doesn't hold up the observer.
+------------------------------------------------------------------------------*/
+func serverLive(srvInfo serverInfo) error {
+	reply := make(chan statsSnapshot, 1)
+	timeout := time.After(livenessTimeout)
+	select {
+	case srvInfo.statsRequests <- reply:
+	case <-timeout:
+		return errObserverUnresponsive
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-timeout:
+		return errObserverUnresponsive
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    serverReady
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func serverReady(srvInfo serverInfo) error
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     error why new connections wouldn't be accepted, nil if they
+--              would.
+--
+-- NOTES:			The listener is bound before the check is served and is only
+--						closed once the server is draining, so a server that isn't
+--						paused or draining is listening.
+------------------------------------------------------------------------------*/
+func serverReady(srvInfo serverInfo) error {
+	if atomic.LoadInt32(srvInfo.draining) == 1 {
+		return errDraining
+	}
+	if srvInfo.acceptGate.isPaused() {
+		return errPaused
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 health_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func freeAddress(t *testing.T) string
+--  func healthStatus(t *testing.T, address string) int
+--  func TestReadinessDuringDrain(t *testing.T)
+--
+-- NOTES: Tests for the liveness and readiness checks, served over HTTP on
+--        ports picked for the test. The observer runs in its own go routine,
+--        the drain is left waiting on a connection that is never finished.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// freeAddress a loopback address with a port nothing is listening on.
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// healthStatus the status code of a check served at address.
+func healthStatus(t *testing.T, address string) int {
+	t.Helper()
+	response, err := http.Get("http://" + address + "/")
+	if err != nil {
+		t.Fatal("GET:", err)
+	}
+	response.Body.Close()
+
+	return response.StatusCode
+}
+
+// TestReadinessDuringDrain checks that readiness answers 503 while paused and
+// once the drain has started, and that liveness answers 200 throughout.
+func TestReadinessDuringDrain(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{shutdownTimeout: time.Minute,
+		liveAddr: freeAddress(t), readyAddr: freeAddress(t)})
+	srvInfo.acceptGate = newAcceptGate()
+	server, client := net.Pipe()
+	defer client.Close()
+	srvInfo.connections.add(server)
+	go observerLoop(srvInfo, nil)
+	srvInfo.serverConnection <- newConnectionConst
+	startHealthServers(srvInfo)
+
+	checks := func(stage string, wantReady int) {
+		t.Helper()
+		if status := healthStatus(t, srvInfo.config.liveAddr); status != http.StatusOK {
+			t.Errorf("liveness %s answered %d, want %d", stage, status, http.StatusOK)
+		}
+		if status := healthStatus(t, srvInfo.config.readyAddr); status != wantReady {
+			t.Errorf("readiness %s answered %d, want %d", stage, status, wantReady)
+		}
+	}
+	checks("at startup", http.StatusOK)
+	srvInfo.acceptGate.pause()
+	checks("while paused", http.StatusServiceUnavailable)
+	srvInfo.acceptGate.resume()
+	checks("once resumed", http.StatusOK)
+
+	requestShutdown(srvInfo, nil)
+	readStats(srvInfo) // the drain has started
+	checks("during the drain", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,108 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 keepalive_linux_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func keepAliveOptions(t *testing.T, conn *net.TCPConn) (int, int)
+--  func TestSetKeepAlive(t *testing.T)
+--
+-- NOTES: Tests for keep-alive on accepted connections. The settings are read
+--        back from the socket with getsockopt, so the test is only built on
+--        Linux.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// keepAliveOptions whether keep-alive is on for conn's socket, and how many
+// seconds it idles before the first probe.
+func keepAliveOptions(t *testing.T, conn *net.TCPConn) (int, int) {
+	t.Helper()
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal("SyscallConn:", err)
+	}
+	var enabled, idle int
+	var optErr error
+	raw.Control(func(fd uintptr) {
+		if enabled, optErr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE); optErr == nil {
+			idle, optErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE)
+		}
+	})
+	if optErr != nil {
+		t.Fatal("getsockopt:", optErr)
+	}
+
+	return enabled, idle
+}
+
+// TestSetKeepAlive checks that keep-alive and its period are set on an
+// accepted TCP connection under the wrappers the server adds, that it can be
+// turned off, and that a Unix socket connection is left alone.
+func TestSetKeepAlive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+
+	tests := []struct {
+		enabled     bool
+		period      time.Duration
+		wantEnabled int
+		wantIdle    int
+	}{
+		{true, 42 * time.Second, 1, 42},
+		{false, 42 * time.Second, 0, -1},
+	}
+	for _, test := range tests {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		defer client.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal("accept:", err)
+		}
+		defer conn.Close()
+		conn.(*net.TCPConn).SetKeepAlive(!test.enabled) // the opposite of what is wanted
+
+		setKeepAlive(&countingConn{Conn: conn}, test.enabled, test.period)
+		enabled, idle := keepAliveOptions(t, conn.(*net.TCPConn))
+		if enabled != test.wantEnabled || (test.wantIdle >= 0 && idle != test.wantIdle) {
+			t.Errorf("setKeepAlive(%v, %s) left keep-alive %d idling %ds, want %d idling %ds",
+				test.enabled, test.period, enabled, idle, test.wantEnabled, test.wantIdle)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "keepalive.sock")
+	unixListener, err := net.Listen(networkUnix, path)
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer unixListener.Close()
+	client, err := net.Dial(networkUnix, path)
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	setKeepAlive(client, true, 42*time.Second) // mustn't panic or fail
+}
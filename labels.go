@@ -0,0 +1,140 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 labels.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func parseLabels(data []byte) map[string]string
+--  func validLabelText(text string, maxLength int) bool
+--  func filterByLabel(elements *list.List, filter string) *list.List
+--
+-- NOTES: This file handles the labels a client can give its connection by
+--        sending "key=value key=value" as its first request. The labels are
+--        listed in the report, which can be grouped or filtered by them.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"container/list"
+	"strings"
+)
+
+// maxLabels the most labels accepted on a connection.
+const maxLabels = 8
+
+// maxLabelKey and maxLabelValue the longest label key and value accepted.
+const maxLabelKey = 32
+const maxLabelValue = 64
+
+// labelGroup the totals for the connections sharing a label value.
+type labelGroup struct {
+	Label         string // the value of the label, empty for connections without it
+	Connections   int    // the connections with this value
+	Requests      int    // the requests made on those connections
+	BytesReceived int    // the bytes read from those connections
+	BytesSent     int    // the bytes written to those connections
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parseLabels
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parseLabels(data []byte) map[string]string
+--      data:		the payload of the first request, without its delimiter.
+--
+-- RETURNS:     map[string]string the labels, nil if data isn't a valid set
+--              of labels.
+--
+-- NOTES:			A request that isn't made up entirely of valid labels is
+--						treated as an ordinary request rather than an error.
+------------------------------------------------------------------------------*/
+func parseLabels(data []byte) map[string]string {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields) > maxLabels {
+		return nil
+	}
+
+	labels := make(map[string]string, len(fields))
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found || !validLabelText(key, maxLabelKey) || !validLabelText(value, maxLabelValue) {
+			return nil
+		}
+		labels[key] = value
+	}
+
+	return labels
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    validLabelText
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func validLabelText(text string, maxLength int) bool
+--      text:		a label key or value.
+-- maxLength:		the longest text accepted.
+--
+-- RETURNS:     bool true if text is letters, digits, '_', '-' and '.' only.
+------------------------------------------------------------------------------*/
+func validLabelText(text string, maxLength int) bool {
+	if text == "" || len(text) > maxLength {
+		return false
+	}
+	for _, c := range text {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-.", c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    filterByLabel
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func filterByLabel(elements *list.List, filter string) *list.List
+--  elements:		A list of connectionInfo.
+--    filter:		"key=value", the label connections must have.
+--
+-- RETURNS:     *list.List the connections with the label.
+------------------------------------------------------------------------------*/
+func filterByLabel(elements *list.List, filter string) *list.List {
+	key, value, _ := strings.Cut(filter, "=")
+	filtered := list.New()
+	for e := elements.Front(); e != nil; e = e.Next() {
+		if e.Value.(connectionInfo).Labels[key] == value {
+			filtered.PushBack(e.Value)
+		}
+	}
+
+	return filtered
+}
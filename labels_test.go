@@ -0,0 +1,76 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 labels_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestReportGroupedByLabel(t *testing.T)
+--  func TestParseLabelsInvalid(t *testing.T)
+--
+-- NOTES: Tests for labelling connections from their first request and
+--        grouping the report by a label.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestReportGroupedByLabel checks that connections sending labels are totalled
+// by the -report-group-by label and can be filtered by it.
+func TestReportGroupedByLabel(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', connectionLabels: true})
+	stats := newServerStats("region")
+	for _, labels := range []string{"region=east team=a", "region=west team=a", "region=east team=b", "not labels"} {
+		client, served := serveTestConnection(t, srvInfo)
+		reader := bufio.NewReader(client)
+		for _, request := range []string{labels + "\n", "hello\n"} {
+			io.WriteString(client, request)
+			reader.ReadString('\n')
+		}
+		client.Close()
+		stats.connectionOpened()
+		stats.connectionClosed(<-served)
+	}
+
+	var groups []string
+	for _, group := range stats.summary().LabelGroups {
+		groups = append(groups, fmt.Sprintf("%q:%d/%d", group.Label, group.Connections, group.Requests))
+	}
+	if got := strings.Join(groups, " "); got != `"":1/2 "east":2/4 "west":1/2` {
+		t.Errorf("the groups were %s, want connections and requests by region", got)
+	}
+	if east := filterByLabel(stats.connectionsMade, "region=east"); east.Len() != 2 {
+		t.Errorf("%d connections filtered to region=east, want 2", east.Len())
+	}
+}
+
+// TestParseLabelsInvalid checks that requests that aren't a bounded set of
+// key=value labels give no labels.
+func TestParseLabelsInvalid(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"region",
+		"region=",
+		"=east",
+		"region=east extra",
+		strings.Repeat("k", maxLabelKey+1) + "=v",
+		"k=" + strings.Repeat("v", maxLabelValue+1),
+		strings.Repeat("k=v ", maxLabels+1),
+	} {
+		if labels := parseLabels([]byte(data)); labels != nil {
+			t.Errorf("parseLabels(%q) = %v, want nil", data, labels)
+		}
+	}
+}
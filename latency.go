@@ -0,0 +1,195 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 latency.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newLatencyRecorder(fname string, start time.Time) *latencyRecorder
+--  func (recorder *latencyRecorder) record(received []time.Time, now time.Time)
+--  func (recorder *latencyRecorder) percentiles(summary *reportSummary)
+--  func (recorder *latencyRecorder) writeLog(end time.Time)
+--
+-- NOTES: This file records how long each request took to answer, from the
+--        request being read to its response being written, in an
+--        HdrHistogram. The histogram is written to -latency-hdr on exit as
+--        an HdrHistogram log with a single interval covering the run, so it
+--        can be read and merged by the usual HdrHistogram tools. Latencies
+--        are recorded in nanoseconds.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// latencyLowest and latencyHighest the range of latencies the histogram tracks.
+const latencyLowest = int64(time.Microsecond)
+const latencyHighest = int64(time.Hour)
+
+// latencySignificantDigits the precision latencies are recorded with.
+const latencySignificantDigits = 3
+
+// latencyRecorder the latencies of every request, shared by all workers.
+type latencyRecorder struct {
+	mutex     sync.Mutex
+	histogram *hdrhistogram.Histogram
+	logFile   *os.File // where the histogram is written on exit
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newLatencyRecorder
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newLatencyRecorder(fname string, start time.Time) *latencyRecorder
+--     fname:		the file to write the histogram log to.
+--     start:		when the server started, the start of the interval.
+--
+-- RETURNS:     *latencyRecorder an empty recorder, nil if fname is empty.
+--
+-- NOTES:			The file is created straight away so a bad path exits the
+--						program before anything is served.
+------------------------------------------------------------------------------*/
+func newLatencyRecorder(fname string, start time.Time) *latencyRecorder {
+	if fname == "" {
+		return nil
+	}
+
+	logFile, err := os.Create(fname)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	histogram := hdrhistogram.New(latencyLowest, latencyHighest, latencySignificantDigits)
+	histogram.SetStartTimeMs(start.UnixMilli())
+
+	return &latencyRecorder{histogram: histogram, logFile: logFile}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    record
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (recorder *latencyRecorder) record(received []time.Time, now time.Time)
+--  received:		when each of the requests answered was read.
+--       now:		when the response was written.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil recorder. Latencies over latencyHighest
+--						are recorded as latencyHighest.
+------------------------------------------------------------------------------*/
+func (recorder *latencyRecorder) record(received []time.Time, now time.Time) {
+	if recorder == nil {
+		return
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	for _, start := range received {
+		recorder.histogram.RecordValue(min(int64(now.Sub(start)), latencyHighest))
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    percentiles
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (recorder *latencyRecorder) percentiles(summary *reportSummary)
+--   summary:		the summary the percentiles are added to.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil recorder.
+------------------------------------------------------------------------------*/
+func (recorder *latencyRecorder) percentiles(summary *reportSummary) {
+	if recorder == nil {
+		return
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	summary.LatencyP50 = time.Duration(recorder.histogram.ValueAtQuantile(50))
+	summary.LatencyP90 = time.Duration(recorder.histogram.ValueAtQuantile(90))
+	summary.LatencyP99 = time.Duration(recorder.histogram.ValueAtQuantile(99))
+	summary.LatencyP999 = time.Duration(recorder.histogram.ValueAtQuantile(99.9))
+	summary.LatencyMax = time.Duration(recorder.histogram.Max())
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeLog
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (recorder *latencyRecorder) writeLog(end time.Time)
+--       end:		when the server stopped, the end of the interval.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil recorder. Writes the histogram as a V2
+--						compressed interval and closes the file, errors are logged
+--						as the server is already exiting.
+------------------------------------------------------------------------------*/
+func (recorder *latencyRecorder) writeLog(end time.Time) {
+	if recorder == nil {
+		return
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.histogram.SetEndTimeMs(end.UnixMilli())
+	writer := hdrhistogram.NewHistogramLogWriter(recorder.logFile)
+	err := writer.OutputLogFormatVersion()
+	if err == nil {
+		err = writer.OutputStartTime(recorder.histogram.StartTimeMs())
+	}
+	if err == nil {
+		err = writer.OutputLegend()
+	}
+	if err == nil {
+		err = writer.OutputIntervalHistogram(recorder.histogram)
+	}
+	if err != nil {
+		log.Println("Unable to write the latency histogram:", err)
+	}
+	if err = recorder.logFile.Close(); err != nil {
+		log.Println(err)
+	}
+}
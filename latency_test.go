@@ -0,0 +1,81 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 latency_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestLatencyHDRLog(t *testing.T)
+--
+-- NOTES: Tests for recording request latency. Latencies are recorded from
+--        times given by the test rather than measured, and the log is read
+--        back with hdrhistogram-go's own reader.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// TestLatencyHDRLog checks that the recorded latencies give the expected
+// percentiles, and that the log written on exit parses with an HDR reader to
+// the same histogram.
+func TestLatencyHDRLog(t *testing.T) {
+	start := time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+	fname := filepath.Join(t.TempDir(), "latency.hlog")
+	recorder := newLatencyRecorder(fname, start)
+	now := start.Add(time.Minute)
+	for i := 1; i <= 1000; i++ {
+		recorder.record([]time.Time{now.Add(-time.Duration(i) * time.Millisecond)}, now)
+	}
+
+	var summary reportSummary
+	recorder.percentiles(&summary)
+	percentiles := []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"P50", summary.LatencyP50, 500 * time.Millisecond},
+		{"P90", summary.LatencyP90, 900 * time.Millisecond},
+		{"P99", summary.LatencyP99, 990 * time.Millisecond},
+		{"P99.9", summary.LatencyP999, 999 * time.Millisecond},
+		{"Max", summary.LatencyMax, time.Second},
+	}
+	for _, percentile := range percentiles {
+		if diff := percentile.got - percentile.want; diff < -percentile.want/1000 || diff > percentile.want/1000 {
+			t.Errorf("%s was %s, want %s", percentile.name, percentile.got, percentile.want)
+		}
+	}
+
+	recorder.writeLog(now)
+	logFile, err := os.Open(fname)
+	if err != nil {
+		t.Fatal("the log wasn't written:", err)
+	}
+	defer logFile.Close()
+	histogram, err := hdrhistogram.NewHistogramLogReader(logFile).NextIntervalHistogram()
+	if err != nil || histogram == nil {
+		t.Fatalf("the log didn't parse: %v", err)
+	}
+	if histogram.TotalCount() != 1000 {
+		t.Errorf("the log holds %d latencies, want 1000", histogram.TotalCount())
+	}
+	if p99 := time.Duration(histogram.ValueAtQuantile(99)); p99 != summary.LatencyP99 {
+		t.Errorf("the log's P99 was %s, the report's %s", p99, summary.LatencyP99)
+	}
+	if histogram.StartTimeMs() != start.UnixMilli() || histogram.EndTimeMs() != now.UnixMilli() {
+		t.Errorf("the log covers %d to %d, want %d to %d",
+			histogram.StartTimeMs(), histogram.EndTimeMs(), start.UnixMilli(), now.UnixMilli())
+	}
+}
@@ -0,0 +1,84 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 logging.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newLogger(w io.Writer, level string, format string) *slog.Logger
+--
+-- NOTES: This file builds the leveled logger the workers, the accept loops
+--        and the observer log through, set by -log-level and -log-format.
+--        Connections ending normally are logged at debug so a busy server
+--        doesn't flood its log, errors serving a connection are warnings
+--        and anything that stops the server is an error. Messages from
+--        before the logger exists, like bad flags, still go through the
+--        log package.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// the -log-level values.
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+)
+
+// the -log-format values.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newLogger
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newLogger(w io.Writer, level string, format string) *slog.Logger
+--         w:		where the log is written.
+--     level:		the -log-level, the least severe level written.
+--    format:		the -log-format.
+--
+-- RETURNS:     *slog.Logger the logger.
+--
+-- NOTES:			level and format have already been checked by parseConfig.
+------------------------------------------------------------------------------*/
+func newLogger(w io.Writer, level string, format string) *slog.Logger {
+	var minimum slog.Level
+	switch level {
+	case logLevelDebug:
+		minimum = slog.LevelDebug
+	case logLevelWarn:
+		minimum = slog.LevelWarn
+	case logLevelError:
+		minimum = slog.LevelError
+	default:
+		minimum = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: minimum}
+	if format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+
+	return slog.New(slog.NewTextHandler(w, options))
+}
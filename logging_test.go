@@ -0,0 +1,72 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 logging_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestLogLevelSuppressesEOF(t *testing.T)
+--  func TestLogFormatJSON(t *testing.T)
+--
+-- NOTES: Tests for the leveled logger. The server's logger is swapped for one
+--        writing to a strings.Builder, read once the connection has been
+--        served.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestLogLevelSuppressesEOF checks that a client closing its connection is
+// logged at debug, so it is left out at warn.
+func TestLogLevelSuppressesEOF(t *testing.T) {
+	for _, test := range []struct {
+		level  string
+		logged bool
+	}{{logLevelWarn, false}, {logLevelDebug, true}} {
+		var logged strings.Builder
+		srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+		srvInfo.logger = newLogger(&logged, test.level, logFormatText)
+		client, served := serveTestConnection(t, srvInfo)
+		go io.WriteString(client, "hello\n")
+		bufio.NewReader(client).ReadString('\n')
+		client.Close()
+		<-served
+
+		if got := strings.Contains(logged.String(), "level=DEBUG msg=\"Client closed the connection\""); got != test.logged {
+			t.Errorf("with -log-level %s the close was logged %v, want %v, logged %q", test.level, got, test.logged, logged.String())
+		}
+	}
+}
+
+// TestLogFormatJSON checks that -log-format json writes each message as a
+// JSON object with its level and attributes.
+func TestLogFormatJSON(t *testing.T) {
+	var logged strings.Builder
+	logger := newLogger(&logged, logLevelInfo, logFormatJSON)
+	logger.Debug("left out")
+	logger.Warn("Accept failed", "err", "too many open files")
+
+	var entry struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+		Err   string `json:"err"`
+	}
+	if err := json.Unmarshal([]byte(logged.String()), &entry); err != nil {
+		t.Fatalf("the log %q isn't one JSON object: %v", logged.String(), err)
+	}
+	if entry.Level != "WARN" || entry.Msg != "Accept failed" || entry.Err != "too many open files" {
+		t.Errorf("the entry was %+v, want the warning and its error", entry)
+	}
+}
@@ -12,129 +12,1095 @@
 --
 -- INTERFACE:
 --	func newConnection(srvInfo serverInfo)
---  func worker(srvInfo serverInfo)
---  func connectionInstance(conn net.Conn) connectionInfo
---  func handleData(conn net.Conn, connInfo *connectionInfo) error
+--  func finishedConnection(srvInfo serverInfo)
+--  func resizePool(srvInfo serverInfo, target int)
+--  func retireWorker(srvInfo serverInfo) bool
+--  func workerRetired(srvInfo serverInfo)
+--  func requestWorker(srvInfo serverInfo)
+--  func spawnPendingWorkers(srvInfo serverInfo, now time.Time)
+--  func spawnWorker(srvInfo serverInfo)
+--  func wakeIdleWorkers(srvInfo serverInfo, workers int)
+--  func worker(ctx context.Context, srvInfo serverInfo, workerID int)
+--  func (accept *acceptor) next(srvInfo serverInfo) (acceptResult, bool)
+--  func (accept *acceptor) handOff(srvInfo serverInfo)
+--  func acceptFailed(srvInfo serverInfo, err error, backoff time.Duration) (time.Duration, bool)
+--  func turnedAway(srvInfo serverInfo, conn net.Conn) bool
+--  func admitConnection(srvInfo serverInfo, conn net.Conn) (string, time.Duration)
+--  func sendClosed(srvInfo serverInfo, connInfo connectionInfo)
+--  func servedByWorkers(cfg *serverConfig) bool
+--  func acceptBackoff(previous time.Duration) time.Duration
+--  func isTemporary(err error) bool
+--  func unservedConnection(conn net.Conn, workerID int, reason string, endTime time.Time) connectionInfo
+--  func serveConnection(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) (connInfo connectionInfo)
+--  func connectionInstance(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) connectionInfo
+--  func handleData(ctx context.Context, conn net.Conn, reader *bufio.Reader, connInfo *connectionInfo, state *connectionState) error
+--  func processRequest(data []byte, connInfo *connectionInfo, state *connectionState) []byte
 --  func observerLoop(srvInfo serverInfo, osSignals chan os.Signal)
---  func newServerInfo() serverInfo
+--  func connectionsClosed(srvInfo serverInfo, stats *serverStats, handed time.Time, closed ...connectionInfo)
+--  func collectShutdownClosed(srvInfo serverInfo, stats *serverStats, pending int)
+--  func drained(srvInfo serverInfo, stats *serverStats) bool
+--  func requestShutdown(srvInfo serverInfo, reason error)
+--  func writeReport(srvInfo serverInfo, stats *serverStats) reportSummary
+--  func exitServer(srvInfo serverInfo, stats *serverStats, exitCode int)
+--  func newServerInfo(cfg serverConfig) serverInfo
 --
 -- NOTES: This file is for functions that are part of child go routines which
---        handle data for the EPoll version of the scalable server.
+--        handle data for the scalable server. With -mode thread, the
+--        default, each connection is served by its own worker go routine.
+--        With -mode epoll they are all served by the event loop in
+--        epoll_linux.go, which shares the accepting, admission and reporting
+--        here. With -proto udp datagrams are echoed by udp.go instead.
 ------------------------------------------------------------------------------*/
 package main
 
 import (
 	"bufio"
-	"container/list"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 type connectionInfo struct {
-	HostName           string // the remote host name
-	AmmountOfData      int    // the ammount of data transfered to/from the host
-	NumberOfRequests   int    // the total requests sent to the server from this client
-	ConnectionsAtClose int    // the total number of connections being sustained when the connection was closed.
+	HostName           string            // the remote host name
+	LocalAddr          string            // the local address the connection was accepted on
+	PeerCred           string            // the uid, gid and pid of a Unix socket client, if -peer-cred is set
+	FastOpen           bool              // whether the client sent data in its SYN, if -tfo is set
+	SynToAccept        time.Duration     // the estimated time from the SYN arriving to Accept returning, if -syn-latency is set
+	AdmissionWait      time.Duration     // how long the connection waited to be admitted
+	TLSVersion         string            // the negotiated TLS version, if -tls-stats is set
+	TLSCipherSuite     string            // the negotiated TLS cipher suite, if -tls-stats is set
+	AmmountOfData      int               // the ammount of data transfered to/from the host
+	BytesReceived      int               // the bytes read from the host, including framing
+	BytesSent          int               // the bytes written to the host, including framing
+	ResponseRatio      float64           // BytesSent / BytesReceived, 0 if nothing was received
+	NumberOfRequests   int               // the total requests sent to the server from this client
+	ConnectionsAtClose int               // the total number of connections being sustained when the connection was closed.
+	WorkerID           int               // the worker that accepted the connection
+	DedupHits          int               // repeated requests answered from the dedup cache
+	CoalescedRequests  int               // requests answered in the same write as an earlier request
+	BackpressureEvents int               // times reading paused to answer -max-inflight unanswered requests
+	ReadCalls          int               // reads from the socket that returned data, if -count-reads is set
+	EchoMismatches     int               // echoes that didn't carry their request, if -self-check is set
+	PipelineBytesIn    int               // request bytes passed into the -pipeline stages
+	PipelineBytesOut   int               // bytes the -pipeline stages produced from them
+	PipelineErrors     int               // requests a -pipeline stage failed on, echoed untransformed
+	Stalls             int               // times a request made no progress for -stuck-threshold, if it is set
+	DelayedRequests    int               // requests whose response was delayed
+	ResponseDelay      time.Duration     // the total delay added to responses
+	MaxResponseDelay   time.Duration     // the longest delay added to a response
+	PacingDeviation    time.Duration     // the total time delayed responses were sent off their intended delay
+	MaxPacingDeviation time.Duration     // the furthest a delayed response was sent off its intended delay
+	Duration           time.Duration     // how long the connection was served for, 0 if it wasn't
+	HeldOpen           time.Duration     // how long the connection was kept open after the client finished, if -hold-open is set
+	FairWait           time.Duration     // the time requests waited for a -fair-slots slot
+	CloseReason        string            // why the server closed the connection, empty if the client did
+	Labels             map[string]string // the labels sent in the first request, if -connection-labels is set
+	StartTime          time.Time         // when the worker started serving the connection
+	EndTime            time.Time         // when the connection was closed
 }
 
+// serverInfo the state shared by the workers and the observer. The plain int
+// counters belong to the observer, main sets them up before the observer starts
+// and only the observer's go routine touches them afterwards. Anything workers
+// update is an int64 updated atomically.
 type serverInfo struct {
-	totalConnections *int
-	availableServers *int
+	totalConnections *int       // owned by the observer
+	availableServers *int       // owned by the observer
+	workersSpawned   *int       // owned by the observer
+	activeWorkers    *int       // owned by the observer
+	pendingWorkers   *int       // workers waiting on the spawn rate, owned by the observer
+	lastSpawn        *time.Time // when the last worker was spawned for a connection
+	acceptErrors     *int64     // updated atomically by the workers
+	bytesTransferred *int64     // bytes read and written by all workers, updated atomically
 	serverConnection chan int
 	connectInfo      chan connectionInfo
+	connectInfoBatch chan []connectionInfo // finished connections sent several at a time
 	listener         net.Listener
+	config           *serverConfig
+	shutdown         chan error // asks the observer to stop the server, nil for a clean stop
+	traceFile        *os.File   // the runtime trace, nil if tracing is off
+	retire           chan bool  // tells a worker in the lazy pool whether to exit after a connection
+	rng              *lockedRand
+	connections      *connRegistry           // the connections being served, closed when the server stops
+	admission        *admissionBucket        // caps the rate connections are admitted, nil for no cap
+	connectionLimit  connectionLimit         // caps the connections served at once, nil for no cap
+	closeBatch       *closeBatcher           // finished connections waiting to be sent, nil if they're sent alone
+	probes           *sync.Map               // the addresses of the health probe's connections
+	nonces           *int64                  // the nonces added to echoes, updated atomically
+	workerResize     chan workerResize       // requests from the control endpoint to resize the pool
+	retireQuota      *int64                  // workers still to retire to shrink the pool, updated atomically
+	retireIdle       chan struct{}           // wakes a worker waiting on Accept to retire
+	handedOff        chan acceptResult       // what a retired worker's Accept returned, for another worker
+	clock            Clock                   // the clock the server is timed by
+	acceptClosing    *int32                  // set to 1 once -accept-drain ends, updated atomically
+	otlp             *otlpExporter           // pushes metrics to -otlp-endpoint, nil if it isn't set
+	statsRequests    chan chan statsSnapshot // requests for a snapshot of the observer's statistics
+	statsResets      chan chan bool          // requests to clear the observer's statistics
+	acceptGate       *acceptGate             // holds the workers back from accepting while paused
+	fair             *fairScheduler          // shares processing between -fair-label tags, nil if it isn't set
+	draining         *int32                  // set to 1 once a signal starts the shutdown, updated atomically
+	latency          *latencyRecorder        // the latency of every request, nil if -latency-hdr isn't set
+	metrics          *metricsServer          // serves Prometheus metrics, nil if -metrics-addr isn't set
+	summaryFile      *os.File                // where the exit summary is written, nil if -summary-fd isn't set
+	watchdog         *stuckWatchdog          // watches connections for stalls, nil if -stuck-threshold isn't set
+	udp              *udpServer              // echoes datagrams with -proto udp, nil otherwise
+	snapshotFile     io.Writer               // where -snapshot-interval lines are written, nil if it isn't set
+	logger           *slog.Logger            // the leveled log the workers and observer write to
+	ipFilter         *ipFilter               // the clients that may connect, nil if every client may
+	rateLimiter      *ipRateLimiter          // caps how fast each client IP connects, nil for no cap
+	ctx              context.Context         // cancelled when the server closes the connections it is serving
+	cancel           context.CancelFunc      // cancels ctx
+}
+
+// acceptResult what a worker's Accept returned.
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// acceptor runs a worker's Accept on its own go routine, so a worker waiting
+// for a connection can still be woken to retire.
+type acceptor struct {
+	accepted chan acceptResult // what Accept returned, buffered so it never blocks
+	pending  bool              // whether Accept is still waiting for a connection
 }
 
 const newConnectionConst = 1
 const finishedConnectionConst = -1
-const startingClients = 15
-const freeServerMinimum = 10
+const workerRetiredConst = -2
+const defaultStartingClients = 15
+const defaultFreeServerMinimum = 10
+const defaultListenAddress = ":8005"
+
+// modeThread and modeEpoll how connections are served, a worker go routine each or one event loop.
+const modeThread = "thread"
+const modeEpoll = "epoll"
+
+// protoTCP and protoUDP whether the server echoes streams or datagrams.
+const protoTCP = "tcp"
+const protoUDP = "udp"
+
+// closeReasonByteCap the CloseReason of a connection that sent more than -max-bytes-per-conn.
+const closeReasonByteCap = "byte-cap"
+
+// closeReasonRefused the CloseReason of a connection closed at random by -refuse-rate.
+const closeReasonRefused = "refused"
+
+// closeReasonAcceptDrain the CloseReason of a connection accepted after -accept-drain ended.
+const closeReasonAcceptDrain = "accept-drain"
+
+// closeReasonIdle the CloseReason of a connection that was silent for -idle-timeout.
+const closeReasonIdle = "idle-timeout"
+
+// closeReasonUndersized the CloseReason of a connection that sent a request under -min-request.
+const closeReasonUndersized = "undersized-request"
+
+// closeReasonOversized the CloseReason of a connection that sent a request over -max-line.
+const closeReasonOversized = "oversized-request"
+
+// closeReasonPanic the CloseReason of a connection torn down by a panic while it was served.
+const closeReasonPanic = "panic"
+
+// defaultCloseMessage the message sent to a connection accepted after -accept-drain
+// ended unless -close-message says otherwise.
+const defaultCloseMessage = "server shutting down"
+
+// acceptDrainFlush how long the workers have to turn away the queued connections
+// once -accept-drain ends, before the listener is closed.
+const acceptDrainFlush = 100 * time.Millisecond
+
+// defaultShutdownTimeout how long connections are given to finish after a signal
+// unless -shutdown-timeout says otherwise.
+const defaultShutdownTimeout = 10 * time.Second
+
+// shutdownCollectWait how long the observer waits for the workers to report the
+// connections the shutdown closed.
+const shutdownCollectWait = time.Second
+
+var errTooManyAcceptErrors = errors.New("too many accept errors")
+
+// minAcceptBackoff and maxAcceptBackoff the range of the pause after a temporary accept error.
+const minAcceptBackoff = 5 * time.Millisecond
+const maxAcceptBackoff = time.Second
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == clientCommand {
+		runClient(os.Args[2:])
+		return
+	}
+
+	srvInfo := newServerInfo(parseConfig())
+	srvInfo.traceFile = startTrace(srvInfo.config.trace)
+	startProbeServer(srvInfo)
+	startControlServer(srvInfo)
+	startAdminServer(srvInfo)
+	startHealthServers(srvInfo)
+
+	// create servers
+	if srvInfo.config.proto == protoUDP {
+		go srvInfo.udp.serve(srvInfo)
+	} else if srvInfo.config.mode == modeEpoll {
+		startEpollLoop(srvInfo)
+	} else {
+		initialWorkers := srvInfo.config.startingClients
+		if srvInfo.config.workerFloor > 0 {
+			initialWorkers = srvInfo.config.workerFloor
+		}
+		for i := 0; i < initialWorkers; i++ {
+			*srvInfo.availableServers++
+			spawnWorker(srvInfo)
+		}
+	}
+
+	// when the server is killed it should print statistics need to catch the signal
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+	observerLoop(srvInfo, osSignals)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newConnection
+--
+-- DATE:        February 6, 2016
+--
+-- REVISIONS:   October 15, 2026 - added the lazy worker pool
+--              October 15, 2026 - documented that only the observer calls this
+--              October 15, 2026 - reads the free worker minimum from the config
+--              October 15, 2026 - no workers are spawned with -mode epoll
+--              October 15, 2026 - no workers are spawned with -proto udp
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   newConnection(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called by the observer when a new client connects to the
+--						server, workers report connections over serverConnection
+--						rather than calling this so the counters need no locking. In
+--						the lazy pool a worker is only spawned to keep the floor of
+--						workers waiting on Accept, and never past the cap. With -mode
+--						epoll or -proto udp there are no workers and the connection is
+--						only counted.
+------------------------------------------------------------------------------*/
+func newConnection(srvInfo serverInfo) {
+	*srvInfo.totalConnections++
+	if !servedByWorkers(srvInfo.config) {
+		return
+	}
+	if cfg := srvInfo.config; cfg.workerFloor > 0 {
+		*srvInfo.availableServers--
+		workers := *srvInfo.activeWorkers + *srvInfo.pendingWorkers
+		if *srvInfo.availableServers < cfg.workerFloor && (cfg.workerCap <= 0 || workers < cfg.workerCap) {
+			requestWorker(srvInfo)
+		}
+		return
+	}
+
+	if *srvInfo.availableServers < srvInfo.config.freeServerMinimum {
+		*srvInfo.availableServers--
+		requestWorker(srvInfo)
+	} else {
+		*srvInfo.availableServers--
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    finishedConnection
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the worker is available again in the
+--                pre-spawned pool too
+--              October 15, 2026 - does nothing with -mode epoll
+--              October 15, 2026 - does nothing with -proto udp
+--              October 15, 2026 - called for finishedConnectionConst with
+--                -close-batch
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   finishedConnection(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called when a worker finishes with a connection, as its record
+--						arrives or, with -close-batch, when the worker sends
+--						finishedConnectionConst before batching the record. The worker
+--						goes back to waiting on Accept so it is available again. In
+--						the lazy pool the worker is told to exit instead if there are
+--						already enough workers waiting on Accept. Does nothing with
+--						-mode epoll or -proto udp.
+------------------------------------------------------------------------------*/
+func finishedConnection(srvInfo serverInfo) {
+	if !servedByWorkers(srvInfo.config) {
+		return
+	}
+	floor := srvInfo.config.workerFloor
+	if floor > 0 && *srvInfo.availableServers >= floor {
+		*srvInfo.activeWorkers--
+		srvInfo.retire <- true
+		return
+	}
+
+	*srvInfo.availableServers++
+	if floor > 0 {
+		srvInfo.retire <- false
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    resizePool
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - wakes the idle workers to retire
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   resizePool(srvInfo serverInfo, target int)
+--	 srvInfo:		information about the overall server
+--    target:		the number of workers wanted.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Growing the pool requests the missing workers. Shrinking it
+--						first cancels workers held back by the spawn rate, then sets
+--						the number of workers to retire and wakes that many workers
+--						waiting on Accept. A busy worker retires when it finishes its
+--						connection instead, so the pool shrinks on an idle server too.
+--						The lazy pool never shrinks below its floor, and still grows
+--						and shrinks with the load afterwards.
+------------------------------------------------------------------------------*/
+func resizePool(srvInfo serverInfo, target int) {
+	if target < srvInfo.config.workerFloor {
+		target = srvInfo.config.workerFloor
+	}
+	workers := *srvInfo.activeWorkers + *srvInfo.pendingWorkers
+	if target >= workers {
+		atomic.StoreInt64(srvInfo.retireQuota, 0)
+		for ; workers < target; workers++ {
+			requestWorker(srvInfo)
+		}
+		return
+	}
+
+	excess := workers - target
+	cancelled := min(excess, *srvInfo.pendingWorkers)
+	*srvInfo.pendingWorkers -= cancelled
+	atomic.StoreInt64(srvInfo.retireQuota, int64(excess-cancelled))
+	go wakeIdleWorkers(srvInfo, excess-cancelled)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    wakeIdleWorkers
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   wakeIdleWorkers(srvInfo serverInfo, workers int)
+--	 srvInfo:		information about the overall server
+--   workers:		the number of workers to wake.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Run on its own go routine so the observer isn't held up while
+--						the workers are busy. A woken worker only retires while the
+--						pool is still over its size, busy workers may have retired in
+--						the meantime. Gives up once the server stops.
+------------------------------------------------------------------------------*/
+func wakeIdleWorkers(srvInfo serverInfo, workers int) {
+	for ; workers > 0; workers-- {
+		select {
+		case srvInfo.retireIdle <- struct{}{}:
+		case <-srvInfo.ctx.Done():
+			return
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    retireWorker
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   retireWorker(srvInfo serverInfo) bool
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     bool true if the calling worker should exit to shrink the pool.
+--
+-- NOTES:			Called by a worker when it finishes a connection or is woken
+--						while waiting on Accept. Safe to call from any go routine.
+------------------------------------------------------------------------------*/
+func retireWorker(srvInfo serverInfo) bool {
+	for {
+		quota := atomic.LoadInt64(srvInfo.retireQuota)
+		if quota <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(srvInfo.retireQuota, quota, quota-1) {
+			return true
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    workerRetired
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the pre-spawned pool counts the worker as available too
+--              October 15, 2026 - also called for idle workers
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   workerRetired(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called when a worker exits to shrink the pool. The worker was
+--						waiting on Accept, or was counted as waiting on Accept again
+--						when it finished its connection.
+------------------------------------------------------------------------------*/
+func workerRetired(srvInfo serverInfo) {
+	*srvInfo.activeWorkers--
+	*srvInfo.availableServers--
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    requestWorker
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - reads the time from the server clock
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   requestWorker(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Adds a worker to the available servers, straight away unless
+--						that would spawn workers faster than the spawn rate.
+------------------------------------------------------------------------------*/
+func requestWorker(srvInfo serverInfo) {
+	*srvInfo.pendingWorkers++
+	spawnPendingWorkers(srvInfo, srvInfo.clock.Now())
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    spawnPendingWorkers
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   spawnPendingWorkers(srvInfo serverInfo, now time.Time)
+--	 srvInfo:		information about the overall server
+--       now:		the current time.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Spawns as many of the pending workers as the spawn rate allows.
+--						The observer calls this on a ticker so that workers held back
+--						during a connection storm are still spawned.
+------------------------------------------------------------------------------*/
+func spawnPendingWorkers(srvInfo serverInfo, now time.Time) {
+	rate := srvInfo.config.workerSpawnRate
+	for *srvInfo.pendingWorkers > 0 {
+		if rate > 0 && now.Sub(*srvInfo.lastSpawn) < time.Duration(float64(time.Second)/rate) {
+			return
+		}
+		*srvInfo.pendingWorkers--
+		*srvInfo.availableServers++
+		*srvInfo.lastSpawn = now
+		spawnWorker(srvInfo)
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    spawnWorker
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   spawnWorker(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func spawnWorker(srvInfo serverInfo) {
+	*srvInfo.workersSpawned++
+	*srvInfo.activeWorkers++
+	go worker(srvInfo.ctx, srvInfo, *srvInfo.workersSpawned)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    worker
+--
+-- DATE:        February 6, 2016
+--
+-- REVISIONS:   October 15, 2026 - connections are registered while served
+--              October 15, 2026 - connections are admitted at a capped rate
+--              October 15, 2026 - records the SYN to accept latency
+--              October 15, 2026 - finished connections can be sent in batches
+--              October 15, 2026 - tags the health probe's connections
+--              October 15, 2026 - refuses a random fraction of connections
+--              October 15, 2026 - can yield the processor between accepts
+--              October 15, 2026 - retires itself when the pool is shrunk
+--              October 15, 2026 - serves the connection the registry returns
+--              October 15, 2026 - waits on the server clock
+--              October 15, 2026 - turns connections away once -accept-drain ends
+--              October 15, 2026 - records whether the connection used TCP Fast Open
+--              October 15, 2026 - waits while the admin protocol has paused accepting
+--              October 15, 2026 - the turned away connections are sent -close-message
+--              October 15, 2026 - turns connections away over -max-connections
+--              October 15, 2026 - stops accepting once its context is cancelled
+--              October 15, 2026 - backs off after temporary accept errors and stops on permanent ones
+--              October 15, 2026 - survives a panic serving a connection
+--              October 15, 2026 - accept errors, admission and reporting the
+--                connection moved out to be shared with the epoll loop
+--              October 15, 2026 - sets keep-alive on accepted connections
+--              October 15, 2026 - closes connections -allow-cidr and -deny-cidr filter out
+--              October 15, 2026 - closes connections over -rate-limit
+--              October 15, 2026 - reads the -proxy-protocol header before filtering the connection
+--              October 15, 2026 - accepts through an acceptor so an idle worker can retire
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   worker(ctx context.Context, srvInfo serverInfo, workerID int)
+--       ctx:		cancelled when the server stops serving connections.
+--	 srvInfo:		information about the overall server
+--  workerID:		identifies this worker in the report
+--
+-- RETURNS:     void
+--
+-- NOTES:			This function is a worker thread, it accepts connections from
+--						outside and handles data from them. If the listener is closed
+--						or there have been too many accept errors the server is shut
+--						down. After a temporary accept error the worker backs off
+--						before accepting again, any other error shuts the server down.
+--						Connections over the admission cap wait their turn, or are
+--						closed straight away if too many are already waiting.
+--						-refuse-rate of connections are closed before being served.
+--						With -max-connections connections accepted while that many are
+--						being served are sent -busy-message and closed.
+--						With -proxy-protocol the header is read before the filters,
+--						so they and the report see the client it names, a connection
+--						with a bad header is reported with its own CloseReason.
+--						With -accept-yield the worker yields every so many accepts so
+--						a busy worker can't starve the observer. The worker returns
+--						before its next accept once ctx is cancelled. A panic while
+--						serving a connection only tears down that connection. A
+--						worker woken while waiting on Accept retires if the pool is
+--						being shrunk.
+------------------------------------------------------------------------------*/
+func worker(ctx context.Context, srvInfo serverInfo, workerID int) {
+	var backoff time.Duration
+	accept := acceptor{accepted: make(chan acceptResult, 1)}
+	for accepts := 1; ctx.Err() == nil; accepts++ {
+		if yield := srvInfo.config.acceptYield; yield > 0 && accepts%yield == 0 {
+			runtime.Gosched()
+		}
+		srvInfo.acceptGate.wait()
+		result, ok := accept.next(srvInfo)
+		if !ok {
+			srvInfo.serverConnection <- workerRetiredConst
+			return
+		}
+		conn, err := result.conn, result.err
+		if err != nil {
+			var retry bool
+			if backoff, retry = acceptFailed(srvInfo, err, backoff); !retry {
+				return
+			}
+			continue
+		}
+		backoff = 0
+		var synLatency time.Duration
+		if srvInfo.config.synLatency {
+			synLatency = synToAccept(conn)
+		}
+		fastOpen := srvInfo.config.tfo && fastOpened(conn)
+		var proxyErr error
+		if srvInfo.config.proxyProtocol {
+			conn, proxyErr = readProxyHeader(conn, srvInfo.config.proxyHeaderTimeout)
+		}
+		if turnedAway(srvInfo, conn) {
+			conn.Close()
+			continue
+		}
+		setKeepAlive(conn, srvInfo.config.keepAlive, srvInfo.config.keepAlivePeriod)
+
+		registered := srvInfo.connections.add(conn)
+		srvInfo.serverConnection <- newConnectionConst
+		var connInfo connectionInfo
+		if proxyErr != nil {
+			srvInfo.logger.Warn("Bad PROXY protocol header", "host", conn.RemoteAddr(), "err", proxyErr)
+			connInfo = unservedConnection(conn, workerID, closeReasonProxyHeader, srvInfo.clock.Now())
+		} else if reason, wait := admitConnection(srvInfo, conn); reason != "" {
+			connInfo = unservedConnection(conn, workerID, reason, srvInfo.clock.Now())
+		} else {
+			srvInfo.clock.Sleep(wait)
+			connInfo = serveConnection(ctx, registered, srvInfo, workerID)
+			connInfo.AdmissionWait = wait
+			srvInfo.connectionLimit.release()
+		}
+		connInfo.SynToAccept = synLatency
+		connInfo.FastOpen = fastOpen
+		conn.Close()
+		srvInfo.connections.remove(registered)
+		sendClosed(srvInfo, connInfo)
+		if srvInfo.config.workerFloor > 0 && <-srvInfo.retire {
+			return
+		}
+		if retireWorker(srvInfo) {
+			srvInfo.serverConnection <- workerRetiredConst
+			return
+		}
+	}
+
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    next
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (accept *acceptor) next(srvInfo serverInfo) (acceptResult, bool)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     acceptResult the connection, or error, to handle next.
+--              bool false if the worker should retire instead.
+--
+-- NOTES:			Starts an Accept unless one is still waiting from before, then
+--						waits for it, for a connection handed off by a retired worker,
+--						or to be woken to retire. The connection is served on the
+--						worker's go routine, only Accept runs on its own. A retiring
+--						worker hands its waiting Accept off to the other workers.
+------------------------------------------------------------------------------*/
+func (accept *acceptor) next(srvInfo serverInfo) (acceptResult, bool) {
+	for {
+		if !accept.pending {
+			accept.pending = true
+			go func() {
+				conn, err := srvInfo.listener.Accept()
+				accept.accepted <- acceptResult{conn, err}
+			}()
+		}
+		select {
+		case result := <-accept.accepted:
+			accept.pending = false
+			return result, true
+		case result := <-srvInfo.handedOff:
+			return result, true
+		case <-srvInfo.retireIdle:
+			if retireWorker(srvInfo) {
+				go accept.handOff(srvInfo)
+				return acceptResult{}, false
+			}
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    handOff
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (accept *acceptor) handOff(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Passes what a retired worker's Accept returns to another
+--						worker, so neither a connection nor a closed listener is
+--						missed. The connection is closed if the server stops first.
+------------------------------------------------------------------------------*/
+func (accept *acceptor) handOff(srvInfo serverInfo) {
+	result := <-accept.accepted
+	select {
+	case srvInfo.handedOff <- result:
+	case <-srvInfo.ctx.Done():
+		if result.conn != nil {
+			result.conn.Close()
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    acceptFailed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - logs through the leveled logger
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func acceptFailed(srvInfo serverInfo, err error, backoff time.Duration) (time.Duration, bool)
+--	 srvInfo:		information about the overall server
+--       err:		the error Accept returned.
+--   backoff:		the pause after the previous accept error, 0 if the last
+--              accept succeeded.
+--
+-- RETURNS:     time.Duration the pause taken, to be passed to the next call.
+--              bool whether to keep accepting.
+--
+-- NOTES:			A closed listener shuts the server down cleanly and a permanent
+--						error shuts it down with the error. After a temporary error
+--						the caller is paused before accepting again. Every error
+--						counts towards -max-accept-errors.
+------------------------------------------------------------------------------*/
+func acceptFailed(srvInfo serverInfo, err error, backoff time.Duration) (time.Duration, bool) {
+	if errors.Is(err, net.ErrClosed) {
+		requestShutdown(srvInfo, nil)
+		return backoff, false
+	}
+	srvInfo.logger.Warn("Accept failed", "err", err)
+	errorCount := atomic.AddInt64(srvInfo.acceptErrors, 1)
+	if limit := srvInfo.config.maxAcceptErrors; limit > 0 && errorCount > limit {
+		requestShutdown(srvInfo, errTooManyAcceptErrors)
+	}
+	if !isTemporary(err) {
+		requestShutdown(srvInfo, err)
+		return backoff, false
+	}
+	backoff = acceptBackoff(backoff)
+	srvInfo.clock.Sleep(backoff)
+
+	return backoff, true
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    turnedAway
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func turnedAway(srvInfo serverInfo, conn net.Conn) bool
+--	 srvInfo:		information about the overall server
+--      conn:		a connection that has just been accepted.
+--
+-- RETURNS:     bool whether the connection is filtered by -allow-cidr and
+--              -deny-cidr or over -rate-limit, the caller closes it.
+--
+-- NOTES:			Connections turned away here are logged but never reported to
+--						the observer, so they don't take a worker or a slot. A
+--						connection over -rate-limit is sent -rate-limit-message.
+------------------------------------------------------------------------------*/
+func turnedAway(srvInfo serverInfo, conn net.Conn) bool {
+	if !srvInfo.ipFilter.permits(conn.RemoteAddr()) {
+		srvInfo.logger.Info("Closed a filtered connection", "host", conn.RemoteAddr())
+		return true
+	}
+	if !srvInfo.rateLimiter.allow(conn.RemoteAddr()) {
+		srvInfo.logger.Debug("Closed a connection over -rate-limit", "host", conn.RemoteAddr())
+		if message := closeMessage(srvInfo.config.rateLimitMessage, srvInfo.config); message != nil {
+			conn.Write(message)
+		}
+		return true
+	}
+
+	return false
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    admitConnection
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func admitConnection(srvInfo serverInfo, conn net.Conn) (string, time.Duration)
+--	 srvInfo:		information about the overall server
+--      conn:		a connection that has just been accepted.
+--
+-- RETURNS:     string the CloseReason the connection is turned away with, empty
+--              if it is admitted.
+--              time.Duration how long an admitted connection has to wait
+--              for the admission cap before it is served.
+--
+-- NOTES:			An admitted connection holds a -max-connections slot, which
+--						has to be released once it has been served. Connections
+--						turned away once -accept-drain ends or over -max-connections
+--						are sent their message here, the caller closes them.
+------------------------------------------------------------------------------*/
+func admitConnection(srvInfo serverInfo, conn net.Conn) (string, time.Duration) {
+	if atomic.LoadInt32(srvInfo.acceptClosing) == 1 {
+		if message := closeMessage(srvInfo.config.closeMessage, srvInfo.config); message != nil {
+			conn.Write(message)
+		}
+		return closeReasonAcceptDrain, 0
+	}
+	if rate := srvInfo.config.refuseRate; rate > 0 && srvInfo.rng.Float64() < rate {
+		return closeReasonRefused, 0
+	}
+	if !srvInfo.connectionLimit.acquire() {
+		if message := closeMessage(srvInfo.config.busyMessage, srvInfo.config); message != nil {
+			conn.Write(message)
+		}
+		return closeReasonBusy, 0
+	}
+	wait, ok := srvInfo.admission.admit(srvInfo.clock.Now())
+	if !ok {
+		srvInfo.connectionLimit.release()
+		return closeReasonAdmission, 0
+	}
+
+	return "", wait
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    sendClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - a batching worker is available before its
+--                batch is sent
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func sendClosed(srvInfo serverInfo, connInfo connectionInfo)
+--	 srvInfo:		information about the overall server
+--  connInfo:		a connection that has been closed.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Tags the health probe's connections, then sends the connection
+--						to the observer, in a batch with -close-batch. A batched
+--						connection could wait up to closeBatchFlush to reach the
+--						observer, so a worker tells the observer it is available again
+--						straight away with finishedConnectionConst, which only costs a
+--						send on the buffered serverConnection channel.
+------------------------------------------------------------------------------*/
+func sendClosed(srvInfo serverInfo, connInfo connectionInfo) {
+	if isProbe(srvInfo, connInfo) {
+		connInfo.CloseReason = closeReasonProbe
+	}
+	if srvInfo.closeBatch == nil {
+		srvInfo.connectInfo <- connInfo
+		return
+	}
+	if servedByWorkers(srvInfo.config) {
+		srvInfo.serverConnection <- finishedConnectionConst
+	}
+	if batch := srvInfo.closeBatch.add(connInfo); batch != nil {
+		srvInfo.connectInfoBatch <- batch
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    servedByWorkers
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func servedByWorkers(cfg *serverConfig) bool
+--       cfg:		the server configuration.
+--
+-- RETURNS:     bool whether connections are served by the worker pool, false
+--              with -mode epoll or -proto udp.
+------------------------------------------------------------------------------*/
+func servedByWorkers(cfg *serverConfig) bool {
+	return cfg.mode == modeThread && cfg.proto == protoTCP
+}
 
-func main() {
-	if len(os.Args) < 2 { // validate args
-		log.Fatalln("Missing args:", os.Args[0], " [PORT]")
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    acceptBackoff
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func acceptBackoff(previous time.Duration) time.Duration
+--  previous:		the last backoff, 0 if the last accept succeeded.
+--
+-- RETURNS:     time.Duration how long to wait before accepting again.
+--
+-- NOTES:			Starts at minAcceptBackoff and doubles up to maxAcceptBackoff,
+--						the same as net/http's server.
+------------------------------------------------------------------------------*/
+func acceptBackoff(previous time.Duration) time.Duration {
+	if previous == 0 {
+		return minAcceptBackoff
 	}
 
-	srvInfo := newServerInfo()
-
-	// create servers
-	for i := 0; i < startingClients; i++ {
-		*srvInfo.availableServers++
-		go worker(srvInfo)
-	}
+	return min(previous*2, maxAcceptBackoff)
+}
 
-	// when the server is killed it should print statistics need to catch the signal
-	osSignals := make(chan os.Signal, 1)
-	signal.Notify(osSignals, os.Interrupt, os.Kill)
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isTemporary
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func isTemporary(err error) bool
+--       err:		an error from Accept.
+--
+-- RETURNS:     bool true if accepting may succeed if retried, such as when the
+--              process has run out of file descriptors.
+------------------------------------------------------------------------------*/
+func isTemporary(err error) bool {
+	var temporary interface{ Temporary() bool }
 
-	observerLoop(srvInfo, osSignals)
+	return errors.As(err, &temporary) && temporary.Temporary()
 }
 
 /*-----------------------------------------------------------------------------
--- FUNCTION:    newConnection
+-- FUNCTION:    unservedConnection
 --
--- DATE:        February 6, 2016
+-- DATE:        October 15, 2026
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - the close time is passed in from the clock
+--              October 15, 2026 - records the start time
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:   newConnection(srvInfo serverInfo)
---	 srvInfo:		information about the overall server
+-- INTERFACE:   func unservedConnection(conn net.Conn, workerID int, reason string, endTime time.Time) connectionInfo
+--      conn:		a connection that is being closed without being served.
+--  workerID:		the worker that accepted the connection.
+--    reason:		why the connection wasn't served.
+--   endTime:		when the connection was closed.
 --
--- RETURNS:     void
+-- RETURNS:   connectionInfo information about the connection for the report
 --
--- NOTES:			Called when a new client connects to the server.
+-- NOTES:			The connection starts and ends at endTime, it was never served.
 ------------------------------------------------------------------------------*/
-func newConnection(srvInfo serverInfo) {
-	*srvInfo.totalConnections++
-	if *srvInfo.availableServers < freeServerMinimum {
-		go worker(srvInfo)
-	} else {
-		*srvInfo.availableServers--
-	}
+func unservedConnection(conn net.Conn, workerID int, reason string, endTime time.Time) connectionInfo {
+	return connectionInfo{HostName: conn.RemoteAddr().String(), LocalAddr: conn.LocalAddr().String(),
+		WorkerID: workerID, CloseReason: reason, StartTime: endTime, EndTime: endTime}
 }
 
 /*-----------------------------------------------------------------------------
--- FUNCTION:    worker
+-- FUNCTION:    serveConnection
 --
--- DATE:        February 6, 2016
+-- DATE:        October 15, 2026
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - logs panics through the leveled logger
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:   serverInstance(srvInfo serverInfo)
---	 srvInfo:		information about the overall server
+-- INTERFACE:   func serveConnection(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) (connInfo connectionInfo)
+--       ctx:		cancelled when the server stops serving connections.
+--      conn:		a connection to a client.
+--   srvInfo:		information about the overall server
+--  workerID:		the worker handling the connection.
 --
--- RETURNS:     void
+-- RETURNS:   connectionInfo information about the connection when it's complete
 --
--- NOTES:			This function is a worker thread, it accepts connections from
---						outside and handles data from them.
+-- NOTES:			Serves the connection with connectionInstance. A panic while
+--						serving it is logged with its stack and recovered, the
+--						connection is reported with the "panic" CloseReason and the
+--						worker closes it like any other, so the worker keeps accepting
+--						and the statistics still see the connection. What the
+--						connection had done before the panic is lost.
 ------------------------------------------------------------------------------*/
-func worker(srvInfo serverInfo) {
-
-	for {
-		conn, err := srvInfo.listener.Accept()
-		if err != nil {
-			log.Println(err)
-			continue
+func serveConnection(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) (connInfo connectionInfo) {
+	start := srvInfo.clock.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			srvInfo.logger.Error("Recovered from a panic", "host", conn.RemoteAddr(), "panic", recovered,
+				"stack", string(debug.Stack()))
+			connInfo = unservedConnection(conn, workerID, closeReasonPanic, srvInfo.clock.Now())
+			connInfo.StartTime = start
+			connInfo.Duration = connInfo.EndTime.Sub(start)
 		}
+	}()
 
-		srvInfo.serverConnection <- newConnectionConst
-		srvInfo.connectInfo <- connectionInstance(conn)
-		conn.Close()
-	}
-
+	return connectionInstance(ctx, conn, srvInfo, workerID)
 }
 
 /*-----------------------------------------------------------------------------
@@ -142,64 +1108,360 @@ func worker(srvInfo serverInfo) {
 --
 -- DATE:        February 6, 2016
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - one reader is kept for every request on the connection
+--              October 15, 2026 - the connection is attributed to its worker
+--              October 15, 2026 - closed once the client has sent too much
+--              October 15, 2026 - records the credentials of Unix socket clients
+--              October 15, 2026 - a connection closed by the shutdown isn't
+--                reported as an error
+--              October 15, 2026 - the close time is read from the server clock
+--              October 15, 2026 - records how long the connection was served for
+--              October 15, 2026 - records the negotiated TLS version and cipher suite
+--              October 15, 2026 - closed without an error after an undersized request
+--              October 15, 2026 - bounds the TLS handshake by -handshake-timeout
+--              October 15, 2026 - records the response to request size ratio
+--              October 15, 2026 - held open after the client finishes for -hold-open
+--              October 15, 2026 - records when the connection started being served
+--              October 15, 2026 - resets the connection after -fail-after bytes
+--              October 15, 2026 - closes idle connections without logging an error
+--              October 15, 2026 - passes on the latency recorder
+--              October 15, 2026 - counts plaintext sent to a TLS listener apart from other handshake errors
+--              October 15, 2026 - oversized requests are logged and counted with their own reason
+--              October 15, 2026 - watched by the stuck watchdog
+--              October 15, 2026 - closed with the shutdown reason once its context is cancelled
+--              October 15, 2026 - stops being watched even if serving it panics
+--              October 15, 2026 - logs through the leveled logger, EOF at debug
+--              October 15, 2026 - the client is the one named by the
+--                -proxy-protocol header
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:   func connectionInstance(conn net.Conn) connectionInfo
+-- INTERFACE:   func connectionInstance(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) connectionInfo
+--       ctx:		cancelled when the server stops serving connections.
 --      conn:		a connection to a client.
+--   srvInfo:		information about the overall server
+--  workerID:		the worker handling the connection.
 --
 -- RETURNS:   connectionInfo information about the connection when it's complete
 --
--- NOTES:			This is the main data handling function
+-- NOTES:			This is the main data handling function. Every request on the
+--						connection is handled by the worker that accepted it. The
+--						connection is closed after the request that takes the bytes
+--						received to -max-bytes-per-conn. With -hold-open the server
+--						keeps its side open and silent for that long after the client
+--						finishes, a drain waits for the hold like any other connection.
+--						With -proxy-protocol conn's remote address is the client named
+--						by its PROXY header, read by the worker.
 ------------------------------------------------------------------------------*/
-func connectionInstance(conn net.Conn) connectionInfo {
-	connInfo := connectionInfo{HostName: conn.RemoteAddr().String()}
+func connectionInstance(ctx context.Context, conn net.Conn, srvInfo serverInfo, workerID int) connectionInfo {
+	cfg := srvInfo.config
+	start := srvInfo.clock.Now()
+	connInfo := connectionInfo{HostName: conn.RemoteAddr().String(),
+		LocalAddr: conn.LocalAddr().String(), WorkerID: workerID, StartTime: start}
+	if cfg.peerCred {
+		connInfo.PeerCred = peerCredentials(conn)
+	}
+	tlsConn := tlsConnection(conn)
+	if tlsConn != nil && cfg.handshakeTimeout > 0 {
+		if err := handshake(tlsConn, cfg.handshakeTimeout); err != nil {
+			if isTimeout(err) {
+				connInfo.CloseReason = closeReasonHandshakeTimeout
+			} else if isPlaintextOnTLS(tlsConn, err) {
+				connInfo.CloseReason = closeReasonPlaintext
+			} else {
+				srvInfo.logger.Warn("TLS handshake failed", "host", connInfo.HostName, "err", err)
+				connInfo.CloseReason = "error"
+			}
+			connInfo.EndTime = srvInfo.clock.Now()
+			connInfo.Duration = connInfo.EndTime.Sub(start)
+			return connInfo
+		}
+	}
+	progress := srvInfo.watchdog.watch(connInfo.HostName)
+	defer srvInfo.watchdog.unwatch(progress)
+	conn = &countingConn{Conn: conn, connInfo: &connInfo, transferred: srvInfo.bytesTransferred,
+		countReads: cfg.countReads, failAfter: cfg.failAfter, progress: progress}
+	state := connectionState{cfg: cfg,
+		dedup: newDedupCache(cfg.dedupWindow, cfg.dedupSize), rng: srvInfo.rng, nonces: srvInfo.nonces, clock: srvInfo.clock,
+		fair: srvInfo.fair, latency: srvInfo.latency, progress: progress, logger: srvInfo.logger}
+	reader := bufio.NewReader(conn)
 	for {
-		err := handleData(conn, &connInfo)
+		err := handleData(ctx, conn, reader, &connInfo, &state)
 		if err == nil {
+			if limit := cfg.maxBytesPerConn; limit > 0 && connInfo.BytesReceived >= limit {
+				connInfo.CloseReason = closeReasonByteCap
+				break
+			}
 			continue
 		} else if err == io.EOF {
+			srvInfo.logger.Debug("Client closed the connection", "host", connInfo.HostName)
+			if cfg.holdOpen > 0 {
+				held := srvInfo.clock.Now()
+				srvInfo.clock.Sleep(cfg.holdOpen)
+				connInfo.HeldOpen = srvInfo.clock.Now().Sub(held)
+			}
+			break
+		} else if errors.Is(err, errClosedByShutdown) || errors.Is(err, context.Canceled) {
+			connInfo.CloseReason = closeReasonShutdown
+			break
+		} else if err == errRequestTooSmall {
+			connInfo.CloseReason = closeReasonUndersized
+			break
+		} else if err == errRequestTooLarge {
+			srvInfo.logger.Warn("Closing the connection", "host", connInfo.HostName, "err", err)
+			connInfo.CloseReason = closeReasonOversized
+			break
+		} else if cfg.idleTimeout > 0 && isTimeout(err) {
+			connInfo.CloseReason = closeReasonIdle
+			break
+		} else if err == errInjectedFailure {
+			connInfo.CloseReason = closeReasonInjectedFailure
+			abortOnClose(conn)
+			break
+		} else if tlsConn != nil && isPlaintextOnTLS(tlsConn, err) {
+			connInfo.CloseReason = closeReasonPlaintext
 			break
 		}
-		log.Println(err)
+		srvInfo.logger.Warn("Connection failed", "host", connInfo.HostName, "err", err)
+		connInfo.CloseReason = "error"
+		if isRenegotiationError(err) {
+			connInfo.CloseReason = closeReasonRenegotiation
+		}
 		break
 	}
+	connInfo.Stalls = progress.stalls()
+	if cfg.tlsStats && tlsConn != nil {
+		connInfo.TLSVersion, connInfo.TLSCipherSuite = tlsNegotiated(tlsConn)
+	}
+	if connInfo.BytesReceived > 0 {
+		connInfo.ResponseRatio = float64(connInfo.BytesSent) / float64(connInfo.BytesReceived)
+	}
+	connInfo.EndTime = srvInfo.clock.Now()
+	connInfo.Duration = connInfo.EndTime.Sub(start)
 
 	return connInfo
 }
 
 /*-----------------------------------------------------------------------------
--- FUNCTION:    connectionInstance
+-- FUNCTION:    handleData
 --
 -- DATE:        February 6, 2016
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - requests are read using the configured framing
+--              October 15, 2026 - reads from the connection's reader so pipelined requests aren't lost
+--              October 15, 2026 - requests are attributed to a worker
+--              October 15, 2026 - repeated requests are answered from a cache
+--              October 15, 2026 - buffered requests can be answered in one write
+--              October 15, 2026 - oversized requests are NACKed in ack mode
+--              October 15, 2026 - unanswered requests are capped by -max-inflight
+--              October 15, 2026 - requests wait for a -fair-slots slot
+--              October 15, 2026 - reads are bounded by -idle-timeout
+--              October 15, 2026 - records the latency of each request
+--              October 15, 2026 - marks the connection busy for the stuck watchdog
+--              October 15, 2026 - aborts waiting for a request once its context is cancelled
+--              October 15, 2026 - the coalesce window is timed on the connection clock
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:   func connectionInstance(conn net.Conn) connectionInfo
+-- INTERFACE:   func handleData(ctx context.Context, conn net.Conn, reader *bufio.Reader, connInfo *connectionInfo, state *connectionState) error
+--       ctx:		cancelled when the server stops serving connections.
 --      conn:		a connection to a client.
+--    reader:		buffers the connection's reads for as long as it is served.
+--  connInfo:		information about the connection to be updated.
+--     state:		the state kept for the connection between requests.
 --
--- RETURNS:   connectionInfo information about the connection when it's complete
+-- RETURNS:   error any error reading or echoing the request
 --
--- NOTES:			This is the main data handling function
+-- NOTES:			Echos a request back to the client. With a coalesce window any
+--						further requests that arrive within the window are answered in
+--						the same write, in the order they were received. In ack mode
+--						a request over the size limit is answered with a NACK before
+--						the connection is closed. No more than -max-inflight requests
+--						are left unanswered, once that many have been read their
+--						responses are written before reading carries on.
+--						Without a coalesce window each request is answered before the
+--						next is read, so only one is ever in flight. With -fair-slots
+--						the requests are processed and answered once the connection's
+--						tag is given a slot, a connection's first request is processed
+--						before its labels are known so it counts as untagged. With
+--						-idle-timeout the client has that long to send each request.
+--						With -latency-hdr the time from each request being read to
+--						its response being written is recorded.
+--						With -stuck-threshold the connection is watched for stalls
+--						from the first request being read until this returns.
+--						Cancelling ctx aborts a read waiting for the next request by
+--						moving the read deadline to now, ctx's error is returned.
 ------------------------------------------------------------------------------*/
-func handleData(conn net.Conn, connInfo *connectionInfo) error {
-	reader := bufio.NewReader(conn)
-	data, err := reader.ReadBytes('\n')
+func handleData(ctx context.Context, conn net.Conn, reader *bufio.Reader, connInfo *connectionInfo, state *connectionState) error {
+	cfg := state.cfg
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var readDeadline time.Time
+	if cfg.idleTimeout > 0 {
+		readDeadline = time.Now().Add(cfg.idleTimeout)
+		conn.SetReadDeadline(readDeadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	data, err := readRequest(reader, cfg)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if nack := nackResponse(err, connInfo.NumberOfRequests+1, cfg); nack != nil {
+			writeResponse(ctx, conn, nack, state)
+		}
+		return err
+	}
+	received := []time.Time{state.clock.Now()}
+	state.progress.begin()
+	defer state.progress.end()
+	if state.fair != nil {
+		connInfo.FairWait += state.fair.acquire(connInfo.Labels[cfg.fairLabel], state.clock)
+		defer state.fair.release()
+	}
+	response := frameResponse(processRequest(data, connInfo, state), cfg)
+
+	if cfg.coalesceWindow > 0 {
+		end := state.clock.Now().Add(cfg.coalesceWindow)
+		for inflight := 1; waitForRequest(ctx, conn, reader, state, end, readDeadline); inflight++ {
+			if cfg.maxInflight > 0 && inflight >= cfg.maxInflight {
+				connInfo.BackpressureEvents++
+				if err = writeResponse(ctx, conn, response, state); err != nil {
+					return err
+				}
+				state.latency.record(received, state.clock.Now())
+				response, inflight, received = nil, 0, nil
+			}
+			if data, err = readRequest(reader, cfg); err != nil {
+				writeResponse(ctx, conn, append(response, nackResponse(err, connInfo.NumberOfRequests+1, cfg)...), state)
+				return err
+			}
+			received = append(received, state.clock.Now())
+			response = append(response, frameResponse(processRequest(data, connInfo, state), cfg)...)
+			if inflight > 0 {
+				connInfo.CoalescedRequests++
+			}
+		}
+	}
+
+	if err = writeResponse(ctx, conn, response, state); err != nil {
 		return err
 	}
+	state.latency.record(received, state.clock.Now())
+
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    processRequest
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the first request can label the connection
+--              October 15, 2026 - delays and dedup windows use the connection clock
+--              October 15, 2026 - echoes can be checked against their request
+--              October 15, 2026 - records how far each response delay was from its target
+--              October 15, 2026 - runs requests through -pipeline
+--              October 15, 2026 - logs through the leveled logger
+--              October 15, 2026 - the self-check is given a copy of the request as read
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func processRequest(data []byte, connInfo *connectionInfo, state *connectionState) []byte
+--      data:		the payload of the request.
+--  connInfo:		information about the connection to be updated.
+--     state:		the state kept for the connection between requests.
+--
+-- RETURNS:   []byte the payload of the response.
+--
+-- NOTES:			The echo is wrapped in the echo prefix and suffix if they are
+--						set. A request repeated within the dedup window is answered
+--						with dedupMarker instead. If response delays are on this
+--						sleeps for the sampled delay first and records how far the
+--						sleep was from it. In ack mode the echo is
+--						replaced by an ACK carrying the request's number. With -nonce
+--						the echo carries the server's time and a sequence number, and
+--						with -debug-tee a copy of it is written to stderr. With
+--						-self-check the echo is checked against a copy of the request
+--						taken before anything else touches it. With
+--						-connection-labels a first request of labels is recorded and
+--						still answered like any other request. With -pipeline the
+--						request goes through its stages before it is echoed, a request
+--						a stage fails on is logged and echoed as it was received.
+------------------------------------------------------------------------------*/
+func processRequest(data []byte, connInfo *connectionInfo, state *connectionState) []byte {
+	var received []byte
+	if state.cfg.selfCheck {
+		received = bytes.Clone(data)
+	}
 	connInfo.AmmountOfData += len(data)
 	connInfo.NumberOfRequests++
-	conn.Write(data)
+	if state.cfg.connectionLabels && connInfo.NumberOfRequests == 1 {
+		body, _ := splitDelimiter(data, state.cfg)
+		connInfo.Labels = parseLabels(body)
+	}
 
-	return nil
+	if delay := responseDelay(state.cfg, state.rng); delay > 0 {
+		slept := state.clock.Now()
+		state.clock.Sleep(delay)
+		deviation := state.clock.Now().Sub(slept) - delay
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		connInfo.DelayedRequests++
+		connInfo.ResponseDelay += delay
+		if delay > connInfo.MaxResponseDelay {
+			connInfo.MaxResponseDelay = delay
+		}
+		connInfo.PacingDeviation += deviation
+		if deviation > connInfo.MaxPacingDeviation {
+			connInfo.MaxPacingDeviation = deviation
+		}
+	}
+
+	if state.dedup.duplicate(data, state.clock.Now()) {
+		connInfo.DedupHits++
+		_, delimiter := splitDelimiter(data, state.cfg)
+		return append([]byte(dedupMarker), delimiter...)
+	}
+
+	if state.cfg.ack {
+		return ackResponse(connInfo.NumberOfRequests, state.cfg)
+	}
+
+	if state.cfg.pipeline != nil {
+		body, delimiter := splitDelimiter(data, state.cfg)
+		transformed, err := state.cfg.pipeline.run(body)
+		if err != nil {
+			state.logger.Warn("Pipeline failed", "host", connInfo.HostName, "request", connInfo.NumberOfRequests, "err", err)
+			connInfo.PipelineErrors++
+		} else {
+			connInfo.PipelineBytesIn += len(body)
+			connInfo.PipelineBytesOut += len(transformed)
+			data = append(transformed[:len(transformed):len(transformed)], delimiter...)
+		}
+	}
+	response := buildResponse(data, state.cfg)
+	if state.cfg.nonce {
+		response = appendNonce(response, state.clock.Now(), atomic.AddInt64(state.nonces, 1), state.cfg)
+	}
+	if state.cfg.selfCheck && !echoMatches(received, response, state.cfg) {
+		state.logger.Warn("Echo mismatch", "host", connInfo.HostName, "request", connInfo.NumberOfRequests)
+		connInfo.EchoMismatches++
+	}
+	if state.cfg.debugTee {
+		teeEcho(os.Stderr, connInfo.HostName, response, state.cfg.debugTeeLength)
+	}
+
+	return response
 }
 
 /*-----------------------------------------------------------------------------
@@ -207,7 +1469,35 @@ func handleData(conn net.Conn, connInfo *connectionInfo) error {
 --
 -- DATE:        February 6, 2016
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - statistics moved to serverStats, old
+--                connections are pruned after the retention window
+--              October 15, 2026 - stops the server when asked by a worker
+--              October 15, 2026 - samples the bytes transferred on a ticker
+--              October 15, 2026 - spawns workers held back by the spawn rate
+--              October 15, 2026 - drains connections on the first signal, a
+--                second signal forces the server to exit
+--              October 15, 2026 - handles batches of finished connections
+--              October 15, 2026 - resizes the pool for the control endpoint
+--              October 15, 2026 - reports the connections a cut short drain
+--                closed
+--              October 15, 2026 - tickers and timeouts come from the server clock
+--              October 15, 2026 - keeps accepting for -accept-drain after a signal
+--              October 15, 2026 - pushes metrics to -otlp-endpoint
+--              October 15, 2026 - answers requests for a snapshot of the statistics
+--              October 15, 2026 - writes a report every -report-interval
+--              October 15, 2026 - clears the statistics for the admin protocol
+--              October 15, 2026 - exits with 0 when a signal stops the server cleanly
+--              October 15, 2026 - flags the server as draining for the readiness check
+--              October 15, 2026 - updates the Prometheus metrics
+--              October 15, 2026 - cancels the workers' context when a drain is cut short
+--              October 15, 2026 - the pool isn't resized with -mode epoll
+--              October 15, 2026 - the pool isn't resized with -proto udp
+--              October 15, 2026 - writes a line of running totals every -snapshot-interval
+--              October 15, 2026 - logs shutdown events through the leveled logger
+--              October 15, 2026 - a closed listener drains under -shutdown-timeout
+--              October 15, 2026 - resets the clients' peaks with the statistics
+--              October 15, 2026 - a drain waits for the records of the finished connections
+--              October 15, 2026 - handles finishedConnectionConst from batching workers
 --
 -- DESIGNER:		Marc Vouve
 --
@@ -219,27 +1509,459 @@ func handleData(conn net.Conn, connInfo *connectionInfo) error {
 --
 -- RETURNS:   connectionInfo information about the connection when it's complete
 --
--- NOTES:			This is the main data handling function
+-- NOTES:			This is the main data handling function. With a shutdown timeout
+--						the first signal stops accepting connections and waits up to
+--						the timeout for the connections being served to finish. A
+--						second signal while draining closes them straight away. When
+--						the drain is cut short the workers' context is cancelled and
+--						connections are closed in -drain-order
+--						and reported with the "shutdown" CloseReason. With
+--						-accept-drain the listener stays open after the first signal
+--						so queued connections are served, then the connections
+--						still queued are sent -close-message before it closes.
+--						A signal is a request to stop so the server exits with 0 once
+--						the connections have finished, and with 1 only if the drain
+--						was cut short and connections were closed on their clients.
+--						A listener closed under the server drains the same way, with
+--						-shutdown-timeout, as nothing more can be accepted. The drain
+--						is over once drained says so, not when the registry empties.
 ------------------------------------------------------------------------------*/
 func observerLoop(srvInfo serverInfo, osSignals chan os.Signal) {
-	currentConnections := 0
-	connectionsMade := list.New()
+	stats := newServerStats(srvInfo.config.reportGroupBy)
+
+	var throughputSamples <-chan time.Time
+	if srvInfo.config.throughputSample > 0 {
+		ticker := srvInfo.clock.NewTicker(srvInfo.config.throughputSample)
+		defer ticker.Stop()
+		throughputSamples = ticker.Chan()
+	}
+
+	var spawnTicks <-chan time.Time
+	if rate := srvInfo.config.workerSpawnRate; rate > 0 {
+		ticker := srvInfo.clock.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		spawnTicks = ticker.Chan()
+	}
+
+	var closeBatchTicks <-chan time.Time
+	if srvInfo.closeBatch != nil {
+		ticker := srvInfo.clock.NewTicker(closeBatchFlush)
+		defer ticker.Stop()
+		closeBatchTicks = ticker.Chan()
+	}
+
+	var otlpTicks <-chan time.Time
+	if srvInfo.otlp != nil {
+		ticker := srvInfo.clock.NewTicker(srvInfo.config.otlpInterval)
+		defer ticker.Stop()
+		otlpTicks = ticker.Chan()
+	}
+	started := srvInfo.clock.Now()
+
+	var snapshotTicks <-chan time.Time
+	if srvInfo.config.snapshotInterval > 0 {
+		ticker := srvInfo.clock.NewTicker(srvInfo.config.snapshotInterval)
+		defer ticker.Stop()
+		snapshotTicks = ticker.Chan()
+	}
+
+	var reportTicks <-chan time.Time
+	if srvInfo.config.reportInterval > 0 {
+		ticker := srvInfo.clock.NewTicker(srvInfo.config.reportInterval)
+		defer ticker.Stop()
+		reportTicks = ticker.Chan()
+	}
+
+	draining := false
+	var drainTimeout, acceptDrainEnd, listenerClose <-chan time.Time
 
 	for {
 		select {
-		case <-srvInfo.serverConnection:
-			currentConnections++
+		case event := <-srvInfo.serverConnection:
+			if event == workerRetiredConst {
+				workerRetired(srvInfo)
+				continue
+			}
+			if event == finishedConnectionConst {
+				finishedConnection(srvInfo)
+				continue
+			}
+			stats.connectionOpened()
+			srvInfo.metrics.connectionOpened()
+			srvInfo.metrics.setCurrent(stats.currentConnections)
 			newConnection(srvInfo)
+		case resize := <-srvInfo.workerResize:
+			if resize.target > 0 && servedByWorkers(srvInfo.config) {
+				resizePool(srvInfo, resize.target)
+			}
+			resize.reply <- *srvInfo.activeWorkers
+		case reply := <-srvInfo.statsRequests:
+			reply <- stats.snapshot()
+		case reply := <-srvInfo.statsResets:
+			stats.reset()
+			srvInfo.connections.resetClients()
+			started = srvInfo.clock.Now()
+			reply <- true
 		case serverHost := <-srvInfo.connectInfo:
-			serverHost.ConnectionsAtClose = currentConnections
-			connectionsMade.PushBack(serverHost)
-			currentConnections--
+			connectionsClosed(srvInfo, stats, serverHost.EndTime, serverHost)
+		case batch := <-srvInfo.connectInfoBatch:
+			connectionsClosed(srvInfo, stats, batchFilled(batch), batch...)
+		case now := <-closeBatchTicks:
+			connectionsClosed(srvInfo, stats, now, srvInfo.closeBatch.take()...)
+		case now := <-spawnTicks:
+			spawnPendingWorkers(srvInfo, now)
+		case now := <-throughputSamples:
+			stats.sampleThroughput(now, atomic.LoadInt64(srvInfo.bytesTransferred))
+		case now := <-snapshotTicks:
+			if err := writeSnapshotLine(srvInfo.snapshotFile, now, stats.snapshot()); err != nil {
+				srvInfo.logger.Warn("Unable to write the snapshot", "err", err)
+			}
+		case now := <-reportTicks:
+			connectionsClosed(srvInfo, stats, now, srvInfo.closeBatch.take()...)
+			writeReport(srvInfo, stats)
+			if srvInfo.config.resetOnReport {
+				stats.reset()
+				srvInfo.connections.resetClients()
+				started = now
+			}
+		case now := <-otlpTicks:
+			if payload, err := otlpMetrics(srvInfo, stats, started, now); err != nil {
+				srvInfo.logger.Warn("Unable to encode OTLP metrics", "err", err)
+			} else {
+				srvInfo.otlp.export(payload)
+			}
 		case <-osSignals:
-			generateReport(time.Now().String(), connectionsMade)
-			fmt.Println("Total connections made:", connectionsMade.Len())
-			os.Exit(1)
+			if draining {
+				srvInfo.logger.Warn("Signal received while draining, shutdown escalated")
+				pending := srvInfo.connections.len()
+				srvInfo.cancel()
+				srvInfo.connections.closeAll(srvInfo.config.drainOrder == drainLIFO)
+				collectShutdownClosed(srvInfo, stats, pending)
+				exitServer(srvInfo, stats, 1)
+			}
+			if srvInfo.config.shutdownTimeout <= 0 ||
+				srvInfo.config.acceptDrain <= 0 && drained(srvInfo, stats) {
+				exitServer(srvInfo, stats, 0)
+			}
+			draining = true
+			atomic.StoreInt32(srvInfo.draining, 1)
+			if srvInfo.config.acceptDrain > 0 {
+				srvInfo.logger.Info("Accepting queued connections, signal again to exit now", "for", srvInfo.config.acceptDrain)
+				acceptDrainEnd = srvInfo.clock.After(srvInfo.config.acceptDrain)
+				continue
+			}
+			srvInfo.logger.Info("Draining connections, signal again to exit now", "connections", srvInfo.connections.len())
+			drainTimeout = srvInfo.clock.After(srvInfo.config.shutdownTimeout)
+			srvInfo.listener.Close()
+		case <-acceptDrainEnd:
+			atomic.StoreInt32(srvInfo.acceptClosing, 1)
+			listenerClose = srvInfo.clock.After(acceptDrainFlush)
+		case <-listenerClose:
+			srvInfo.logger.Info("Draining connections, signal again to exit now", "connections", srvInfo.connections.len())
+			drainTimeout = srvInfo.clock.After(srvInfo.config.shutdownTimeout)
+			srvInfo.listener.Close()
+		case <-drainTimeout:
+			pending := srvInfo.connections.len()
+			srvInfo.logger.Warn("Timed out draining", "connections", pending)
+			srvInfo.cancel()
+			srvInfo.connections.closeAll(srvInfo.config.drainOrder == drainLIFO)
+			collectShutdownClosed(srvInfo, stats, pending)
+			exitServer(srvInfo, stats, 1)
+		case reason := <-srvInfo.shutdown:
+			if reason != nil {
+				srvInfo.logger.Error("Shutting down", "err", reason)
+				exitServer(srvInfo, stats, 1)
+			}
+			if draining {
+				continue // the drain closed the listener
+			}
+			if srvInfo.config.shutdownTimeout <= 0 || drained(srvInfo, stats) {
+				srvInfo.logger.Info("Listener closed, shutting down")
+				exitServer(srvInfo, stats, 0)
+			}
+			draining = true
+			atomic.StoreInt32(srvInfo.draining, 1)
+			srvInfo.logger.Info("Listener closed, draining connections, signal to exit now", "connections", srvInfo.connections.len())
+			drainTimeout = srvInfo.clock.After(srvInfo.config.shutdownTimeout)
+		}
+
+		if drainTimeout != nil && drained(srvInfo, stats) {
+			srvInfo.logger.Info("Connections drained, shutting down")
+			exitServer(srvInfo, stats, 0)
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionsClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - updates the Prometheus metrics
+--              October 15, 2026 - drops statistics while the observer is over -max-observer-lag
+--              October 15, 2026 - logs through the leveled logger
+--              October 15, 2026 - the lag is timed from the connections being
+--                handed over, batched workers are already available
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func connectionsClosed(srvInfo serverInfo, stats *serverStats, handed time.Time, closed ...connectionInfo)
+--   srvInfo:		Information about the server.
+--     stats:		the statistics kept by the observer.
+--    handed:		when the connections were handed to the observer.
+--    closed:		the connections the workers have finished with.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Handles finished connections whether they were sent alone or
+--						in a batch. Connections in a batch see ConnectionsAtClose as
+--						it was when the batch arrived. The health probe's connections
+--						are left out of the statistics. The lag is the time from the
+--						connections being handed to the observer to it getting to
+--						them. A connection sent alone is handed over when it closes,
+--						a full batch when its last connection closes, and the
+--						observer takes a batch that hasn't filled itself, so the time
+--						a connection waits in a batch isn't lag and -close-batch
+--						doesn't trip -max-observer-lag. While the lag is over
+--						-max-observer-lag connections are only counted, the rest of
+--						their statistics are dropped so the observer can catch up and
+--						the workers aren't held up sending to it. The workers of
+--						batched connections were made available when they finished.
+------------------------------------------------------------------------------*/
+func connectionsClosed(srvInfo serverInfo, stats *serverStats, handed time.Time, closed ...connectionInfo) {
+	now := srvInfo.clock.Now()
+	for _, serverHost := range closed {
+		lag := now.Sub(handed)
+		stats.recordLag(lag)
+		if serverHost.CloseReason == closeReasonProbe {
+			stats.probeClosed()
+		} else if limit := srvInfo.config.maxObserverLag; limit > 0 && lag > limit {
+			if !stats.lagGuardTripped {
+				srvInfo.logger.Warn("Observer is behind, dropping connection statistics until it catches up", "lag", lag)
+				stats.lagGuardTripped = true
+			}
+			stats.connectionDropped()
+			srvInfo.metrics.connectionClosed(serverHost)
+		} else {
+			stats.connectionClosed(serverHost)
+			srvInfo.metrics.connectionClosed(serverHost)
+		}
+		if srvInfo.closeBatch == nil {
+			finishedConnection(srvInfo)
+		}
+	}
+	srvInfo.metrics.setCurrent(stats.currentConnections)
+	if srvInfo.config.retention > 0 {
+		stats.prune(srvInfo.clock.Now().Add(-srvInfo.config.retention))
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    collectShutdownClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - reads the workers' events while it waits
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func collectShutdownClosed(srvInfo serverInfo, stats *serverStats, pending int)
+--   srvInfo:		Information about the server.
+--     stats:		the statistics the connections are added to.
+--   pending:		the number of connections the shutdown closed.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called by the observer after closing the registry so the
+--						closed connections make it into the report. Gives up after
+--						shutdownCollectWait, connections left in a batch are taken
+--						by exitServer. Events from the workers are still read so a
+--						batching worker isn't held up sending finishedConnectionConst
+--						before its connection is batched.
+------------------------------------------------------------------------------*/
+func collectShutdownClosed(srvInfo serverInfo, stats *serverStats, pending int) {
+	timeout := srvInfo.clock.After(shutdownCollectWait)
+	for pending > 0 {
+		select {
+		case event := <-srvInfo.serverConnection:
+			if event == newConnectionConst {
+				stats.connectionOpened()
+			}
+		case serverHost := <-srvInfo.connectInfo:
+			connectionsClosed(srvInfo, stats, serverHost.EndTime, serverHost)
+			pending--
+		case batch := <-srvInfo.connectInfoBatch:
+			connectionsClosed(srvInfo, stats, batchFilled(batch), batch...)
+			pending -= len(batch)
+		case <-timeout:
+			return
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    drained
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func drained(srvInfo serverInfo, stats *serverStats) bool
+--   srvInfo:		Information about the server.
+--     stats:		the statistics kept by the observer.
+--
+-- RETURNS:     bool true once every connection has finished and the observer
+--              has its record.
+--
+-- NOTES:			A worker takes its connection out of the registry before its
+--						record reaches the observer, and tells the observer about a
+--						new connection on a buffered channel, so an empty registry
+--						alone could exit without the last connections in the report.
+--						Once the registry is empty and no new connection is waiting
+--						to be counted, the connections left in a close batch are
+--						taken, as nothing else would fill it, and the drain is over
+--						when the observer has no connections still open. UDP sources
+--						are only finished on exit, so they aren't waited on.
+------------------------------------------------------------------------------*/
+func drained(srvInfo serverInfo, stats *serverStats) bool {
+	if srvInfo.connections.len() > 0 || len(srvInfo.serverConnection) > 0 {
+		return false
+	}
+	connectionsClosed(srvInfo, stats, srvInfo.clock.Now(), srvInfo.closeBatch.take()...)
+
+	return srvInfo.config.proto == protoUDP || stats.currentConnections == 0
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    requestShutdown
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func requestShutdown(srvInfo serverInfo, reason error)
+--   srvInfo:		Information about the server.
+--    reason:		why the server is stopping, nil if it is a clean stop.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Safe to call from any go routine. Only the first request is
+--						acted on, the rest are dropped.
+------------------------------------------------------------------------------*/
+func requestShutdown(srvInfo serverInfo, reason error) {
+	select {
+	case srvInfo.shutdown <- reason:
+	default:
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    exitServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the listed connections can be filtered by label
+--              October 15, 2026 - the report is named from the server clock
+--              October 15, 2026 - lists the clients with the most concurrent connections
+--              October 15, 2026 - the report is written by writeReport
+--              October 15, 2026 - writes the latency histogram
+--              October 15, 2026 - stops the metrics server
+--              October 15, 2026 - writes the exit summary to -summary-fd
+--              October 15, 2026 - removes the Unix socket file
+--              October 15, 2026 - reports the -proto udp sources
+--              October 15, 2026 - the total goes to stderr when the report is on stdout
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func exitServer(srvInfo serverInfo, stats *serverStats, exitCode int)
+--   srvInfo:		Information about the server.
+--     stats:		the statistics to report.
+--  exitCode:		the status the process exits with.
+--
+-- RETURNS:     does not return
+--
+-- NOTES:			Writes the report, including any connections still waiting in
+--						a batch and every -proto udp source, flushes the trace, closes
+--						a Unix socket listener so its file is removed and exits the
+--						process.
+------------------------------------------------------------------------------*/
+func exitServer(srvInfo serverInfo, stats *serverStats, exitCode int) {
+	for _, serverHost := range append(srvInfo.closeBatch.take(), srvInfo.udp.finish()...) {
+		if serverHost.CloseReason != closeReasonProbe {
+			stats.connectionClosed(serverHost)
 		}
 	}
+	summary := writeReport(srvInfo, stats)
+	totalsOut := os.Stdout
+	if srvInfo.config.reportFile == "-" {
+		totalsOut = os.Stderr // keep stdout to the report
+	}
+	fmt.Fprintln(totalsOut, "Total connections made:", summary.GrandTotalConnections)
+	writeExitSummary(srvInfo.summaryFile, summary, exitCode)
+	srvInfo.latency.writeLog(srvInfo.clock.Now())
+	srvInfo.metrics.shutdown()
+	stopTrace(srvInfo.traceFile)
+	if srvInfo.config.network == networkUnix {
+		srvInfo.listener.Close() // removes the socket file
+	}
+	os.Exit(exitCode)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeReport
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the summary records when the report was written
+--              October 15, 2026 - adds the request latency percentiles
+--              October 15, 2026 - adds the rollups of the listed connections
+--              October 15, 2026 - written to -report-file
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeReport(srvInfo serverInfo, stats *serverStats) reportSummary
+--   srvInfo:		Information about the server.
+--     stats:		the statistics to report.
+--
+-- RETURNS:     reportSummary the summary written to the report.
+--
+-- NOTES:			Moved out of exitServer so reports can also be written while
+--						the server runs. With -report-filter only the matching
+--						connections are listed, the summary still covers every
+--						connection.
+------------------------------------------------------------------------------*/
+func writeReport(srvInfo serverInfo, stats *serverStats) reportSummary {
+	summary := stats.summary()
+	summary.GeneratedAt = srvInfo.clock.Now()
+	srvInfo.latency.percentiles(&summary)
+	summary.NonceEcho = srvInfo.config.nonce
+	summary.NoncesIssued = atomic.LoadInt64(srvInfo.nonces)
+	summary.UniqueClients, summary.TopClients = srvInfo.connections.clients(topClients)
+	connections := stats.connectionsMade
+	if srvInfo.config.reportFilter != "" {
+		connections = filterByLabel(connections, srvInfo.config.reportFilter)
+	}
+	rollupConnections(connections, &summary)
+	writeReportFile(srvInfo.config.reportFile, summary.GeneratedAt.String(), connections, summary,
+		srvInfo.config.reportFormat, srvInfo.config.reportCompress)
+
+	return summary
 }
 
 /*-----------------------------------------------------------------------------
@@ -247,25 +1969,90 @@ func observerLoop(srvInfo serverInfo, osSignals chan os.Signal) {
 --
 -- DATE:        February 6, 2016
 --
--- REVISIONS:
+-- REVISIONS:   October 15, 2026 - starts on the real clock
+--              October 15, 2026 - added the accept drain flag
+--              October 15, 2026 - starts the OTLP exporter
+--              October 15, 2026 - added the snapshot requests
+--              October 15, 2026 - the listener can enable TCP Fast Open
+--              October 15, 2026 - added the accept gate and stats resets
+--              October 15, 2026 - added the fair scheduler
+--              October 15, 2026 - creates the draining flag
+--              October 15, 2026 - creates the latency recorder
+--              October 15, 2026 - starts the metrics server
+--              October 15, 2026 - opens -summary-fd
+--              October 15, 2026 - creates the context the workers are cancelled by
+--              October 15, 2026 - listens on -network, replacing a stale Unix socket file
+--              October 15, 2026 - listens for datagrams with -proto udp
+--              October 15, 2026 - opens the -snapshot-file
+--              October 15, 2026 - creates the leveled logger
+--              October 15, 2026 - creates the -allow-cidr and -deny-cidr filter
+--              October 15, 2026 - creates the -rate-limit buckets
+--              October 15, 2026 - -fair-weights is read by parseConfig
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:   func newServerInfo() serverInfo
+-- INTERFACE:   func newServerInfo(cfg serverConfig) serverInfo
+--       cfg:		the server configuration.
 --
 -- RETURNS:   serverInfo information about the server
 --
--- NOTES:			This function builds the basic info about the server.
+-- NOTES:			This function builds the basic info about the server. The
+--						listener is wrapped in TLS if a certificate is configured.
 ------------------------------------------------------------------------------*/
-func newServerInfo() serverInfo {
-	var err error
+func newServerInfo(cfg serverConfig) serverInfo {
 	srvInfo := serverInfo{totalConnections: new(int), availableServers: new(int),
-		serverConnection: make(chan int, 10), connectInfo: make(chan connectionInfo)}
-	if srvInfo.listener, err = net.Listen("tcp", os.Args[1]); err != nil {
+		workersSpawned: new(int), activeWorkers: new(int),
+		pendingWorkers: new(int), lastSpawn: new(time.Time), acceptErrors: new(int64),
+		bytesTransferred: new(int64), serverConnection: make(chan int, 10), connectInfo: make(chan connectionInfo),
+		connectInfoBatch: make(chan []connectionInfo),
+		config:           &cfg, shutdown: make(chan error, 1), retire: make(chan bool, cfg.workerCap),
+		rng: newLockedRand(cfg.seed), connections: newConnRegistry(),
+		admission:  newAdmissionBucket(cfg.maxConnectionsPerSecond, cfg.connQueueSize),
+		closeBatch: newCloseBatcher(cfg.closeBatch), probes: new(sync.Map),
+		nonces: new(int64), workerResize: make(chan workerResize), retireQuota: new(int64),
+		retireIdle: make(chan struct{}), handedOff: make(chan acceptResult),
+		clock: realClock{}, acceptClosing: new(int32), draining: new(int32),
+		otlp: newOTLPExporter(cfg.otlpEndpoint), statsRequests: make(chan chan statsSnapshot),
+		statsResets: make(chan chan bool), acceptGate: newAcceptGate(),
+		logger: newLogger(os.Stderr, cfg.logLevel, cfg.logFormat), ipFilter: newIPFilter(cfg.allowCIDRs, cfg.denyCIDRs)}
+	srvInfo.logger.Info("Random seed", "seed", cfg.seed)
+	srvInfo.fair = newFairScheduler(cfg.fairSlots, cfg.fairWeights)
+	srvInfo.latency = newLatencyRecorder(cfg.latencyHDR, srvInfo.clock.Now())
+	srvInfo.metrics = newMetricsServer(cfg.metricsAddr)
+	srvInfo.summaryFile = openSummaryFD(cfg.summaryFD)
+	srvInfo.snapshotFile = openSnapshotFile(cfg.snapshotInterval, cfg.snapshotFile, cfg.reportMaxSize, cfg.reportKeep)
+	srvInfo.watchdog = newStuckWatchdog(cfg.stuckThreshold, srvInfo.clock)
+	srvInfo.connectionLimit = newConnectionLimit(cfg.maxConnections)
+	srvInfo.rateLimiter = newIPRateLimiter(cfg.rateLimit, srvInfo.clock)
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	var err error
+	if cfg.proto == protoUDP {
+		if srvInfo.udp, err = newUDPServer(cfg.network, cfg.address); err != nil {
+			log.Fatalln(err)
+		}
+		return srvInfo
+	}
+	var listenConfig net.ListenConfig
+	if cfg.tfo {
+		listenConfig.Control = tfoControl
+	}
+	if cfg.network == networkUnix {
+		if err = removeStaleSocket(cfg.address); err != nil {
+			log.Fatalln("Unable to listen on", cfg.address+":", err)
+		}
+	}
+	if srvInfo.listener, err = listenConfig.Listen(context.Background(), cfg.network, cfg.address); err != nil {
 		log.Fatalln(err)
 	}
+	if cfg.tlsCert != "" || cfg.tlsKey != "" {
+		tlsConfig, err := newTLSConfig(cfg.tlsCert, cfg.tlsKey)
+		if err != nil {
+			log.Fatalln("Unable to load TLS certificate:", err)
+		}
+		srvInfo.listener = tls.NewListener(srvInfo.listener, tlsConfig)
+	}
 
 	return srvInfo
 }
@@ -0,0 +1,1142 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 main_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newTestServerInfo(cfg serverConfig) serverInfo
+--  func serveTestConnection(t *testing.T, srvInfo serverInfo) (net.Conn, <-chan connectionInfo)
+--  func testChildReport() string
+--  func runTestChild(t *testing.T, test string) (string, int, string)
+--  func TestLazyPoolFloorSpikeFloor(t *testing.T)
+--  func goroutineID() string
+--  func TestRequestsAttributedToOneWorker(t *testing.T)
+--  func TestListenerClosedDrains(t *testing.T)
+--  func TestDrainWaitsForInFlightClose(t *testing.T)
+--  func TestMaxAcceptErrors(t *testing.T)
+--  func TestCoalescedInOrder(t *testing.T)
+--  func TestLocalAddrRecorded(t *testing.T)
+--  func TestWorkerSpawnRate(t *testing.T)
+--  func TestSecondSignalEscalates(t *testing.T)
+--  func TestMaxBytesPerConn(t *testing.T)
+--  func TestMaxInflightBackpressure(t *testing.T)
+--  func (listener deniedListener) Accept() (net.Conn, error)
+--  func (deniedListener) Close() error
+--  func (deniedListener) Addr() net.Addr
+--  func (closedConn) Close() error
+--  func BenchmarkAcceptYield(b *testing.B)
+--  func TestAcceptDrainServesBacklog(t *testing.T)
+--  func TestConcurrentConnectionsCounted(t *testing.T)
+--  func TestSequentialConnectionsReuseWorkers(t *testing.T)
+--  func TestSignalFinishesInFlightRequest(t *testing.T)
+--  func TestHoldOpenAfterHalfClose(t *testing.T)
+--  func TestObserverLagGuard(t *testing.T)
+--  func TestSummaryFD(t *testing.T)
+--  func (listener pipeListener) Accept() (net.Conn, error)
+--  func (pipeListener) Close() error
+--  func (pipeListener) Addr() net.Addr
+--  func TestCancelStopsWorkers(t *testing.T)
+--  func TestClosedListenerStopsWorkers(t *testing.T)
+--  func TestAcceptFailedBackoff(t *testing.T)
+--  func TestPanicRecovered(t *testing.T)
+--  func TestConnectionDuration(t *testing.T)
+--
+-- NOTES: Tests for the workers and the observer. The observer's handlers are
+--        called directly from the test, which stands in for the observer's
+--        go routine. Workers spawned by the tests are cancelled before they
+--        start, so they return without accepting, unless the test gives
+--        them a listener of their own. Tests of how the server
+--        exits run the observer in a child test process, as exitServer ends
+--        the process it runs in.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestServerInfo a serverInfo without a listener, for the observer's
+// handlers to be called on directly.
+func newTestServerInfo(cfg serverConfig) serverInfo {
+	srvInfo := serverInfo{totalConnections: new(int), availableServers: new(int),
+		workersSpawned: new(int), activeWorkers: new(int), pendingWorkers: new(int),
+		lastSpawn: new(time.Time), retireQuota: new(int64), bytesTransferred: new(int64), acceptErrors: new(int64),
+		nonces: new(int64), rng: newLockedRand(1), config: &cfg,
+		retire: make(chan bool, cfg.workerCap), connections: newConnRegistry(),
+		retireIdle: make(chan struct{}), handedOff: make(chan acceptResult),
+		serverConnection: make(chan int), connectInfo: make(chan connectionInfo),
+		statsRequests: make(chan chan statsSnapshot), statsResets: make(chan chan bool),
+		shutdown: make(chan error, 1), draining: new(int32), acceptClosing: new(int32),
+		clock:  newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)),
+		logger: newLogger(io.Discard, logLevelError, logFormatText)}
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	srvInfo.cancel()
+
+	return srvInfo
+}
+
+// serveTestConnection serves the server's end of a net.Pipe as worker 1 would,
+// returning the client's end and where the connection's information is sent
+// once it has been served.
+func serveTestConnection(t *testing.T, srvInfo serverInfo) (net.Conn, <-chan connectionInfo) {
+	server, client := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	served := make(chan connectionInfo, 1)
+	go func() {
+		defer server.Close()
+		served <- connectionInstance(context.Background(), server, srvInfo, 1)
+	}()
+
+	return client, served
+}
+
+// testChildReport the report file a child test process runs the server with,
+// empty in the parent test process.
+func testChildReport() string {
+	return os.Getenv("TEST_CHILD_REPORT")
+}
+
+// runTestChild runs test in a child test process, returning what it wrote to
+// stdout, its exit status and the report file it was given in the test's
+// temporary directory.
+func runTestChild(t *testing.T, test string) (string, int, string) {
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	child := exec.Command(os.Args[0], "-test.run=^"+test+"$")
+	child.Env = append(os.Environ(), "TEST_CHILD_REPORT="+reportFile)
+	output, err := child.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal("Unable to run the child test process:", err)
+	}
+
+	return string(output), child.ProcessState.ExitCode(), reportFile
+}
+
+// TestLazyPoolFloorSpikeFloor checks that the lazy pool idles at -worker-floor,
+// grows no further than -worker-cap under a spike and shrinks back to the floor
+// once the spike is over.
+func TestLazyPoolFloorSpikeFloor(t *testing.T) {
+	const floor, capacity = 2, 5
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP,
+		workerFloor: floor, workerCap: capacity})
+	for i := 0; i < floor; i++ {
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+	}
+	if *srvInfo.activeWorkers != floor || *srvInfo.availableServers != floor {
+		t.Fatalf("idle pool has %d workers, %d available, want %d", *srvInfo.activeWorkers, *srvInfo.availableServers, floor)
+	}
+
+	// every worker is given a connection, the last ones arrive once the pool
+	// is at the cap and can't keep the floor waiting on Accept
+	for i := 0; i < capacity; i++ {
+		newConnection(srvInfo)
+		if *srvInfo.activeWorkers > capacity {
+			t.Fatalf("pool grew to %d workers, past the cap of %d", *srvInfo.activeWorkers, capacity)
+		}
+	}
+	if *srvInfo.activeWorkers != capacity {
+		t.Fatalf("pool has %d workers during the spike, want the cap of %d", *srvInfo.activeWorkers, capacity)
+	}
+	if *srvInfo.availableServers != 0 {
+		t.Fatalf("%d workers waiting on Accept at the cap, want 0", *srvInfo.availableServers)
+	}
+
+	retired := 0
+	for i := 0; i < capacity; i++ {
+		finishedConnection(srvInfo)
+		if <-srvInfo.retire {
+			retired++
+		}
+	}
+	if *srvInfo.activeWorkers != floor || *srvInfo.availableServers != floor {
+		t.Errorf("pool has %d workers, %d available after the spike, want %d", *srvInfo.activeWorkers, *srvInfo.availableServers, floor)
+	}
+	if retired != capacity-floor {
+		t.Errorf("%d workers retired, want %d", retired, capacity-floor)
+	}
+}
+
+// goroutineID the ID of the calling go routine, read from its stack trace.
+func goroutineID() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	return strings.Fields(string(buf))[1]
+}
+
+// TestRequestsAttributedToOneWorker checks that every request on a keep-alive
+// connection is handled on the go routine of the worker that accepted it, and
+// is attributed to that worker. Only Accept runs on a go routine of its own.
+func TestRequestsAttributedToOneWorker(t *testing.T) {
+	const workerID = 3
+	handlers := make(chan string, 3)
+	record := func(body []byte) ([]byte, error) {
+		handlers <- goroutineID()
+		return body, nil
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', pipeline: pipeline{record}})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	srvInfo.serverConnection = make(chan int, 1)
+	srvInfo.connectInfo = make(chan connectionInfo, 1)
+	listener := pipeListener{conns: make(chan net.Conn, 1)}
+	defer close(listener.conns)
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	server, client := net.Pipe()
+	listener.conns <- server
+	routine := make(chan string, 1)
+	go func() {
+		routine <- goroutineID()
+		worker(srvInfo.ctx, srvInfo, workerID)
+	}()
+
+	reader := bufio.NewReader(client)
+	for _, request := range []string{"one\n", "two\n", "three\n"} {
+		go io.WriteString(client, request)
+		if echo, err := reader.ReadString('\n'); err != nil || echo != request {
+			t.Fatalf("echo of %q was %q, %v", request, echo, err)
+		}
+	}
+	client.Close()
+
+	connInfo := <-srvInfo.connectInfo
+	workerRoutine := <-routine
+	for i := 1; i <= 3; i++ {
+		if handler := <-handlers; handler != workerRoutine {
+			t.Errorf("request %d was handled on go routine %s, want the worker's %s", i, handler, workerRoutine)
+		}
+	}
+	if connInfo.NumberOfRequests != 3 || connInfo.WorkerID != workerID {
+		t.Errorf("%d requests attributed to worker %d, want 3 to worker %d",
+			connInfo.NumberOfRequests, connInfo.WorkerID, workerID)
+	}
+}
+
+// TestListenerClosedDrains checks that the server keeps serving the open
+// connections when the listener is closed under it, exiting with 0 once they
+// have finished.
+func TestListenerClosedDrains(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{shutdownTimeout: time.Minute,
+			reportFile: reportFile, reportFormat: "json"})
+		server, client := net.Pipe()
+		defer client.Close()
+		registered := srvInfo.connections.add(server)
+		go observerLoop(srvInfo, nil)
+		srvInfo.serverConnection <- newConnectionConst
+
+		requestShutdown(srvInfo, nil)
+		readStats(srvInfo) // the observer is still running after the shutdown
+		os.Stdout.WriteString("draining\n")
+		srvInfo.connections.remove(registered)
+		srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000"}
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the drain didn't finish
+	}
+
+	output, status, reportFile := runTestChild(t, "TestListenerClosedDrains")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0", status)
+	}
+	if !strings.Contains(output, "draining") {
+		t.Errorf("the server exited without draining, output %q", output)
+	}
+	if _, err := os.Stat(reportFile); err != nil {
+		t.Error("the report wasn't written:", err)
+	}
+}
+
+// TestDrainWaitsForInFlightClose checks that a drain isn't over while the
+// record of a connection that has left the registry is still on its way to
+// the observer, and that the connection makes it into the report.
+func TestDrainWaitsForInFlightClose(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{shutdownTimeout: time.Minute,
+			reportFile: reportFile, reportFormat: "json"})
+		server, client := net.Pipe()
+		defer client.Close()
+		registered := srvInfo.connections.add(server)
+		go observerLoop(srvInfo, nil)
+		srvInfo.serverConnection <- newConnectionConst
+
+		requestShutdown(srvInfo, nil)
+		srvInfo.connections.remove(registered)
+		readStats(srvInfo) // the observer handles another event before the record
+		srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000"}
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the drain didn't finish
+	}
+
+	_, status, reportFile := runTestChild(t, "TestDrainWaitsForInFlightClose")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0", status)
+	}
+	if connections := readJSONReport(t, reportFile); len(connections) != 1 || connections[0].HostName != "192.0.2.1:40000" {
+		t.Errorf("the report has %d connections, want the one closed during the drain", len(connections))
+	}
+}
+
+// TestMaxAcceptErrors checks that the server keeps accepting through
+// -max-accept-errors errors and exits with 1 at the next one.
+func TestMaxAcceptErrors(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{maxAcceptErrors: 3, reportFile: reportFile, reportFormat: "json"})
+		srvInfo.clock = realClock{} // only sleeps the few milliseconds of the backoff
+		for i := 0; i < 3; i++ {
+			if _, accepting := acceptFailed(srvInfo, syscall.EMFILE, 0); !accepting {
+				os.Exit(3)
+			}
+		}
+		if len(srvInfo.shutdown) == 0 {
+			os.Stdout.WriteString("accepting\n")
+		}
+		go observerLoop(srvInfo, nil)
+		acceptFailed(srvInfo, syscall.EMFILE, 0)
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the server wasn't shut down
+	}
+
+	output, status, reportFile := runTestChild(t, "TestMaxAcceptErrors")
+	if status != 1 {
+		t.Errorf("the server exited with %d, want 1", status)
+	}
+	if !strings.Contains(output, "accepting") {
+		t.Errorf("the server shut down under -max-accept-errors, output %q", output)
+	}
+	if _, err := os.Stat(reportFile); err != nil {
+		t.Error("the report wasn't written:", err)
+	}
+}
+
+// TestCoalescedInOrder checks that requests arriving together inside the
+// -coalesce-window are all echoed, in order, in a single write.
+func TestCoalescedInOrder(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		coalesceWindow: 20 * time.Millisecond})
+	client, served := serveTestConnection(t, srvInfo)
+	io.WriteString(client, "one\ntwo\nthree\n")
+
+	echo := make([]byte, 64)
+	n, err := client.Read(echo)
+	if err != nil || string(echo[:n]) != "one\ntwo\nthree\n" {
+		t.Errorf("the first write was %q, %v, want every echo in order", echo[:n], err)
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.NumberOfRequests != 3 || connInfo.CoalescedRequests != 2 {
+		t.Errorf("%d requests with %d coalesced, want 3 with 2", connInfo.NumberOfRequests, connInfo.CoalescedRequests)
+	}
+}
+
+// TestLocalAddrRecorded checks that a connection accepted on a wildcard
+// listener records the address the client actually connected to.
+func TestLocalAddrRecorded(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	client, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal("accept:", err)
+	}
+	defer server.Close()
+	client.Close()
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	connInfo := connectionInstance(context.Background(), server, srvInfo, 1)
+	if want := net.JoinHostPort("127.0.0.1", strconv.Itoa(port)); connInfo.LocalAddr != want {
+		t.Errorf("the local address was %q, want %q", connInfo.LocalAddr, want)
+	}
+}
+
+// TestWorkerSpawnRate checks that a storm of requests for workers is spawned
+// no faster than -worker-spawn-rate, and that every worker is spawned in the
+// end.
+func TestWorkerSpawnRate(t *testing.T) {
+	const storm, rate = 50, 10
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP,
+		workerCap: storm, workerSpawnRate: rate})
+	clock := srvInfo.clock.(*fakeClock)
+	start := clock.Now()
+	for i := 0; i < storm; i++ {
+		requestWorker(srvInfo)
+	}
+	if *srvInfo.workersSpawned != 1 {
+		t.Fatalf("%d workers spawned by the storm, want 1", *srvInfo.workersSpawned)
+	}
+
+	for *srvInfo.workersSpawned < storm {
+		clock.Advance(30 * time.Millisecond)
+		spawnPendingWorkers(srvInfo, clock.Now())
+		elapsed := clock.Now().Sub(start)
+		if allowed := 1 + int(elapsed.Seconds()*rate); *srvInfo.workersSpawned > allowed {
+			t.Fatalf("%d workers spawned after %s, want at most %d", *srvInfo.workersSpawned, elapsed, allowed)
+		}
+		if elapsed > 10*time.Second {
+			t.Fatalf("only %d of %d workers spawned after %s", *srvInfo.workersSpawned, storm, elapsed)
+		}
+	}
+	if *srvInfo.pendingWorkers != 0 || *srvInfo.availableServers != storm {
+		t.Errorf("%d workers pending and %d available, want 0 and %d", *srvInfo.pendingWorkers, *srvInfo.availableServers, storm)
+	}
+}
+
+// TestSecondSignalEscalates checks that a signal received while a drain is
+// stuck closes the open connections and exits straight away, without waiting
+// on -shutdown-timeout.
+func TestSecondSignalEscalates(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+			shutdownTimeout: time.Hour, reportFile: reportFile, reportFormat: "json"})
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("listen:", err)
+		}
+		srvInfo.listener = listener
+		server, client := net.Pipe()
+		defer client.Close()
+		registered := srvInfo.connections.add(server)
+		go func() {
+			connInfo := connectionInstance(context.Background(), registered, srvInfo, 1)
+			srvInfo.connectInfo <- connInfo
+		}()
+		osSignals := make(chan os.Signal)
+		go observerLoop(srvInfo, osSignals)
+		srvInfo.serverConnection <- newConnectionConst
+
+		osSignals <- syscall.SIGINT
+		osSignals <- syscall.SIGINT // the drain is stuck on the open connection
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the second signal didn't exit
+	}
+
+	_, status, reportFile := runTestChild(t, "TestSecondSignalEscalates")
+	if status != 1 {
+		t.Fatalf("the server exited with %d, want 1", status)
+	}
+	report, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal("the report wasn't written:", err)
+	}
+	if !strings.Contains(string(report), `"CloseReason": "`+closeReasonShutdown+`"`) {
+		t.Errorf("the open connection wasn't reported as closed by the shutdown, report %s", report)
+	}
+}
+
+// TestMaxBytesPerConn checks that the connection is closed once the requests
+// it has sent reach -max-bytes-per-conn.
+func TestMaxBytesPerConn(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', maxBytesPerConn: 10})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	for i := 0; i < 2; i++ {
+		io.WriteString(client, "hello\n")
+		if echo, err := reader.ReadString('\n'); err != nil || echo != "hello\n" {
+			t.Fatalf("echo %d was %q, %v", i+1, echo, err)
+		}
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("read past the cap returned %v, want the connection closed", err)
+	}
+
+	connInfo := <-served
+	if connInfo.CloseReason != closeReasonByteCap || connInfo.BytesReceived != 12 {
+		t.Errorf("closed with %q after %d bytes, want %q after 12", connInfo.CloseReason, connInfo.BytesReceived, closeReasonByteCap)
+	}
+}
+
+// TestMaxInflightBackpressure checks that requests pipelined past
+// -max-inflight are echoed in order a few at a time, with each pause counted.
+func TestMaxInflightBackpressure(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		coalesceWindow: 20 * time.Millisecond, maxInflight: 2})
+	client, served := serveTestConnection(t, srvInfo)
+	go io.WriteString(client, "one\ntwo\nthree\nfour\nfive\n")
+
+	var writes []string
+	var echo string
+	buffer := make([]byte, 64)
+	for echo != "one\ntwo\nthree\nfour\nfive\n" {
+		n, err := client.Read(buffer)
+		if err != nil {
+			t.Fatalf("read failed after %q: %v", echo, err)
+		}
+		writes = append(writes, string(buffer[:n]))
+		echo += string(buffer[:n])
+	}
+	client.Close()
+
+	if strings.Join(writes, "|") != "one\ntwo\n|three\nfour\n|five\n" {
+		t.Errorf("the echoes were written as %q, want two at a time", writes)
+	}
+	if connInfo := <-served; connInfo.BackpressureEvents != 2 {
+		t.Errorf("%d backpressure events, want 2", connInfo.BackpressureEvents)
+	}
+}
+
+// deniedListener a listener that always has a connection waiting from a
+// client -deny-cidr turns away, keeping a worker busy accepting.
+type deniedListener struct {
+	accepts *int64
+}
+
+// Accept a connection from 192.0.2.1.
+func (listener deniedListener) Accept() (net.Conn, error) {
+	atomic.AddInt64(listener.accepts, 1)
+
+	return closedConn{clientConn("192.0.2.1", 40000)}, nil
+}
+
+// Close does nothing.
+func (deniedListener) Close() error {
+	return nil
+}
+
+// Addr the address being listened on.
+func (deniedListener) Addr() net.Addr {
+	return tcpAddr("127.0.0.1", 7000)
+}
+
+// closedConn a connection that is already closed.
+type closedConn struct {
+	net.Conn
+}
+
+// Close does nothing.
+func (closedConn) Close() error {
+	return nil
+}
+
+// BenchmarkAcceptYield measures how late a timer the observer waits on is
+// picked up while a worker on the same processor accepts as fast as it can,
+// and how many accepts the worker makes, with and without -accept-yield.
+func BenchmarkAcceptYield(b *testing.B) {
+	const wait = 100 * time.Microsecond
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	var deny cidrList
+	deny.Set("192.0.2.0/24")
+	for _, yield := range []int{0, 1, 64} {
+		b.Run(fmt.Sprintf("yield=%d", yield), func(b *testing.B) {
+			listener := deniedListener{accepts: new(int64)}
+			srvInfo := newTestServerInfo(serverConfig{acceptYield: yield})
+			srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+			srvInfo.ipFilter = newIPFilter(nil, deny)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go worker(ctx, srvInfo, 1)
+
+			var late time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				<-time.After(wait)
+				late += time.Since(start) - wait
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(late.Nanoseconds())/float64(b.N), "late-ns/op")
+			b.ReportMetric(float64(atomic.LoadInt64(listener.accepts))/float64(b.N), "accepts/op")
+		})
+	}
+}
+
+// TestAcceptDrainServesBacklog checks that a client connecting after the
+// shutdown signal is still served during -accept-drain, and that one
+// connecting once it has ended is sent -close-message and closed.
+func TestAcceptDrainServesBacklog(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+			delimiter: '\n', acceptDrain: time.Minute, shutdownTimeout: time.Minute, closeMessage: "closing",
+			reportFile: reportFile, reportFormat: "json"})
+		srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("listen:", err)
+		}
+		srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+		clock := srvInfo.clock.(*fakeClock)
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+		osSignals := make(chan os.Signal)
+		go observerLoop(srvInfo, osSignals)
+		osSignals <- syscall.SIGINT
+		readStats(srvInfo) // the accept drain has started
+
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		io.WriteString(client, "hello\n")
+		echo, _ := bufio.NewReader(client).ReadString('\n')
+		client.Close()
+		os.Stdout.WriteString("served " + echo)
+
+		clock.Advance(time.Minute)
+		readStats(srvInfo) // the accept drain has ended
+		client, err = net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		message, _ := bufio.NewReader(client).ReadString('\n')
+		client.Close()
+		os.Stdout.WriteString("turned away " + message)
+
+		clock.Advance(acceptDrainFlush)
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the drain didn't finish
+	}
+
+	output, status, reportFile := runTestChild(t, "TestAcceptDrainServesBacklog")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0, output %q", status, output)
+	}
+	if !strings.Contains(output, "served hello\n") || !strings.Contains(output, "turned away closing\n") {
+		t.Errorf("output %q, want the first client served and the second sent -close-message", output)
+	}
+	report, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal("the report wasn't written:", err)
+	}
+	if !strings.Contains(string(report), `"CloseReason": "`+closeReasonAcceptDrain+`"`) {
+		t.Errorf("the second client wasn't reported as turned away by the accept drain, report %s", report)
+	}
+}
+
+// TestConcurrentConnectionsCounted checks that every one of many clients
+// connecting at once is counted, with the workers and the observer running in
+// their own go routines. Run with -race to check the pool's counters are only
+// touched by the observer.
+func TestConcurrentConnectionsCounted(t *testing.T) {
+	const clients = 100
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+		delimiter: '\n', freeServerMinimum: 2})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	for i := 0; i < 4; i++ {
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+	}
+	go observerLoop(srvInfo, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Error("dial:", err)
+				return
+			}
+			defer client.Close()
+			client.SetDeadline(time.Now().Add(5 * time.Second))
+			io.WriteString(client, "hello\n")
+			if echo, err := bufio.NewReader(client).ReadString('\n'); echo != "hello\n" {
+				t.Errorf("the echo was %q, %v", echo, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := readStats(srvInfo)
+	for deadline := time.Now().Add(5 * time.Second); snapshot.TotalConnections < clients && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		snapshot = readStats(srvInfo)
+	}
+	if snapshot.TotalConnections != clients || snapshot.TotalRequests != clients {
+		t.Errorf("%d connections and %d requests recorded, want %d of each",
+			snapshot.TotalConnections, snapshot.TotalRequests, clients)
+	}
+	// readStats is answered by the observer, so its counters can be read now
+	if *srvInfo.totalConnections != clients {
+		t.Errorf("totalConnections is %d, want %d", *srvInfo.totalConnections, clients)
+	}
+}
+
+// TestSequentialConnectionsReuseWorkers checks that workers are available
+// again once they have finished a connection, so 1000 connections one after
+// the other are served without the pool, or the go routines, growing.
+func TestSequentialConnectionsReuseWorkers(t *testing.T) {
+	const workers = 10
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+		delimiter: '\n', freeServerMinimum: 5})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	for i := 0; i < workers; i++ {
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+	}
+	go observerLoop(srvInfo, nil)
+
+	goroutines := 0
+	for i := 1; i <= 1000; i++ {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		io.WriteString(client, "hello\n")
+		client.(*net.TCPConn).CloseWrite()
+		if echo, err := io.ReadAll(client); string(echo) != "hello\n" {
+			t.Fatalf("the echo of connection %d was %q, %v", i, echo, err)
+		}
+		client.Close()
+		for readStats(srvInfo).TotalConnections < i {
+			runtime.Gosched()
+		}
+		if i == 100 {
+			goroutines = runtime.NumGoroutine()
+		}
+	}
+
+	// readStats is answered by the observer, so its counters can be read now
+	if *srvInfo.workersSpawned != workers || *srvInfo.availableServers != workers {
+		t.Errorf("%d workers spawned and %d available, want %d of each",
+			*srvInfo.workersSpawned, *srvInfo.availableServers, workers)
+	}
+	if grown := runtime.NumGoroutine() - goroutines; grown > workers {
+		t.Errorf("the go routines grew by %d between the 100th and the 1000th connection", grown)
+	}
+}
+
+// TestSignalFinishesInFlightRequest checks that a request half sent when
+// SIGTERM arrives is still echoed, and that the server then exits with 0.
+func TestSignalFinishesInFlightRequest(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine,
+			delimiter: '\n', shutdownTimeout: time.Minute, reportFile: reportFile, reportFormat: "json"})
+		srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("listen:", err)
+		}
+		srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+		*srvInfo.availableServers++
+		spawnWorker(srvInfo)
+		osSignals := make(chan os.Signal)
+		go observerLoop(srvInfo, osSignals)
+
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		io.WriteString(client, "hel")
+		for readStats(srvInfo).CurrentConnections == 0 {
+			runtime.Gosched()
+		}
+		osSignals <- syscall.SIGTERM
+		readStats(srvInfo) // the shutdown has started
+		io.WriteString(client, "lo\n")
+		echo, _ := bufio.NewReader(client).ReadString('\n')
+		os.Stdout.WriteString("echoed " + echo)
+		client.Close()
+		time.Sleep(10 * time.Second)
+		os.Exit(3) // the drain didn't finish
+	}
+
+	output, status, reportFile := runTestChild(t, "TestSignalFinishesInFlightRequest")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0, output %q", status, output)
+	}
+	if !strings.Contains(output, "echoed hello\n") {
+		t.Errorf("the request in flight wasn't echoed, output %q", output)
+	}
+	if _, err := os.Stat(reportFile); err != nil {
+		t.Error("the report wasn't written:", err)
+	}
+}
+
+// TestHoldOpenAfterHalfClose checks that the server keeps its side open for
+// -hold-open after the client half-closes, timed on the server clock.
+func TestHoldOpenAfterHalfClose(t *testing.T) {
+	const hold = time.Second
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal("accept:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', holdOpen: hold})
+	clock := srvInfo.clock.(*fakeClock)
+	served := make(chan connectionInfo, 1)
+	go func() {
+		defer server.Close()
+		served <- connectionInstance(context.Background(), server, srvInfo, 1)
+	}()
+
+	io.WriteString(client, "hello\n")
+	client.(*net.TCPConn).CloseWrite()
+	waitForWaiters(clock, 1) // the server has seen the half-close
+	clock.Advance(hold - time.Millisecond)
+	select {
+	case <-served:
+		t.Fatal("the connection was closed before -hold-open had passed")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if echo, err := io.ReadAll(client); err != nil || string(echo) != "hello\n" {
+		t.Errorf("the client read %q, %v before the close, want the echo", echo, err)
+	}
+	if connInfo := <-served; connInfo.HeldOpen != hold || connInfo.Duration < hold {
+		t.Errorf("held open %s of a %s connection, want %s", connInfo.HeldOpen, connInfo.Duration, hold)
+	}
+}
+
+// TestObserverLagGuard checks that connections the observer gets to after
+// -max-observer-lag are only counted, that the trip is logged once, and that
+// their workers are still made available again.
+func TestObserverLagGuard(t *testing.T) {
+	var logged strings.Builder
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, maxObserverLag: time.Second})
+	srvInfo.logger = newLogger(&logged, logLevelWarn, logFormatText)
+	clock := srvInfo.clock.(*fakeClock)
+	stats := newServerStats("")
+	closed := func(connections int) []connectionInfo {
+		batch := make([]connectionInfo, connections)
+		for i := range batch {
+			stats.connectionOpened()
+			batch[i] = connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 1, EndTime: clock.Now()}
+		}
+		return batch
+	}
+
+	connectionsClosed(srvInfo, stats, clock.Now(), closed(2)...)
+	behind, handed := closed(3), clock.Now()
+	clock.Advance(2 * time.Second) // the observer is throttled
+	connectionsClosed(srvInfo, stats, handed, behind...)
+	connectionsClosed(srvInfo, stats, clock.Now(), closed(1)...)
+
+	summary := stats.summary()
+	if summary.TotalConnections != 6 || summary.DroppedConnections != 3 || stats.connectionsMade.Len() != 3 {
+		t.Errorf("%d connections counted, %d dropped and %d listed, want 6, 3 and 3",
+			summary.TotalConnections, summary.DroppedConnections, stats.connectionsMade.Len())
+	}
+	if summary.MaxObserverLag != 2*time.Second {
+		t.Errorf("the longest lag was %s, want 2s", summary.MaxObserverLag)
+	}
+	if trips := strings.Count(logged.String(), "Observer is behind"); trips != 1 {
+		t.Errorf("the guard tripping was logged %d times, want once:\n%s", trips, logged.String())
+	}
+	if *srvInfo.availableServers != 6 {
+		t.Errorf("%d workers available again, want all 6", *srvInfo.availableServers)
+	}
+}
+
+// TestSummaryFD checks that the server writes a one line JSON summary to the
+// -summary-fd pipe it inherited when it exits.
+func TestSummaryFD(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{reportFile: reportFile, reportFormat: "json"})
+		srvInfo.summaryFile = openSummaryFD(3)
+		stats := newServerStats("")
+		stats.connectionOpened()
+		stats.connectionClosed(connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 2, AmmountOfData: 12})
+		exitServer(srvInfo, stats, 0)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal("pipe:", err)
+	}
+	defer reader.Close()
+	child := exec.Command(os.Args[0], "-test.run=^TestSummaryFD$")
+	child.Env = append(os.Environ(), "TEST_CHILD_REPORT="+filepath.Join(t.TempDir(), "report.json"))
+	child.ExtraFiles = []*os.File{writer} // the child's fd 3
+	if err := child.Start(); err != nil {
+		t.Fatal("Unable to run the child test process:", err)
+	}
+	writer.Close()
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	child.Wait()
+	if err != nil {
+		t.Fatalf("no summary line was written, read %q: %v", line, err)
+	}
+
+	var exitSummary struct {
+		ExitCode int
+		Summary  reportSummary
+	}
+	if err := json.Unmarshal([]byte(line), &exitSummary); err != nil {
+		t.Fatalf("the summary %q wasn't valid JSON: %v", line, err)
+	}
+	if exitSummary.ExitCode != child.ProcessState.ExitCode() || exitSummary.Summary.TotalConnections != 1 ||
+		exitSummary.Summary.TotalRequests != 2 || exitSummary.Summary.TotalData != 12 {
+		t.Errorf("the summary had exit status %d, %d connections, %d requests and %d bytes, want %d, 1, 2 and 12",
+			exitSummary.ExitCode, exitSummary.Summary.TotalConnections, exitSummary.Summary.TotalRequests,
+			exitSummary.Summary.TotalData, child.ProcessState.ExitCode())
+	}
+}
+
+// pipeListener a listener accepting the server ends of net.Pipes.
+type pipeListener struct {
+	conns chan net.Conn
+}
+
+// Accept the next connection sent on conns, net.ErrClosed once it is closed.
+func (listener pipeListener) Accept() (net.Conn, error) {
+	conn, ok := <-listener.conns
+	if !ok {
+		return nil, net.ErrClosed
+	}
+
+	return conn, nil
+}
+
+// Close does nothing.
+func (pipeListener) Close() error {
+	return nil
+}
+
+// Addr the address being listened on.
+func (pipeListener) Addr() net.Addr {
+	return tcpAddr("127.0.0.1", 7000)
+}
+
+// TestCancelStopsWorkers checks that cancelling the server's context aborts
+// the reads of workers waiting for their clients' next requests, without the
+// connections or the listener being closed, and that the workers return.
+func TestCancelStopsWorkers(t *testing.T) {
+	const workers = 3
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	srvInfo.serverConnection = make(chan int, workers)
+	srvInfo.connectInfo = make(chan connectionInfo, workers)
+	listener := pipeListener{conns: make(chan net.Conn, workers)}
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+
+	var returned sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		server, client := net.Pipe()
+		defer client.Close()
+		listener.conns <- server
+		returned.Add(1)
+		go func(workerID int) {
+			defer returned.Done()
+			worker(srvInfo.ctx, srvInfo, workerID)
+		}(i)
+		go io.WriteString(client, "hello\n")
+		if echo, err := bufio.NewReader(client).ReadString('\n'); echo != "hello\n" {
+			t.Fatalf("the echo was %q, %v, want %q", echo, err, "hello\n")
+		}
+	}
+
+	srvInfo.cancel()
+	done := make(chan bool)
+	go func() {
+		returned.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the workers didn't return once the context was cancelled")
+	}
+	for i := 0; i < workers; i++ {
+		if connInfo := <-srvInfo.connectInfo; connInfo.CloseReason != closeReasonShutdown {
+			t.Errorf("a connection closed as %q, want %q", connInfo.CloseReason, closeReasonShutdown)
+		}
+	}
+}
+
+// TestClosedListenerStopsWorkers checks that closing the listener returns the
+// workers accepting from it, rather than leaving them spinning, and asks for
+// a clean shutdown without logging an accept error.
+func TestClosedListenerStopsWorkers(t *testing.T) {
+	const workers = 3
+	srvInfo := newTestServerInfo(serverConfig{})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	var logged strings.Builder
+	srvInfo.logger = newLogger(&logged, logLevelWarn, logFormatText)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+
+	var returned sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		returned.Add(1)
+		go func(workerID int) {
+			defer returned.Done()
+			worker(srvInfo.ctx, srvInfo, workerID)
+		}(i)
+	}
+	listener.Close()
+	done := make(chan bool)
+	go func() {
+		returned.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the workers didn't return once the listener was closed")
+	}
+
+	if reason := <-srvInfo.shutdown; reason != nil {
+		t.Errorf("the shutdown was asked for with %v, want nil", reason)
+	}
+	if failures := atomic.LoadInt64(srvInfo.acceptErrors); failures != 0 || logged.Len() != 0 {
+		t.Errorf("%d accept errors counted and %q logged, want none", failures, logged.String())
+	}
+}
+
+// TestAcceptFailedBackoff checks that a temporary accept error backs off on
+// the server clock, doubling up to maxAcceptBackoff, and that any other error
+// stops the worker and asks for a shutdown with it.
+func TestAcceptFailedBackoff(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{})
+	clock := srvInfo.clock.(*fakeClock)
+	type result struct {
+		backoff time.Duration
+		retry   bool
+	}
+	results := make(chan result)
+	go func() {
+		backoff, retry := acceptFailed(srvInfo, syscall.EMFILE, 0)
+		results <- result{backoff, retry}
+	}()
+	waitForWaiters(clock, 1)
+	clock.Advance(minAcceptBackoff)
+	if failed := <-results; failed.backoff != minAcceptBackoff || !failed.retry {
+		t.Errorf("acceptFailed = %s, %v, want %s and a retry", failed.backoff, failed.retry, minAcceptBackoff)
+	}
+
+	var backoffs []time.Duration
+	for backoff := time.Duration(0); backoff < maxAcceptBackoff; {
+		backoff = acceptBackoff(backoff)
+		backoffs = append(backoffs, backoff)
+	}
+	if len(backoffs) != 9 || backoffs[1] != 2*minAcceptBackoff || backoffs[8] != maxAcceptBackoff {
+		t.Errorf("the backoffs were %v, want doubling from %s to %s", backoffs, minAcceptBackoff, maxAcceptBackoff)
+	}
+
+	broken := errors.New("broken listener")
+	if _, retry := acceptFailed(srvInfo, broken, 0); retry {
+		t.Error("the worker retried after a permanent error")
+	}
+	if reason := <-srvInfo.shutdown; reason != broken {
+		t.Errorf("the shutdown was asked for with %v, want %v", reason, broken)
+	}
+}
+
+// TestPanicRecovered checks that a request that panics tears down only its
+// own connection, which is logged and still sent to the observer, and that
+// the worker goes on to serve the next connection.
+func TestPanicRecovered(t *testing.T) {
+	boom := func(body []byte) ([]byte, error) {
+		if string(body) == "boom" {
+			panic("boom")
+		}
+		return body, nil
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', pipeline: pipeline{boom}})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	var logged strings.Builder
+	srvInfo.logger = newLogger(&logged, logLevelError, logFormatText)
+	srvInfo.serverConnection = make(chan int, 2)
+	srvInfo.connectInfo = make(chan connectionInfo, 2)
+	listener := pipeListener{conns: make(chan net.Conn, 2)}
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	returned := make(chan bool)
+	go func() {
+		worker(srvInfo.ctx, srvInfo, 1)
+		close(returned)
+	}()
+
+	for _, test := range []struct{ request, want, reason string }{
+		{"boom\n", "", closeReasonPanic}, {"hello\n", "hello\n", ""}} {
+		server, client := net.Pipe()
+		defer client.Close()
+		listener.conns <- server
+		go io.WriteString(client, test.request)
+		if echo, _ := bufio.NewReader(client).ReadString('\n'); echo != test.want {
+			t.Errorf("%q was echoed as %q, want %q", test.request, echo, test.want)
+		}
+		client.Close()
+		if connInfo := <-srvInfo.connectInfo; connInfo.CloseReason != test.reason {
+			t.Errorf("the connection sending %q closed as %q, want %q", test.request, connInfo.CloseReason, test.reason)
+		}
+	}
+	close(listener.conns)
+	<-returned
+
+	if !strings.Contains(logged.String(), "Recovered from a panic") || !strings.Contains(logged.String(), "goroutine") {
+		t.Errorf("the panic wasn't logged with its stack, logged %q", logged.String())
+	}
+}
+
+// TestConnectionDuration checks that a connection's start, end and duration
+// are stamped from the server clock when it starts being served and when it
+// closes.
+func TestConnectionDuration(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	clock := srvInfo.clock.(*fakeClock)
+	start := clock.Now()
+	client, served := serveTestConnection(t, srvInfo)
+	go io.WriteString(client, "hello\n")
+	bufio.NewReader(client).ReadString('\n') // the connection is being served
+	clock.Advance(3 * time.Second)
+	client.Close()
+
+	if connInfo := <-served; !connInfo.StartTime.Equal(start) || !connInfo.EndTime.Equal(start.Add(3*time.Second)) ||
+		connInfo.Duration != 3*time.Second {
+		t.Errorf("the connection ran from %s to %s for %s, want 3s from %s",
+			connInfo.StartTime, connInfo.EndTime, connInfo.Duration, start)
+	}
+}
@@ -0,0 +1,207 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 metrics.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newMetricsServer(address string) *metricsServer
+--  func (metrics *metricsServer) connectionOpened()
+--  func (metrics *metricsServer) connectionClosed(connInfo connectionInfo)
+--  func (metrics *metricsServer) setCurrent(current int)
+--  func (metrics *metricsServer) shutdown()
+--
+-- NOTES: This file serves Prometheus metrics on /metrics at -metrics-addr so
+--        a run can be watched while it happens rather than only through the
+--        report on exit. The metrics are only updated by the observer, from
+--        the same events it keeps its statistics from, so requests and bytes
+--        are counted when their connection closes.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsShutdownTimeout how long scrapes in progress have to finish when the server exits.
+const metricsShutdownTimeout = time.Second
+
+// metricsServer the Prometheus endpoint and the metrics it serves.
+type metricsServer struct {
+	server      *http.Server
+	connections prometheus.Counter
+	current     prometheus.Gauge
+	bytesEchoed prometheus.Counter
+	requests    prometheus.Counter
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newMetricsServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newMetricsServer(address string) *metricsServer
+--   address:		the address to serve /metrics on.
+--
+-- RETURNS:     *metricsServer a running server, nil if address is empty.
+--
+-- NOTES:			The metrics are kept in their own registry so only the
+--						server's metrics are served. Exits the program if the
+--						address can't be listened on.
+------------------------------------------------------------------------------*/
+func newMetricsServer(address string) *metricsServer {
+	if address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatalln("Unable to listen for metrics scrapes:", err)
+	}
+
+	metrics := &metricsServer{
+		connections: prometheus.NewCounter(prometheus.CounterOpts{Name: "scalableserver_connections_total",
+			Help: "Connections accepted."}),
+		current: prometheus.NewGauge(prometheus.GaugeOpts{Name: "scalableserver_connections_current",
+			Help: "Connections being served."}),
+		bytesEchoed: prometheus.NewCounter(prometheus.CounterOpts{Name: "scalableserver_bytes_echoed_total",
+			Help: "Bytes written to connections that have closed."}),
+		requests: prometheus.NewCounter(prometheus.CounterOpts{Name: "scalableserver_requests_total",
+			Help: "Requests on connections that have closed."}),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.connections, metrics.current, metrics.bytesEchoed, metrics.requests)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	metrics.server = &http.Server{Handler: mux}
+	go metrics.server.Serve(listener)
+
+	return metrics
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionOpened
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (metrics *metricsServer) connectionOpened()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil server. Called by the observer.
+------------------------------------------------------------------------------*/
+func (metrics *metricsServer) connectionOpened() {
+	if metrics == nil {
+		return
+	}
+
+	metrics.connections.Inc()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (metrics *metricsServer) connectionClosed(connInfo connectionInfo)
+--  connInfo:		the connection that closed.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil server. Called by the observer.
+------------------------------------------------------------------------------*/
+func (metrics *metricsServer) connectionClosed(connInfo connectionInfo) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.bytesEchoed.Add(float64(connInfo.BytesSent))
+	metrics.requests.Add(float64(connInfo.NumberOfRequests))
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    setCurrent
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (metrics *metricsServer) setCurrent(current int)
+--   current:		the connections being served.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil server. Called by the observer.
+------------------------------------------------------------------------------*/
+func (metrics *metricsServer) setCurrent(current int) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.current.Set(float64(current))
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    shutdown
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (metrics *metricsServer) shutdown()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil server. Stops accepting scrapes and
+--						gives those in progress metricsShutdownTimeout to finish.
+------------------------------------------------------------------------------*/
+func (metrics *metricsServer) shutdown() {
+	if metrics == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+	defer cancel()
+	if err := metrics.server.Shutdown(ctx); err != nil {
+		log.Println("Unable to stop the metrics server:", err)
+	}
+}
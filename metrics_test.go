@@ -0,0 +1,62 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 metrics_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestMetricsScraped(t *testing.T)
+--
+-- NOTES: Tests for the Prometheus endpoint. The observer runs in its own go
+--        routine and is sent connections over the workers' channels, the
+--        test scrapes /metrics as Prometheus would.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestMetricsScraped checks that the metrics follow the connections the
+// observer is sent, and that the endpoint stops once it is shut down.
+func TestMetricsScraped(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{})
+	address := freeAddress(t)
+	srvInfo.metrics = newMetricsServer(address)
+	url := "http://" + address
+	go observerLoop(srvInfo, nil)
+	for i := 0; i < 4; i++ {
+		srvInfo.serverConnection <- newConnectionConst
+	}
+	for i := 0; i < 3; i++ {
+		srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 4, BytesSent: 24}
+	}
+	readStats(srvInfo)
+
+	response, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal("GET /metrics:", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	for _, want := range []string{"scalableserver_connections_total 4", "scalableserver_connections_current 1",
+		"scalableserver_requests_total 12", "scalableserver_bytes_echoed_total 72"} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("the metrics didn't include %q:\n%s", want, body)
+		}
+	}
+
+	srvInfo.metrics.shutdown()
+	if _, err := http.Get(url + "/metrics"); err == nil {
+		t.Error("the metrics were still served after the shutdown")
+	}
+}
@@ -0,0 +1,283 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 otlp.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newOTLPExporter(endpoint string) *otlpExporter
+--  func (exporter *otlpExporter) export(payload []byte)
+--  func (exporter *otlpExporter) run()
+--  func otlpMetrics(srvInfo serverInfo, stats *serverStats, start time.Time, now time.Time) ([]byte, error)
+--  func otlpInt(name string, unit string, value int64, start time.Time, now time.Time, monotonic bool) otlpMetric
+--
+-- NOTES: This file pushes the server's metrics to an OpenTelemetry collector
+--        using OTLP over HTTP with the JSON encoding, so no OpenTelemetry
+--        libraries are needed. The observer builds the metrics on a ticker
+--        and hands them to the exporter, which posts them on its own go
+--        routine. A slow or failing collector never holds up the observer,
+--        metrics are dropped while an export is still in flight.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// otlpTimeout how long an export may take before it is abandoned.
+const otlpTimeout = 10 * time.Second
+
+// otlpCumulative the OTLP aggregation temporality for totals since the server started.
+const otlpCumulative = 2
+
+// otlpExporter posts metrics to an OTLP/HTTP endpoint.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+	pending  chan []byte // the next payload to post, holds at most one
+}
+
+// The OTLP/HTTP JSON encoding of an ExportMetricsServiceRequest. 64 bit
+// integers are written as strings as the encoding requires.
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   map[string]string `json:"scope"`
+	Metrics []otlpMetric      `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit,omitempty"`
+	Gauge     *otlpData      `json:"gauge,omitempty"`
+	Sum       *otlpData      `json:"sum,omitempty"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpData struct {
+	AggregationTemporality int             `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool            `json:"isMonotonic,omitempty"`
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	StartTimeUnixNano string `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string `json:"timeUnixNano"`
+	AsInt             string `json:"asInt"`
+}
+
+type otlpHistogram struct {
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+}
+
+type otlpHistogramDataPoint struct {
+	StartTimeUnixNano string    `json:"startTimeUnixNano"`
+	TimeUnixNano      string    `json:"timeUnixNano"`
+	Count             string    `json:"count"`
+	Sum               float64   `json:"sum"`
+	BucketCounts      []string  `json:"bucketCounts"`
+	ExplicitBounds    []float64 `json:"explicitBounds"`
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newOTLPExporter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newOTLPExporter(endpoint string) *otlpExporter
+--  endpoint:		the collector's metrics URL, e.g. http://localhost:4318/v1/metrics
+--
+-- RETURNS:     *otlpExporter a running exporter, nil if endpoint is empty.
+------------------------------------------------------------------------------*/
+func newOTLPExporter(endpoint string) *otlpExporter {
+	if endpoint == "" {
+		return nil
+	}
+	exporter := &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: otlpTimeout},
+		pending: make(chan []byte, 1)}
+	go exporter.run()
+
+	return exporter
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    export
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (exporter *otlpExporter) export(payload []byte)
+--   payload:		the metrics built by otlpMetrics.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Never blocks. The payload is dropped if the last one hasn't
+--						been posted yet.
+------------------------------------------------------------------------------*/
+func (exporter *otlpExporter) export(payload []byte) {
+	select {
+	case exporter.pending <- payload:
+	default:
+		log.Println("OTLP export still in progress, dropping metrics")
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    run
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (exporter *otlpExporter) run()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Posts each payload handed to export. Errors are logged and the
+--						metrics are not retried, the next export carries the totals.
+------------------------------------------------------------------------------*/
+func (exporter *otlpExporter) run() {
+	for payload := range exporter.pending {
+		response, err := exporter.client.Post(exporter.endpoint, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			log.Println("OTLP export failed:", err)
+			continue
+		}
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+		if response.StatusCode/100 != 2 {
+			log.Println("OTLP export rejected:", response.Status)
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    otlpMetrics
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func otlpMetrics(srvInfo serverInfo, stats *serverStats, start time.Time, now time.Time) ([]byte, error)
+--   srvInfo:		information about the overall server
+--     stats:		the observer's statistics.
+--     start:		when the server started, the start of the totals.
+--       now:		the time of the measurements.
+--
+-- RETURNS:     []byte the metrics encoded as an OTLP/HTTP JSON request.
+--              error any error encoding them.
+--
+-- NOTES:			Must be called by the observer, which owns stats.
+------------------------------------------------------------------------------*/
+func otlpMetrics(srvInfo serverInfo, stats *serverStats, start time.Time, now time.Time) ([]byte, error) {
+	metrics := []otlpMetric{
+		otlpInt("server.connections.current", "{connection}", int64(stats.currentConnections), start, now, false),
+		otlpInt("server.workers.active", "{worker}", int64(*srvInfo.activeWorkers), start, now, false),
+		otlpInt("server.connections.total", "{connection}", int64(stats.totals.TotalConnections), start, now, true),
+		otlpInt("server.requests.total", "{request}", int64(stats.totals.TotalRequests), start, now, true),
+		otlpInt("server.bytes.transferred", "By", atomic.LoadInt64(srvInfo.bytesTransferred), start, now, true),
+	}
+
+	lifetimes := otlpHistogramDataPoint{StartTimeUnixNano: strconv.FormatInt(start.UnixNano(), 10),
+		TimeUnixNano: strconv.FormatInt(now.UnixNano(), 10), Sum: stats.lifetimeTotal.Seconds()}
+	count := 0
+	for _, connections := range stats.lifetimes {
+		lifetimes.BucketCounts = append(lifetimes.BucketCounts, strconv.Itoa(connections))
+		count += connections
+	}
+	lifetimes.Count = strconv.Itoa(count)
+	for _, bound := range lifetimeBounds {
+		lifetimes.ExplicitBounds = append(lifetimes.ExplicitBounds, bound.Seconds())
+	}
+	metrics = append(metrics, otlpMetric{Name: "server.connection.lifetime", Unit: "s",
+		Histogram: &otlpHistogram{AggregationTemporality: otlpCumulative,
+			DataPoints: []otlpHistogramDataPoint{lifetimes}}})
+
+	return json.Marshal(otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": "scalable-server"}}}},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: map[string]string{"name": "scalableserver"}, Metrics: metrics}},
+	}}})
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    otlpInt
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func otlpInt(name string, unit string, value int64, start time.Time, now time.Time, monotonic bool) otlpMetric
+--      name:		the name of the metric.
+--      unit:		the unit of the metric in UCUM.
+--     value:		the measurement.
+--     start:		the start of a total.
+--       now:		the time of the measurement.
+-- monotonic:		true for a total since start, false for a gauge.
+--
+-- RETURNS:     otlpMetric the metric with a single data point.
+------------------------------------------------------------------------------*/
+func otlpInt(name string, unit string, value int64, start time.Time, now time.Time, monotonic bool) otlpMetric {
+	point := otlpDataPoint{TimeUnixNano: strconv.FormatInt(now.UnixNano(), 10),
+		AsInt: strconv.FormatInt(value, 10)}
+	if !monotonic {
+		return otlpMetric{Name: name, Unit: unit, Gauge: &otlpData{DataPoints: []otlpDataPoint{point}}}
+	}
+	point.StartTimeUnixNano = strconv.FormatInt(start.UnixNano(), 10)
+
+	return otlpMetric{Name: name, Unit: unit, Sum: &otlpData{AggregationTemporality: otlpCumulative,
+		IsMonotonic: true, DataPoints: []otlpDataPoint{point}}}
+}
@@ -0,0 +1,82 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 otlp_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestOTLPExported(t *testing.T)
+--  func TestOTLPStalledCollector(t *testing.T)
+--
+-- NOTES: Tests for pushing metrics over OTLP/HTTP. The collector is a stub
+--        served by httptest, the observer's export ticker runs on a
+--        fakeClock.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestOTLPExported checks that the observer posts the server's totals to the
+// collector each -otlp-interval.
+func TestOTLPExported(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error("the export wasn't OTLP JSON:", err)
+		}
+		received <- request
+	}))
+	defer collector.Close()
+
+	srvInfo := newTestServerInfo(serverConfig{otlpInterval: 10 * time.Second})
+	srvInfo.otlp = newOTLPExporter(collector.URL)
+	go observerLoop(srvInfo, nil)
+	srvInfo.serverConnection <- newConnectionConst
+	srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 3}
+	readStats(srvInfo) // the connection has been counted
+	srvInfo.clock.(*fakeClock).Advance(10 * time.Second)
+
+	request := <-received
+	values := make(map[string]string)
+	for _, resource := range request.ResourceMetrics {
+		for _, scope := range resource.ScopeMetrics {
+			for _, metric := range scope.Metrics {
+				if data := metric.Sum; data != nil && len(data.DataPoints) == 1 {
+					values[metric.Name] = data.DataPoints[0].AsInt
+				}
+			}
+		}
+	}
+	if values["server.connections.total"] != "1" || values["server.requests.total"] != "3" {
+		t.Errorf("exported totals %v, want 1 connection and 3 requests", values)
+	}
+}
+
+// TestOTLPStalledCollector checks that exports are dropped rather than
+// waited on while the collector is stuck on an earlier one.
+func TestOTLPStalledCollector(t *testing.T) {
+	stalled := make(chan bool)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	defer collector.Close()
+	defer close(stalled)
+
+	exporter := newOTLPExporter(collector.URL)
+	for i := 0; i < 5; i++ {
+		exporter.export([]byte("{}")) // returns straight away, or the test never ends
+	}
+}
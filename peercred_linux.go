@@ -0,0 +1,74 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 peercred_linux.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func peerCredentials(conn net.Conn) string
+--
+-- NOTES: This file identifies the process on the other end of a Unix socket
+--        using SO_PEERCRED.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredSupported whether peerCredentials can identify anything on this platform.
+const peerCredSupported = true
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    peerCredentials
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - looks through wrapped connections
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func peerCredentials(conn net.Conn) string
+--      conn:		a connection a worker has accepted.
+--
+-- RETURNS:     string "uid=U gid=G pid=P" for the process that connected, empty
+--              if conn isn't a Unix socket or the credentials can't be read.
+--
+-- NOTES:			The credentials are the ones the client had when it connected.
+------------------------------------------------------------------------------*/
+func peerCredentials(conn net.Conn) string {
+	if wrapped, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = wrapped.NetConn()
+	}
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ""
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return ""
+	}
+
+	var cred *unix.Ucred
+	raw.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("uid=%d gid=%d pid=%d", cred.Uid, cred.Gid, cred.Pid)
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 peercred_linux_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestPeerCredRecorded(t *testing.T)
+--
+-- NOTES: Tests for recording the credentials of Unix socket clients. The test
+--        process connects to itself, so the credentials are its own.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPeerCredRecorded checks that a connection over a Unix socket records
+// the uid, gid and pid of the process that connected.
+func TestPeerCredRecorded(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "echo.sock"))
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal("accept:", err)
+	}
+	defer conn.Close()
+	client.Close()
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', peerCred: true})
+	connInfo := connectionInstance(context.Background(), conn, srvInfo, 1)
+	if want := fmt.Sprintf("uid=%d gid=%d pid=%d", os.Getuid(), os.Getgid(), os.Getpid()); connInfo.PeerCred != want {
+		t.Errorf("peer credentials %q, want %q", connInfo.PeerCred, want)
+	}
+}
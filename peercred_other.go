@@ -0,0 +1,45 @@
+//go:build !linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 peercred_other.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func peerCredentials(conn net.Conn) string
+--
+-- NOTES: Peer credentials are only read on Linux.
+------------------------------------------------------------------------------*/
+package main
+
+import "net"
+
+// peerCredSupported whether peerCredentials can identify anything on this platform.
+const peerCredSupported = false
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    peerCredentials
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func peerCredentials(conn net.Conn) string
+--      conn:		a connection a worker has accepted.
+--
+-- RETURNS:     string always empty.
+------------------------------------------------------------------------------*/
+func peerCredentials(conn net.Conn) string {
+	return ""
+}
@@ -0,0 +1,203 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 pipeline.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func parsePipeline(spec string) (pipeline, error)
+--  func (stages pipeline) run(body []byte) ([]byte, error)
+--  func decompressStage(body []byte) ([]byte, error)
+--  func padStage(width int) pipelineStage
+--
+-- NOTES: This file transforms each request before it is echoed so clients
+--        can be tested against something other than a plain echo. -pipeline
+--        lists the stages, separated by commas, and each request's payload
+--        goes through them in order:
+--          decompress  gunzip the payload
+--          upper       upper case ASCII letters
+--          lower       lower case ASCII letters
+--          reverse     reverse the bytes
+--          pad=N       pad with spaces to at least N bytes
+--        The delimiter isn't passed through the stages, it is put back after
+--        them. A compressed payload can hold any byte so decompress is best
+--        used with header or length framing.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pipelineStage transforms a request payload on its way to being echoed.
+type pipelineStage func(body []byte) ([]byte, error)
+
+// pipeline the stages from -pipeline in the order they are run.
+type pipeline []pipelineStage
+
+// errDecompressedTooLarge a decompressed payload over maxLengthPayload.
+var errDecompressedTooLarge = errors.New("decompressed payload exceeds the maximum size")
+
+// pipelineStages the stages that take no argument, by name.
+var pipelineStages = map[string]pipelineStage{
+	"decompress": decompressStage,
+	"upper":      func(body []byte) ([]byte, error) { return bytes.ToUpper(body), nil },
+	"lower":      func(body []byte) ([]byte, error) { return bytes.ToLower(body), nil },
+	"reverse": func(body []byte) ([]byte, error) {
+		reversed := make([]byte, len(body))
+		for i, b := range body {
+			reversed[len(body)-1-i] = b
+		}
+		return reversed, nil
+	},
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parsePipeline
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parsePipeline(spec string) (pipeline, error)
+--      spec:		"stage,stage,...", as given to -pipeline.
+--
+-- RETURNS:     pipeline the stages in the order they are run, nil if spec
+--              is empty.
+--              error any stage that isn't known or has a bad argument.
+------------------------------------------------------------------------------*/
+func parsePipeline(spec string) (pipeline, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var stages pipeline
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, found := strings.Cut(entry, "=")
+		if name == "pad" && found {
+			width, err := strconv.Atoi(value)
+			if err != nil || width < 1 {
+				return nil, errors.New("invalid pad width " + strconv.Quote(value))
+			}
+			stages = append(stages, padStage(width))
+			continue
+		}
+		stage, ok := pipelineStages[entry]
+		if !ok {
+			return nil, errors.New("unknown stage " + strconv.Quote(entry))
+		}
+		stages = append(stages, stage)
+	}
+
+	return stages, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    run
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stages pipeline) run(body []byte) ([]byte, error)
+--      body:		the request payload without its delimiter.
+--
+-- RETURNS:     []byte the payload after every stage.
+--              error the first error a stage returned.
+------------------------------------------------------------------------------*/
+func (stages pipeline) run(body []byte) ([]byte, error) {
+	var err error
+	for _, stage := range stages {
+		if body, err = stage(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    decompressStage
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func decompressStage(body []byte) ([]byte, error)
+--      body:		a gzip compressed payload.
+--
+-- RETURNS:     []byte the decompressed payload.
+--              error any error decompressing it, or errDecompressedTooLarge.
+--
+-- NOTES:			The output is limited to maxLengthPayload so a small request
+--						can't make the server allocate without bound.
+------------------------------------------------------------------------------*/
+func decompressStage(body []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxLengthPayload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxLengthPayload {
+		return nil, errDecompressedTooLarge
+	}
+
+	return decompressed, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    padStage
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func padStage(width int) pipelineStage
+--     width:		the length payloads are padded to.
+--
+-- RETURNS:     pipelineStage a stage appending spaces to payloads shorter than
+--              width. Longer payloads are left as they are.
+------------------------------------------------------------------------------*/
+func padStage(width int) pipelineStage {
+	return func(body []byte) ([]byte, error) {
+		if len(body) >= width {
+			return body, nil
+		}
+		padded := make([]byte, width)
+		copy(padded, body)
+		for i := len(body); i < width; i++ {
+			padded[i] = ' '
+		}
+		return padded, nil
+	}
+}
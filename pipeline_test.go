@@ -0,0 +1,95 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 pipeline_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestParsePipelineInvalid(t *testing.T)
+--  func TestTwoStagesOnConnection(t *testing.T)
+--  func TestDecompressStageOnConnection(t *testing.T)
+--
+-- NOTES: Tests for the -pipeline stages, run over one end of a net.Pipe as
+--        a worker would serve it.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+// TestParsePipelineInvalid checks that unknown stages and bad pad widths are
+// rejected when the pipeline is parsed.
+func TestParsePipelineInvalid(t *testing.T) {
+	for _, spec := range []string{"bogus", "upper,,reverse", "pad=0", "pad=x", "upper=1"} {
+		if _, err := parsePipeline(spec); err == nil {
+			t.Errorf("parsePipeline(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// TestTwoStagesOnConnection checks that each request goes through both stages
+// in order, with the delimiter put back after them, and that the bytes in and
+// out of the pipeline are counted.
+func TestTwoStagesOnConnection(t *testing.T) {
+	stages, err := parsePipeline("upper,reverse")
+	if err != nil {
+		t.Fatal("parsePipeline:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', pipeline: stages})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+	for _, test := range []struct{ request, want string }{{"hello\n", "OLLEH\n"}, {"Mixed Case\n", "ESAC DEXIM\n"}} {
+		go io.WriteString(client, test.request)
+		if echo, err := reader.ReadString('\n'); echo != test.want {
+			t.Errorf("%q was echoed as %q, %v, want %q", test.request, echo, err, test.want)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.PipelineBytesIn != 15 || connInfo.PipelineBytesOut != 15 || connInfo.PipelineErrors != 0 {
+		t.Errorf("%d bytes into the pipeline, %d out and %d errors, want 15, 15 and 0",
+			connInfo.PipelineBytesIn, connInfo.PipelineBytesOut, connInfo.PipelineErrors)
+	}
+}
+
+// TestDecompressStageOnConnection checks that a gzipped length framed request
+// is decompressed before the next stage, and that one that isn't gzipped is
+// counted as an error and echoed unchanged.
+func TestDecompressStageOnConnection(t *testing.T) {
+	stages, err := parsePipeline("decompress,upper")
+	if err != nil {
+		t.Fatal("parsePipeline:", err)
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	io.WriteString(writer, "hello")
+	writer.Close()
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLength, pipeline: stages})
+	client, served := serveTestConnection(t, srvInfo)
+	for _, test := range []struct{ request, want string }{{compressed.String(), "HELLO"}, {"plain", "plain"}} {
+		go io.WriteString(client, lengthFrame(test.request))
+		echo := make([]byte, lengthPrefixSize+len(test.want))
+		if _, err := io.ReadFull(client, echo); string(echo) != lengthFrame(test.want) {
+			t.Errorf("the echo was %q, %v, want %q", echo, err, lengthFrame(test.want))
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.PipelineBytesIn != compressed.Len() || connInfo.PipelineBytesOut != 5 ||
+		connInfo.PipelineErrors != 1 {
+		t.Errorf("%d bytes into the pipeline, %d out and %d errors, want %d, 5 and 1",
+			connInfo.PipelineBytesIn, connInfo.PipelineBytesOut, connInfo.PipelineErrors, compressed.Len())
+	}
+}
@@ -0,0 +1,171 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 probe.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startProbeServer(srvInfo serverInfo)
+--  func probeEcho(srvInfo serverInfo) error
+--  func isProbe(srvInfo serverInfo, connInfo connectionInfo) bool
+--
+-- NOTES: This file serves a health check over HTTP that connects to the echo
+--        listener and checks a request is echoed, so a server whose workers
+--        are wedged is reported as unhealthy even though it is listening.
+--        The probe's connections are left out of the report.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// closeReasonProbe the CloseReason given to the health probe's connections.
+const closeReasonProbe = "probe"
+
+// probeMessage the request sent by the health probe, before its delimiter.
+const probeMessage = "probe"
+
+var errProbeMismatch = errors.New("probe response did not match the request")
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startProbeServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startProbeServer(srvInfo serverInfo)
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing if -probe-addr isn't set. Every request to the
+--						probe address gets 200 if the echo round trip works and 503
+--						if it doesn't. Exits the program if the address can't be
+--						listened on.
+------------------------------------------------------------------------------*/
+func startProbeServer(srvInfo serverInfo) {
+	if srvInfo.config.probeAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", srvInfo.config.probeAddr)
+	if err != nil {
+		log.Fatalln("Unable to listen for probes:", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := probeEcho(srvInfo); err != nil {
+			log.Println("Probe failed:", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "OK")
+	})
+	go http.Serve(listener, handler)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    probeEcho
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the request ends with -delimiter
+--              October 15, 2026 - dials the network the listener is on
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func probeEcho(srvInfo serverInfo) error
+--   srvInfo:		information about the overall server
+--
+-- RETURNS:     error why the round trip failed, nil if it worked.
+--
+-- NOTES:			Connects to the echo listener the way a client would, using
+--						TLS and the configured framing, and gives up after
+--						-probe-timeout. The probe's address is recorded so the worker
+--						that serves it can tag the connection. Only the echoed
+--						payload is checked when nonces are added to it.
+------------------------------------------------------------------------------*/
+func probeEcho(srvInfo serverInfo) error {
+	cfg := srvInfo.config
+	conn, err := net.DialTimeout(srvInfo.listener.Addr().Network(), srvInfo.listener.Addr().String(), cfg.probeTimeout)
+	if err != nil {
+		return err
+	}
+	srvInfo.probes.Store(conn.LocalAddr().String(), true)
+	if cfg.tlsCert != "" || cfg.tlsKey != "" {
+		conn = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(cfg.probeTimeout))
+
+	request := append([]byte(probeMessage), cfg.delimiter)
+	expected := buildResponse(request, cfg)
+	if cfg.ack {
+		expected = ackResponse(1, cfg)
+	}
+	if _, err = conn.Write(frameResponse(request, cfg)); err != nil {
+		return err
+	}
+	response, err := readRequest(bufio.NewReader(conn), &serverConfig{framing: cfg.framing, delimiter: cfg.delimiter})
+	if err != nil {
+		return err
+	}
+	if cfg.nonce && !cfg.ack {
+		expected, _ = splitDelimiter(expected, cfg)
+		response = response[:min(len(expected), len(response))]
+	}
+	if !bytes.Equal(response, expected) {
+		return errProbeMismatch
+	}
+
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isProbe
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func isProbe(srvInfo serverInfo, connInfo connectionInfo) bool
+--   srvInfo:		information about the overall server
+--  connInfo:		a connection a worker has finished with.
+--
+-- RETURNS:     bool true if the connection was made by the health probe.
+--
+-- NOTES:			The probe's address is forgotten once it has been checked.
+------------------------------------------------------------------------------*/
+func isProbe(srvInfo serverInfo, connInfo connectionInfo) bool {
+	if srvInfo.config.probeAddr == "" {
+		return false
+	}
+	_, ok := srvInfo.probes.LoadAndDelete(connInfo.HostName)
+
+	return ok
+}
@@ -0,0 +1,86 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 probe_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newProbeServerInfo(t *testing.T) serverInfo
+--  func TestProbeHealthy(t *testing.T)
+--  func TestProbeStalledWorkers(t *testing.T)
+--
+-- NOTES: Tests for the health probe. The probe connects to a real loopback
+--        listener, whose connections the test accepts in place of a worker.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newProbeServerInfo a serverInfo listening on loopback with the probe enabled.
+func newProbeServerInfo(t *testing.T) serverInfo {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		probeAddr: "127.0.0.1:0", probeTimeout: 100 * time.Millisecond})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	srvInfo.listener = listener
+	srvInfo.probes = new(sync.Map)
+
+	return srvInfo
+}
+
+// TestProbeHealthy checks that the probe passes when its request is echoed
+// and that its connection is tagged so it stays out of the report.
+func TestProbeHealthy(t *testing.T) {
+	srvInfo := newProbeServerInfo(t)
+	served := make(chan connectionInfo, 1)
+	go func() {
+		conn, err := srvInfo.listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		served <- connectionInstance(context.Background(), conn, srvInfo, 1)
+	}()
+
+	if err := probeEcho(srvInfo); err != nil {
+		t.Fatal("probe of a working server failed:", err)
+	}
+	if connInfo := <-served; !isProbe(srvInfo, connInfo) {
+		t.Errorf("the probe's connection from %s wasn't tagged", connInfo.HostName)
+	}
+}
+
+// TestProbeStalledWorkers checks that the probe fails when the listener
+// accepts its connection but nothing echoes its request.
+func TestProbeStalledWorkers(t *testing.T) {
+	srvInfo := newProbeServerInfo(t)
+	wedged := make(chan bool)
+	defer close(wedged)
+	go func() {
+		conn, err := srvInfo.listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-wedged
+	}()
+
+	if err := probeEcho(srvInfo); !isTimeout(err) {
+		t.Errorf("probe of a stalled server returned %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,190 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 proxy.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func readProxyHeader(conn net.Conn, timeout time.Duration) (net.Conn, error)
+--  func parseProxyHeader(line string) (*net.TCPAddr, error)
+--  func (conn *proxiedConn) RemoteAddr() net.Addr
+--  func (conn *proxiedConn) NetConn() net.Conn
+--
+-- NOTES: This file reads the PROXY protocol v1 header a load balancer sends
+--        at the start of each connection with -proxy-protocol, so the report
+--        lists the real clients instead of the balancer. The header is a
+--        single line such as "PROXY TCP4 192.0.2.1 198.51.100.1 56324 8005",
+--        ended by CRLF and at most 107 bytes long. The version 2 binary
+--        header isn't supported. The header is read as soon as the
+--        connection is accepted, so -allow-cidr, -deny-cidr, -rate-limit and
+--        the peak connections of each client all see the client it names.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// closeReasonProxyHeader the CloseReason of a connection that didn't start with
+// a valid PROXY protocol header.
+const closeReasonProxyHeader = "bad-proxy-header"
+
+// proxyHeaderMax the longest a PROXY protocol v1 header can be, CRLF included.
+const proxyHeaderMax = 107
+
+var errProxyHeader = errors.New("malformed PROXY protocol header")
+
+// proxiedConn a connection whose remote address is the client named by its
+// PROXY protocol header rather than the balancer.
+type proxiedConn struct {
+	net.Conn
+	source net.Addr
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readProxyHeader
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - returns the connection with the client as its remote address
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readProxyHeader(conn net.Conn, timeout time.Duration) (net.Conn, error)
+--      conn:		a connection that has just been accepted.
+--   timeout:		how long the header can take to arrive, -proxy-header-timeout.
+--
+-- RETURNS:     net.Conn the connection to serve, its RemoteAddr is the client
+--              from the header. conn itself if the balancer didn't know the
+--              client or the header couldn't be read.
+--              error errProxyHeader if the header is malformed, or any error
+--              reading it.
+--
+-- NOTES:			The header is read a byte at a time so nothing after it is
+--						taken from the connection before the worker's reader is made.
+--						It isn't counted in the connection's bytes.
+------------------------------------------------------------------------------*/
+func readProxyHeader(conn net.Conn, timeout time.Duration) (net.Conn, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	header := make([]byte, 0, proxyHeaderMax)
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(header, []byte("\r\n")) {
+		if len(header) == proxyHeaderMax {
+			return conn, errProxyHeader
+		}
+		if _, err := conn.Read(b); err != nil {
+			return conn, err
+		}
+		header = append(header, b[0])
+	}
+
+	source, err := parseProxyHeader(string(header[:len(header)-2]))
+	if err != nil || source == nil {
+		return conn, err
+	}
+
+	return &proxiedConn{Conn: conn, source: source}, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    parseProxyHeader
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - returns the address as a *net.TCPAddr
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func parseProxyHeader(line string) (*net.TCPAddr, error)
+--      line:		the header without its CRLF.
+--
+-- RETURNS:     *net.TCPAddr the source address and port from the header, nil
+--              for "PROXY UNKNOWN".
+--              error errProxyHeader if the header is malformed.
+------------------------------------------------------------------------------*/
+func parseProxyHeader(line string) (*net.TCPAddr, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, errProxyHeader
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, errProxyHeader
+	}
+
+	for _, address := range fields[2:4] {
+		ip := net.ParseIP(address)
+		if ip == nil || (ip.To4() != nil) != (fields[1] == "TCP4") {
+			return nil, errProxyHeader
+		}
+	}
+	if _, err := strconv.ParseUint(fields[5], 10, 16); err != nil {
+		return nil, errProxyHeader
+	}
+	sourcePort, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, errProxyHeader
+	}
+
+	return &net.TCPAddr{IP: net.ParseIP(fields[2]), Port: int(sourcePort)}, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    RemoteAddr
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *proxiedConn) RemoteAddr() net.Addr
+--
+-- RETURNS:     net.Addr the client named by the PROXY protocol header.
+------------------------------------------------------------------------------*/
+func (conn *proxiedConn) RemoteAddr() net.Addr {
+	return conn.source
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    NetConn
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *proxiedConn) NetConn() net.Conn
+--
+-- RETURNS:     net.Conn the connection accepted from the listener.
+--
+-- NOTES:			Matches tls.Conn so socket options can be set on the
+--						underlying connection.
+------------------------------------------------------------------------------*/
+func (conn *proxiedConn) NetConn() net.Conn {
+	return conn.Conn
+}
@@ -0,0 +1,153 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 proxy_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func proxyPipe(t *testing.T, data string) net.Conn
+--  func TestReadProxyHeaderValid(t *testing.T)
+--  func TestReadProxyHeaderMalformed(t *testing.T)
+--  func TestParseProxyHeader(t *testing.T)
+--  func proxied(t *testing.T, client string) net.Conn
+--  func TestProxiedConnFiltered(t *testing.T)
+--
+-- NOTES: Tests for reading the -proxy-protocol header. The balancer's side of
+--        each connection is one end of a net.Pipe.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// proxyPipe the server's end of a connection the balancer sends data on.
+func proxyPipe(t *testing.T, data string) net.Conn {
+	server, balancer := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		balancer.Close()
+	})
+	go func() {
+		io.WriteString(balancer, data)
+	}()
+
+	return server
+}
+
+// TestReadProxyHeaderValid checks that a valid header names the client and
+// leaves the request after it to be read.
+func TestReadProxyHeaderValid(t *testing.T) {
+	conn := proxyPipe(t, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 8005\r\nhello\n")
+
+	proxied, err := readProxyHeader(conn, time.Second)
+	if err != nil {
+		t.Fatal("readProxyHeader:", err)
+	}
+	if got := proxied.RemoteAddr().String(); got != "192.0.2.1:56324" {
+		t.Errorf("RemoteAddr() = %q, want 192.0.2.1:56324", got)
+	}
+	request, err := bufio.NewReader(proxied).ReadString('\n')
+	if err != nil || request != "hello\n" {
+		t.Errorf("the request after the header was %q, %v", request, err)
+	}
+}
+
+// TestReadProxyHeaderMalformed checks that a malformed header is an error and
+// the connection keeps the balancer's address.
+func TestReadProxyHeaderMalformed(t *testing.T) {
+	conn := proxyPipe(t, "PROXY TCP4 192.0.2.1 not-an-address 56324 8005\r\nhello\n")
+
+	proxied, err := readProxyHeader(conn, time.Second)
+	if !errors.Is(err, errProxyHeader) {
+		t.Fatalf("readProxyHeader error = %v, want %v", err, errProxyHeader)
+	}
+	if proxied != conn {
+		t.Error("a connection with a malformed header was wrapped")
+	}
+}
+
+// TestParseProxyHeader checks the header lines that are and aren't accepted.
+func TestParseProxyHeader(t *testing.T) {
+	tests := []struct {
+		line string
+		want string // the source address, empty for none
+		err  bool
+	}{
+		{"PROXY TCP4 192.0.2.1 198.51.100.1 56324 8005", "192.0.2.1:56324", false},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 8005", "[2001:db8::1]:56324", false},
+		{"PROXY UNKNOWN", "", false},
+		{"PROXY TCP4 2001:db8::1 198.51.100.1 56324 8005", "", true},
+		{"PROXY TCP4 192.0.2.1 198.51.100.1 56324 70000", "", true},
+		{"PROXY TCP4 192.0.2.1 198.51.100.1 56324", "", true},
+		{"PROXY UDP4 192.0.2.1 198.51.100.1 56324 8005", "", true},
+		{"GET / HTTP/1.1", "", true},
+	}
+
+	for _, test := range tests {
+		source, err := parseProxyHeader(test.line)
+		if (err != nil) != test.err {
+			t.Errorf("parseProxyHeader(%q) error = %v", test.line, err)
+			continue
+		}
+		var got string
+		if source != nil {
+			got = source.String()
+		}
+		if got != test.want {
+			t.Errorf("parseProxyHeader(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+// proxied the server's end of a connection from client through the balancer.
+func proxied(t *testing.T, client string) net.Conn {
+	conn, err := readProxyHeader(proxyPipe(t, "PROXY TCP4 "+client+" 198.51.100.1 56324 8005\r\n"), time.Second)
+	if err != nil {
+		t.Fatal("readProxyHeader:", err)
+	}
+
+	return conn
+}
+
+// TestProxiedConnFiltered checks that -deny-cidr, -rate-limit and the
+// registry see the client named by the header rather than the balancer.
+func TestProxiedConnFiltered(t *testing.T) {
+	var deny cidrList
+	deny.Set("192.0.2.0/24")
+	clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+	srvInfo := serverInfo{config: &serverConfig{}, ipFilter: newIPFilter(nil, deny),
+		rateLimiter: newIPRateLimiter(1, clock), logger: newLogger(io.Discard, logLevelError, logFormatText)}
+
+	if !turnedAway(srvInfo, proxied(t, "192.0.2.1")) {
+		t.Error("a client in -deny-cidr was served through the balancer")
+	}
+	allowed := proxied(t, "203.0.113.1")
+	if turnedAway(srvInfo, allowed) {
+		t.Error("a client outside -deny-cidr was turned away")
+	}
+	if !turnedAway(srvInfo, proxied(t, "203.0.113.1")) {
+		t.Error("a client over -rate-limit was served through the balancer")
+	}
+	if turnedAway(srvInfo, proxied(t, "203.0.113.2")) {
+		t.Error("another client behind the balancer was held to the first one's -rate-limit")
+	}
+
+	registry := newConnRegistry()
+	registered := registry.add(allowed)
+	defer registry.remove(registered)
+	if _, clients := registry.clients(topClients); len(clients) != 1 || clients[0].RemoteIP != "203.0.113.1" {
+		t.Errorf("the registry counted %+v, want 203.0.113.1", clients)
+	}
+}
@@ -0,0 +1,96 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 random.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newLockedRand(seed int64) *lockedRand
+--  func (r *lockedRand) Float64() float64
+--  func (r *lockedRand) ExpFloat64() float64
+--
+-- NOTES: This file holds the random number generator shared by the workers.
+--        It is seeded once so that runs can be reproduced.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// lockedRand a rand.Rand that is safe to use from many go routines.
+type lockedRand struct {
+	mutex sync.Mutex
+	rng   *rand.Rand
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newLockedRand
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newLockedRand(seed int64) *lockedRand
+--      seed:		the seed for the generator.
+--
+-- RETURNS:     *lockedRand the generator.
+------------------------------------------------------------------------------*/
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{rng: rand.New(rand.NewSource(seed))}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Float64
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (r *lockedRand) Float64() float64
+--
+-- RETURNS:     float64 a number in [0.0,1.0)
+------------------------------------------------------------------------------*/
+func (r *lockedRand) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.rng.Float64()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    ExpFloat64
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (r *lockedRand) ExpFloat64() float64
+--
+-- RETURNS:     float64 an exponentially distributed number with a mean of 1
+------------------------------------------------------------------------------*/
+func (r *lockedRand) ExpFloat64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.rng.ExpFloat64()
+}
@@ -0,0 +1,161 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 ratelimit.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newIPRateLimiter(perSecond float64, clock Clock) *ipRateLimiter
+--  func (limiter *ipRateLimiter) allow(addr net.Addr) bool
+--  func (limiter *ipRateLimiter) sweep(now time.Time)
+--
+-- NOTES: This file caps how fast each client IP can open connections with
+--        -rate-limit, so one misbehaving client can't take the server over.
+--        Unlike -max-connections-per-second, which is shared by every
+--        client, each IP gets its own rate.Limiter with a burst of a
+--        second's worth of connections, so a client can connect in bursts as
+--        long as it keeps to the rate on average. The limiters are swept once
+--        a minute, an IP that hasn't connected for long enough for its
+--        bucket to fill up again is forgotten as a new limiter would be the
+--        same.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitSweep how often limiters that have filled up again are forgotten.
+const rateLimitSweep = time.Minute
+
+// ipRateLimiter the connection rate limiters of every client IP, shared by the
+// workers.
+type ipRateLimiter struct {
+	mutex     sync.Mutex
+	limit     rate.Limit    // the connections each IP can open a second
+	burst     int           // the most connections an IP can open at once
+	refill    time.Duration // how long an emptied limiter takes to fill
+	clock     Clock
+	lastSweep time.Time
+	clients   map[string]*ipLimiter // by IP
+}
+
+// ipLimiter the limiter of one client IP.
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time // when the IP last connected
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newIPRateLimiter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - one rate.Limiter per IP
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newIPRateLimiter(perSecond float64, clock Clock) *ipRateLimiter
+--  perSecond:		the most connections each IP can open a second.
+--     clock:		the clock connections are timed by.
+--
+-- RETURNS:     *ipRateLimiter the limiter, nil if perSecond is 0.
+------------------------------------------------------------------------------*/
+func newIPRateLimiter(perSecond float64, clock Clock) *ipRateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	burst := math.Ceil(perSecond)
+
+	return &ipRateLimiter{limit: rate.Limit(perSecond), burst: int(burst),
+		refill: time.Duration(burst / perSecond * float64(time.Second)),
+		clock:  clock, lastSweep: clock.Now(), clients: make(map[string]*ipLimiter)}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    allow
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - one rate.Limiter per IP
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (limiter *ipRateLimiter) allow(addr net.Addr) bool
+--      addr:		the remote address of a connection that was just accepted.
+--
+-- RETURNS:     bool whether the connection is under its IP's limit. true on a
+--              nil limiter or an address without an IP.
+--
+-- NOTES:			The limiter is given the time from the server clock rather
+--						than reading the wall clock itself.
+------------------------------------------------------------------------------*/
+func (limiter *ipRateLimiter) allow(addr net.Addr) bool {
+	if limiter == nil {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return true
+	}
+	now := limiter.clock.Now()
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if now.Sub(limiter.lastSweep) >= rateLimitSweep {
+		limiter.sweep(now)
+	}
+	key := tcpAddr.IP.String()
+	client, ok := limiter.clients[key]
+	if !ok {
+		client = &ipLimiter{limiter: rate.NewLimiter(limiter.limit, limiter.burst)}
+		limiter.clients[key] = client
+	}
+	client.lastSeen = now
+
+	return client.limiter.AllowN(now, 1)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    sweep
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (limiter *ipRateLimiter) sweep(now time.Time)
+--       now:		the time of the sweep.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Must be called with the mutex held. Forgets every IP that hasn't
+--						connected for long enough for its bucket to fill, so the map
+--						only grows with the clients connecting recently.
+------------------------------------------------------------------------------*/
+func (limiter *ipRateLimiter) sweep(now time.Time) {
+	for key, client := range limiter.clients {
+		if now.Sub(client.lastSeen) >= limiter.refill {
+			delete(limiter.clients, key)
+		}
+	}
+	limiter.lastSweep = now
+}
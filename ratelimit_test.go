@@ -0,0 +1,92 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 ratelimit_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func tcpAddr(ip string, port int) net.Addr
+--  func TestIPRateLimiterPerIP(t *testing.T)
+--  func TestIPRateLimiterSweep(t *testing.T)
+--  func TestIPRateLimiterDisabled(t *testing.T)
+--
+-- NOTES: Tests for the -rate-limit limiters. The limiters are driven by a
+--        fakeClock so refilling doesn't have to be waited on.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpAddr the address of a client connecting from ip.
+func tcpAddr(ip string, port int) net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+}
+
+// TestIPRateLimiterPerIP checks that the connections one IP opens over its
+// limit are refused while another IP can still connect.
+func TestIPRateLimiterPerIP(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+	limiter := newIPRateLimiter(3, clock)
+
+	for i := 0; i < 3; i++ {
+		if !limiter.allow(tcpAddr("192.0.2.1", 40000+i)) {
+			t.Fatalf("connection %d from 192.0.2.1 was refused inside the burst", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if limiter.allow(tcpAddr("192.0.2.1", 40010+i)) {
+			t.Fatalf("excess connection %d from 192.0.2.1 was allowed", i)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if !limiter.allow(tcpAddr("192.0.2.2", 40000+i)) {
+			t.Fatalf("connection %d from 192.0.2.2 was refused by 192.0.2.1's limit", i)
+		}
+	}
+
+	clock.Advance(time.Second / 3)
+	if !limiter.allow(tcpAddr("192.0.2.1", 40020)) {
+		t.Fatal("192.0.2.1 was still refused after a connection's worth of time")
+	}
+	if limiter.allow(tcpAddr("192.0.2.1", 40021)) {
+		t.Fatal("192.0.2.1 was allowed more than the rate refilled")
+	}
+}
+
+// TestIPRateLimiterSweep checks that IPs whose limiters have filled up again
+// are forgotten by the sweep and recent ones are kept.
+func TestIPRateLimiterSweep(t *testing.T) {
+	clock := newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC))
+	limiter := newIPRateLimiter(2, clock)
+
+	limiter.allow(tcpAddr("192.0.2.1", 40000))
+	clock.Advance(rateLimitSweep)
+	limiter.allow(tcpAddr("192.0.2.2", 40000))
+
+	if _, ok := limiter.clients["192.0.2.1"]; ok {
+		t.Error("192.0.2.1 was kept after its limiter filled up")
+	}
+	if _, ok := limiter.clients["192.0.2.2"]; !ok {
+		t.Error("192.0.2.2 was swept while connecting")
+	}
+}
+
+// TestIPRateLimiterDisabled checks that a -rate-limit of 0 allows everything.
+func TestIPRateLimiterDisabled(t *testing.T) {
+	limiter := newIPRateLimiter(0, realClock{})
+	for i := 0; i < 100; i++ {
+		if !limiter.allow(tcpAddr("192.0.2.1", 40000+i)) {
+			t.Fatal("a nil limiter refused a connection")
+		}
+	}
+}
@@ -0,0 +1,507 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 registry.go
+--
+-- REVISIONS: 	October 15, 2026 - Connections can be closed newest first
+--              October 15, 2026 - Errors caused by the shutdown closing a
+--                connection are told apart from real errors
+--              October 15, 2026 - Tracks the peak connections from each client
+--              October 15, 2026 - Only clients with connections open are
+--                tracked, the report keeps the top peaks of the rest
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newConnRegistry() *connRegistry
+--  func (registry *connRegistry) add(conn net.Conn) *registeredConn
+--  func (registry *connRegistry) remove(conn *registeredConn)
+--  func (registry *connRegistry) len() int
+--  func (registry *connRegistry) closeAll(newestFirst bool)
+--  func (registry *connRegistry) clients(top int) (int, []clientConcurrency)
+--  func (registry *connRegistry) resetClients()
+--  func (registry *connRegistry) retireClient(client clientConcurrency)
+--  func sortClients(clients []clientConcurrency)
+--  func remoteIP(addr net.Addr) string
+--  func (conn *registeredConn) Read(b []byte) (int, error)
+--  func (conn *registeredConn) Write(b []byte) (int, error)
+--  func (conn *registeredConn) NetConn() net.Conn
+--  func (conn *registeredConn) shutdownClose()
+--
+-- NOTES: This file keeps track of the connections the workers are serving so
+--        that they can be closed when the server shuts down, and of how many
+--        connections each client address has open at once.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// drainFIFO and drainLIFO the orders connections are closed in when a drain times out.
+const drainFIFO = "fifo"
+const drainLIFO = "lifo"
+
+// closeReasonShutdown the CloseReason of a connection closed by the server shutting down.
+const closeReasonShutdown = "shutdown"
+
+// topClients the number of clients listed by their peak connections in the report.
+const topClients = 10
+
+var errClosedByShutdown = errors.New("connection closed by server shutdown")
+
+// connRegistry the connections currently being served, oldest first.
+type connRegistry struct {
+	mutex       sync.Mutex
+	connections *list.List
+	clientPeaks map[string]*clientConcurrency // client addresses with connections open
+	clientLive  map[string]int                // connections open from each client address
+	finished    []clientConcurrency           // the top clients with no connections open, highest first
+	uniqueSeen  int                           // client addresses seen since the last reset
+}
+
+// clientConcurrency the connections made from a client address.
+type clientConcurrency struct {
+	RemoteIP        string // the client's address without its port
+	Connections     int    // connections accepted from the address
+	PeakConnections int    // the most connections the address had open at once
+}
+
+// registeredConn a connection in the registry. The shutdown marks the
+// connection before closing it, so a read or write that fails because of the
+// close returns errClosedByShutdown rather than "use of closed connection".
+type registeredConn struct {
+	net.Conn
+	entry    *list.Element
+	remoteIP string // the client's address, see remoteIP
+	shutdown int32  // set to 1 before the shutdown closes the connection
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newConnRegistry
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newConnRegistry() *connRegistry
+--
+-- RETURNS:     *connRegistry an empty registry.
+------------------------------------------------------------------------------*/
+func newConnRegistry() *connRegistry {
+	return &connRegistry{connections: list.New(), clientPeaks: make(map[string]*clientConcurrency),
+		clientLive: make(map[string]int)}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    add
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - counts the connections open from each client
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) add(conn net.Conn) *registeredConn
+--      conn:		a connection a worker has just accepted.
+--
+-- RETURNS:     *registeredConn the connection to serve the client on, to be
+--              passed to remove once the worker is finished with it.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) add(conn net.Conn) *registeredConn {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registered := &registeredConn{Conn: conn, remoteIP: remoteIP(conn.RemoteAddr())}
+	registered.entry = registry.connections.PushBack(registered)
+
+	client, ok := registry.clientPeaks[registered.remoteIP]
+	if !ok {
+		client = &clientConcurrency{RemoteIP: registered.remoteIP}
+		registry.clientPeaks[registered.remoteIP] = client
+		if !slices.ContainsFunc(registry.finished, func(finished clientConcurrency) bool {
+			return finished.RemoteIP == client.RemoteIP
+		}) {
+			registry.uniqueSeen++
+		}
+	}
+	registry.clientLive[registered.remoteIP]++
+	client.Connections++
+	if live := registry.clientLive[registered.remoteIP]; live > client.PeakConnections {
+		client.PeakConnections = live
+	}
+
+	return registered
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    remove
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - counts the connections open from each client
+--              October 15, 2026 - stops tracking a client once its last
+--                connection closes
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) remove(conn *registeredConn)
+--      conn:		the connection returned by add.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) remove(conn *registeredConn) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.connections.Remove(conn.entry)
+	if registry.clientLive[conn.remoteIP]--; registry.clientLive[conn.remoteIP] == 0 {
+		delete(registry.clientLive, conn.remoteIP)
+		registry.retireClient(*registry.clientPeaks[conn.remoteIP])
+		delete(registry.clientPeaks, conn.remoteIP)
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    len
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) len() int
+--
+-- RETURNS:     int the number of connections being served.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) len() int {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	return registry.connections.Len()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    closeAll
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the order connections are closed in is chosen
+--              October 15, 2026 - connections are marked as closed by the
+--                shutdown
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) closeAll(newestFirst bool)
+-- newestFirst:	close the most recently accepted connections first instead of
+--						the oldest.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Closes every connection being served. The workers see
+--						errClosedByShutdown and remove the connection themselves.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) closeAll(newestFirst bool) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if newestFirst {
+		for e := registry.connections.Back(); e != nil; e = e.Prev() {
+			e.Value.(*registeredConn).shutdownClose()
+		}
+		return
+	}
+	for e := registry.connections.Front(); e != nil; e = e.Next() {
+		e.Value.(*registeredConn).shutdownClose()
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    clients
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - merges the clients with connections open
+--                into the top clients that have none
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) clients(top int) (int, []clientConcurrency)
+--       top:		the most clients to list, no more than topClients.
+--
+-- RETURNS:     int the number of distinct client addresses seen since the
+--              last reset.
+--              []clientConcurrency the clients with the highest peak
+--              connections, highest first.
+--
+-- NOTES:			A client that comes back after all its connections closed and
+--						it fell out of the top clients is counted again.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) clients(top int) (int, []clientConcurrency) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	clients := slices.Clone(registry.finished)
+	for _, client := range registry.clientPeaks {
+		i := slices.IndexFunc(clients, func(finished clientConcurrency) bool {
+			return finished.RemoteIP == client.RemoteIP
+		})
+		if i < 0 {
+			clients = append(clients, *client)
+			continue
+		}
+		clients[i].Connections += client.Connections
+		clients[i].PeakConnections = max(clients[i].PeakConnections, client.PeakConnections)
+	}
+	sortClients(clients)
+
+	return registry.uniqueSeen, clients[:min(top, len(clients))]
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    resetClients
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) resetClients()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called with the observer's statistics reset so the next report
+--						only covers the clients seen after it. The connections still
+--						open carry over as their client's connections and peak.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) resetClients() {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.finished = nil
+	registry.uniqueSeen = len(registry.clientPeaks)
+	for remoteIP, client := range registry.clientPeaks {
+		client.Connections = registry.clientLive[remoteIP]
+		client.PeakConnections = registry.clientLive[remoteIP]
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    retireClient
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (registry *connRegistry) retireClient(client clientConcurrency)
+--    client:		a client whose last connection has closed.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Must be called with the registry locked. Only the topClients
+--						highest peaks are kept, so the clients no longer connected
+--						don't grow the registry without bound.
+------------------------------------------------------------------------------*/
+func (registry *connRegistry) retireClient(client clientConcurrency) {
+	i := slices.IndexFunc(registry.finished, func(finished clientConcurrency) bool {
+		return finished.RemoteIP == client.RemoteIP
+	})
+	if i >= 0 {
+		client.Connections += registry.finished[i].Connections
+		client.PeakConnections = max(client.PeakConnections, registry.finished[i].PeakConnections)
+		registry.finished = slices.Delete(registry.finished, i, i+1)
+	}
+	registry.finished = append(registry.finished, client)
+	sortClients(registry.finished)
+	registry.finished = registry.finished[:min(topClients, len(registry.finished))]
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    sortClients
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func sortClients(clients []clientConcurrency)
+--   clients:		the clients to sort.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Highest peak connections first, then the most connections,
+--						then by address so the order is stable.
+------------------------------------------------------------------------------*/
+func sortClients(clients []clientConcurrency) {
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].PeakConnections != clients[j].PeakConnections {
+			return clients[i].PeakConnections > clients[j].PeakConnections
+		}
+		if clients[i].Connections != clients[j].Connections {
+			return clients[i].Connections > clients[j].Connections
+		}
+		return clients[i].RemoteIP < clients[j].RemoteIP
+	})
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    remoteIP
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func remoteIP(addr net.Addr) string
+--      addr:		the address of a client.
+--
+-- RETURNS:     string the address without its port, the whole address if it
+--              doesn't have one.
+------------------------------------------------------------------------------*/
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Read
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *registeredConn) Read(b []byte) (int, error)
+--         b:		the buffer to read into.
+--
+-- RETURNS:     int the number of bytes read.
+--              error any error reading, errClosedByShutdown if the shutdown
+--              closed the connection.
+------------------------------------------------------------------------------*/
+func (conn *registeredConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	if err != nil && atomic.LoadInt32(&conn.shutdown) == 1 {
+		err = errClosedByShutdown
+	}
+
+	return n, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Write
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *registeredConn) Write(b []byte) (int, error)
+--         b:		the bytes to write.
+--
+-- RETURNS:     int the number of bytes written.
+--              error any error writing, errClosedByShutdown if the shutdown
+--              closed the connection.
+--
+-- NOTES:			The write isn't held under a lock, a write blocked on a slow
+--						client must not stop the shutdown from closing it.
+------------------------------------------------------------------------------*/
+func (conn *registeredConn) Write(b []byte) (int, error) {
+	n, err := conn.Conn.Write(b)
+	if err != nil && atomic.LoadInt32(&conn.shutdown) == 1 {
+		err = errClosedByShutdown
+	}
+
+	return n, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    NetConn
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *registeredConn) NetConn() net.Conn
+--
+-- RETURNS:     net.Conn the connection accepted from the listener.
+--
+-- NOTES:			Matches tls.Conn so socket options can be read from the
+--						underlying connection.
+------------------------------------------------------------------------------*/
+func (conn *registeredConn) NetConn() net.Conn {
+	return conn.Conn
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    shutdownClose
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (conn *registeredConn) shutdownClose()
+--
+-- RETURNS:     void
+--
+-- NOTES:			The connection is marked before it is closed so that any read
+--						or write failing because of the close sees the mark.
+------------------------------------------------------------------------------*/
+func (conn *registeredConn) shutdownClose() {
+	atomic.StoreInt32(&conn.shutdown, 1)
+	conn.Conn.Close()
+}
@@ -0,0 +1,232 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 registry_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func (conn addrConn) RemoteAddr() net.Addr
+--  func clientConn(ip string, port int) net.Conn
+--  func TestRegistryUniqueClientsAndPeaks(t *testing.T)
+--  func TestRegistryEvictsClosedClients(t *testing.T)
+--  func TestRegistryReturningClient(t *testing.T)
+--  func TestRegistryResetClients(t *testing.T)
+--  func (conn closeRecorder) Close() error
+--  func TestRegistryCloseAllOrder(t *testing.T)
+--  func (conn writeSignal) Write(b []byte) (int, error)
+--  func TestShutdownMidWrite(t *testing.T)
+--
+-- NOTES: Tests for the registry's per client counts and the order it closes
+--        connections in. The connections are never read or written, only
+--        their remote address is used, apart from the one closed by the
+--        shutdown while it is being written.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// addrConn a connection from a chosen remote address.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// RemoteAddr the chosen remote address.
+func (conn addrConn) RemoteAddr() net.Addr {
+	return conn.remote
+}
+
+// clientConn a connection from ip and port.
+func clientConn(ip string, port int) net.Conn {
+	return addrConn{remote: tcpAddr(ip, port)}
+}
+
+// TestRegistryUniqueClientsAndPeaks checks that connections from several
+// addresses are counted once per address, each with its peak concurrency.
+func TestRegistryUniqueClientsAndPeaks(t *testing.T) {
+	registry := newConnRegistry()
+	var open []*registeredConn
+	for i, connections := range []int{3, 2, 1} {
+		for port := 0; port < connections; port++ {
+			open = append(open, registry.add(clientConn(fmt.Sprintf("192.0.2.%d", i+1), 40000+port)))
+		}
+	}
+	registry.remove(open[0])
+	registry.add(clientConn("192.0.2.1", 40010)) // back to the same peak
+
+	unique, clients := registry.clients(topClients)
+	if unique != 3 || len(clients) != 3 {
+		t.Fatalf("%d unique clients listed as %+v, want 3", unique, clients)
+	}
+	for i, want := range []clientConcurrency{{RemoteIP: "192.0.2.1", Connections: 4, PeakConnections: 3},
+		{RemoteIP: "192.0.2.2", Connections: 2, PeakConnections: 2},
+		{RemoteIP: "192.0.2.3", Connections: 1, PeakConnections: 1}} {
+		if clients[i] != want {
+			t.Errorf("top client %d was %+v, want %+v", i, clients[i], want)
+		}
+	}
+}
+
+// TestRegistryEvictsClosedClients checks that clients are no longer tracked
+// once their connections close, while the report still counts them and lists
+// the top ones.
+func TestRegistryEvictsClosedClients(t *testing.T) {
+	const clientCount = 3 * topClients
+	registry := newConnRegistry()
+	for i := 0; i < clientCount; i++ {
+		ip := fmt.Sprintf("192.0.2.%d", i+1)
+		var open []*registeredConn
+		for port := 0; port <= i%4; port++ {
+			open = append(open, registry.add(clientConn(ip, 40000+port)))
+		}
+		for _, conn := range open {
+			registry.remove(conn)
+		}
+	}
+
+	if len(registry.clientPeaks) != 0 || len(registry.clientLive) != 0 {
+		t.Errorf("%d clients still tracked with no connections open", len(registry.clientPeaks))
+	}
+	if len(registry.finished) > topClients {
+		t.Errorf("%d finished clients kept, want at most %d", len(registry.finished), topClients)
+	}
+	unique, clients := registry.clients(topClients)
+	if unique != clientCount {
+		t.Errorf("%d unique clients, want %d", unique, clientCount)
+	}
+	if len(clients) != topClients {
+		t.Fatalf("%d top clients, want %d", len(clients), topClients)
+	}
+	for i, client := range clients {
+		if want := 4 - i/(clientCount/4); client.PeakConnections != want {
+			t.Errorf("top client %d had a peak of %d, want %d", i, client.PeakConnections, want)
+		}
+	}
+}
+
+// TestRegistryReturningClient checks that a client that comes back after its
+// connections closed is counted once, with its connections added together.
+func TestRegistryReturningClient(t *testing.T) {
+	registry := newConnRegistry()
+	first := registry.add(clientConn("192.0.2.1", 40000))
+	second := registry.add(clientConn("192.0.2.1", 40001))
+	registry.remove(first)
+	registry.remove(second)
+	registry.add(clientConn("192.0.2.1", 40002))
+
+	unique, clients := registry.clients(topClients)
+	if unique != 1 || len(clients) != 1 {
+		t.Fatalf("%d unique clients listed as %+v, want 1", unique, clients)
+	}
+	if clients[0].Connections != 3 || clients[0].PeakConnections != 2 {
+		t.Errorf("client had %d connections and a peak of %d, want 3 and 2",
+			clients[0].Connections, clients[0].PeakConnections)
+	}
+}
+
+// TestRegistryResetClients checks that a reset forgets the clients that have
+// gone and keeps the connections still open.
+func TestRegistryResetClients(t *testing.T) {
+	registry := newConnRegistry()
+	kept := registry.add(clientConn("192.0.2.1", 40000))
+	registry.remove(registry.add(clientConn("192.0.2.1", 40001)))
+	registry.remove(registry.add(clientConn("192.0.2.2", 40000)))
+
+	registry.resetClients()
+	unique, clients := registry.clients(topClients)
+	if unique != 1 || len(clients) != 1 || clients[0].RemoteIP != "192.0.2.1" {
+		t.Fatalf("%d unique clients listed as %+v after the reset, want 192.0.2.1", unique, clients)
+	}
+	if clients[0].Connections != 1 || clients[0].PeakConnections != 1 {
+		t.Errorf("client had %d connections and a peak of %d after the reset, want 1 and 1",
+			clients[0].Connections, clients[0].PeakConnections)
+	}
+
+	registry.remove(kept)
+	if unique, _ := registry.clients(topClients); unique != 1 {
+		t.Errorf("%d unique clients once the last connection closed, want 1", unique)
+	}
+}
+
+// closeRecorder a connection that records its remote address when closed.
+type closeRecorder struct {
+	addrConn
+	closed *[]string
+}
+
+// Close records the connection as closed.
+func (conn closeRecorder) Close() error {
+	*conn.closed = append(*conn.closed, conn.remote.String())
+
+	return nil
+}
+
+// TestRegistryCloseAllOrder checks that a drain that times out closes the
+// oldest connections first under fifo and the newest first under lifo.
+func TestRegistryCloseAllOrder(t *testing.T) {
+	for _, test := range []struct {
+		order string
+		want  string
+	}{
+		{drainFIFO, "192.0.2.1:40000 192.0.2.2:40000 192.0.2.3:40000"},
+		{drainLIFO, "192.0.2.3:40000 192.0.2.2:40000 192.0.2.1:40000"},
+	} {
+		var closed []string
+		registry := newConnRegistry()
+		for i := 1; i <= 3; i++ {
+			registry.add(closeRecorder{addrConn{remote: tcpAddr(fmt.Sprintf("192.0.2.%d", i), 40000)}, &closed})
+		}
+		registry.closeAll(test.order == drainLIFO)
+		if got := strings.Join(closed, " "); got != test.want {
+			t.Errorf("%s closed %s, want %s", test.order, got, test.want)
+		}
+	}
+}
+
+// writeSignal a connection that signals each time a write starts.
+type writeSignal struct {
+	net.Conn
+	writing chan bool
+}
+
+// Write signals that a write has started, then writes b.
+func (conn writeSignal) Write(b []byte) (int, error) {
+	conn.writing <- true
+
+	return conn.Conn.Write(b)
+}
+
+// TestShutdownMidWrite checks that a connection closed by the shutdown while
+// its echo is being written is reported as closed by the shutdown rather than
+// as an error.
+func TestShutdownMidWrite(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	server, client := net.Pipe()
+	defer client.Close()
+	writing := make(chan bool, 1)
+	registered := srvInfo.connections.add(writeSignal{server, writing})
+	served := make(chan connectionInfo)
+	go func() {
+		served <- connectionInstance(context.Background(), registered, srvInfo, 1)
+	}()
+
+	client.Write([]byte("never read\n")) // the echo blocks until the client reads
+	<-writing
+	srvInfo.connections.closeAll(false)
+
+	if connInfo := <-served; connInfo.CloseReason != closeReasonShutdown {
+		t.Errorf("closed with %q, want %q", connInfo.CloseReason, closeReasonShutdown)
+	}
+}
@@ -4,6 +4,16 @@
 -- Source File:	 report.go
 --
 -- REVISIONS: 	February 13, 2016 - Generalised reporting functionality
+--              October 15, 2026 - Added the worker affinity and summary sheets
+--              October 15, 2026 - Added the throughput sheet
+--              October 15, 2026 - Reports are written by a ReportFormatter
+--              October 15, 2026 - Reports can be gzipped
+--              October 15, 2026 - Connections are streamed to the formatters
+--              October 15, 2026 - Added the label groups sheet
+--              October 15, 2026 - Added the top clients sheet
+--              October 15, 2026 - Added the connection lifetimes sheet
+--              October 15, 2026 - The summary can be written to a file descriptor
+--              October 15, 2026 - The report can be written to a chosen file or stdout
 --
 -- DESIGNER:	   Marc Vouve
 --
@@ -11,19 +21,38 @@
 --
 --
 -- INTERFACE:
---	func generateReport(fname string, elements *list.List)
+--	func writeReportFile(path string, fname string, elements *list.List, summary reportSummary, format string, compress bool)
+--  func generateReport(w io.Writer, elements *list.List, summary reportSummary, format string, compress bool) error
+--  func (xlsxFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
 --  func generateHeaders(i interface{}, row *xlsx.Row)
 --  func generateRow(i interface{}, row *xlsx.Row)
+--  func generateWorkerAffinity(elements *list.List, report *xlsx.Sheet)
+--  func generateSummary(i interface{}, report *xlsx.Sheet)
+--  func generateThroughput(samples []throughputSample, report *xlsx.Sheet)
+--  func throughputIntervals(samples []throughputSample) []throughputInterval
+--  func generateTable(rows interface{}, report *xlsx.Sheet)
+--  func openSummaryFD(fd int) *os.File
+--  func writeExitSummary(file *os.File, summary reportSummary, exitCode int)
 --
 --
--- NOTES: This file generates reports in xlsx format from a list.List of interfaces
+-- NOTES: This file generates the report written when the server exits. The
+--        format is picked from the registered ReportFormatters, xlsx unless
+--        configured otherwise.
 ------------------------------------------------------------------------------*/
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
 	"container/list"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"os"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/tealeg/xlsx"
 )
@@ -31,42 +60,213 @@ import (
 // ExcelMaxRows NAXIMUM ALLOWED ROWS BY EXCEL. https://support.office.com/en-us/article/Excel-specifications-and-limits-1672b34d-7043-467e-8e27-269d656771c3
 const ExcelMaxRows = 1048576
 
+type throughputInterval struct {
+	Start          time.Time // the start of the interval
+	End            time.Time // the end of the interval
+	Bytes          int64     // the bytes transferred during the interval
+	BytesPerSecond float64   // the average throughput over the interval
+}
+
+// xlsxFormatter writes the report as a spreadsheet.
+type xlsxFormatter struct{}
+
+type workerAffinity struct {
+	WorkerID    int // the worker the connections were accepted by
+	Connections int // the connections accepted by the worker
+	Requests    int // the requests handled on those connections
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeReportFile
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func writeReportFile(path string, fname string, elements *list.List, summary reportSummary, format string, compress bool)
+--      path:   The -report-file, empty to name the file after fname, "-"
+--              for stdout
+--     fname:   Name of the file, without its extension
+--  elements:   A list of connectionInfo to be reported
+--   summary:   The totals across every connection
+--    format:   The name the formatter was registered under
+--  compress:   gzip the report, adding .gz to the file name
+--
+-- RETURNS: 		void
+--
+-- NOTES:			Errors writing the report are logged, the server still exits.
+--						A file at path is replaced, so with -report-interval it only
+--						holds the latest report.
+------------------------------------------------------------------------------*/
+func writeReportFile(path string, fname string, elements *list.List, summary reportSummary, format string, compress bool) {
+	registered, ok := reportFormatters[format]
+	if !ok {
+		log.Println("Unknown report format:", format)
+		return
+	}
+	if path == "" {
+		path = fname + "." + registered.extension
+		if compress {
+			path += ".gz"
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			log.Println("Unable to write report:", err)
+			return
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Println("Unable to write report:", err)
+			}
+		}()
+		w = file
+	}
+	if err := generateReport(w, elements, summary, format, compress); err != nil {
+		log.Println("Unable to write report:", err)
+	}
+}
+
 /*-----------------------------------------------------------------------------
 -- FUNCTION:    generateReport
 --
 -- DATE:        February 6, 2016
 --
 -- REVISIONS:	  February 13, 2016 generalised for any list of interface{}s
+--              October 15, 2026 added the worker affinity and summary sheets
+--              October 15, 2026 added the throughput sheet
+--              October 15, 2026 written by the configured ReportFormatter
+--              October 15, 2026 optionally gzipped
+--              October 15, 2026 connections are no longer copied out of the list
+--              October 15, 2026 writes to any io.Writer, the file is opened by
+--                writeReportFile
 --
 -- DESIGNER:		Marc Vouve
 --
 -- PROGRAMMER:	Marc Vouve
 --
--- INTERFACE:		func generateReport(fname string, connections *list.List)
---     fname:   Name of the file
---  elements:   A list of structures to be reported
+-- INTERFACE:		func generateReport(w io.Writer, elements *list.List, summary reportSummary, format string, compress bool) error
+--         w:   Where the report is written
+--  elements:   A list of connectionInfo to be reported
+--   summary:   The totals across every connection
+--    format:   The name the formatter was registered under
+--  compress:   gzip the report
 --
--- RETURNS: 		void
+-- RETURNS: 		error the first error writing the report.
+--
+-- NOTES:			The formatters are given the list itself so that they can
+--						write one connection at a time rather than holding a copy of
+--						every connection.
+------------------------------------------------------------------------------*/
+func generateReport(w io.Writer, elements *list.List, summary reportSummary, format string, compress bool) error {
+	registered, ok := reportFormatters[format]
+	if !ok {
+		return errors.New("unknown report format " + format)
+	}
+
+	var zipper *gzip.Writer
+	if compress {
+		zipper = gzip.NewWriter(w)
+		w = zipper
+	}
+	buffered := bufio.NewWriter(w)
+	err := registered.formatter.Format(buffered, elements, summary)
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if zipper != nil {
+		if closeErr := zipper.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Format
+--
+-- DATE:        February 6, 2016
+--
+-- REVISIONS:	  February 13, 2016 generalised for any list of interface{}s
+--              October 15, 2026 added the worker affinity and summary sheets
+--              October 15, 2026 added the throughput sheet
+--              October 15, 2026 moved from generateReport into a ReportFormatter
+--              October 15, 2026 reads the connections from the list
+--              October 15, 2026 added the label groups sheet
+--              October 15, 2026 added the top clients sheet
+--              October 15, 2026 added the connection lifetimes sheet
+--              October 15, 2026 added the TLS sheet
+--              October 15, 2026 added the response ratios sheet
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func (xlsxFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error
+--         w:   where the report is written
+-- connections: A list of connectionInfo to be reported
+--   summary:   The totals written to the "Summary" sheet
+--
+-- RETURNS: 		error any error writing the report
 --
 -- NOTES:			This function will only generate a report of up to ExcelMaxRows rows
 --            They will all be on "Sheet 1" and a warning will be logged if more
---            data is present.
+--            data is present. The summary is written even if there are no
+--            connections since they may have been pruned. Unlike the other
+--            formats the whole spreadsheet is built in memory before it is
+--            written, xlsx can't be streamed.
 ------------------------------------------------------------------------------*/
-func generateReport(fname string, elements *list.List) {
+func (xlsxFormatter) Format(w io.Writer, connections *list.List, summary reportSummary) error {
 	doc := xlsx.NewFile()
-	if elements.Len() <= 0 {
-		return
-	}
-	report, _ := doc.AddSheet("Sheet 1") // TODO: make this more generalised?
-	generateHeaders(elements.Front().Value, report.AddRow())
-	for e := elements.Front(); e != nil; e = e.Next() {
-		generateRow(e.Value, report.AddRow())
-		if report.MaxRow >= ExcelMaxRows {
-			log.Println("Too many entries for report, stopping at ", report.MaxRow)
-			break
+	if connections.Len() > 0 {
+		report, _ := doc.AddSheet("Sheet 1") // TODO: make this more generalised?
+		generateHeaders(connections.Front().Value, report.AddRow())
+		for e := connections.Front(); e != nil; e = e.Next() {
+			generateRow(e.Value, report.AddRow())
+			if report.MaxRow >= ExcelMaxRows {
+				log.Println("Too many entries for report, stopping at ", report.MaxRow)
+				break
+			}
 		}
+		affinity, _ := doc.AddSheet("Worker Affinity")
+		generateWorkerAffinity(connections, affinity)
+	}
+	totals, _ := doc.AddSheet("Summary")
+	generateSummary(summary, totals)
+	if len(summary.ThroughputSamples) > 1 {
+		throughput, _ := doc.AddSheet("Throughput")
+		generateThroughput(summary.ThroughputSamples, throughput)
+	}
+	if len(summary.LifetimeHistogram) > 0 {
+		lifetimes, _ := doc.AddSheet("Connection Lifetimes")
+		generateTable(summary.LifetimeHistogram, lifetimes)
+	}
+	if len(summary.ResponseRatios) > 0 {
+		ratios, _ := doc.AddSheet("Response Ratios")
+		generateTable(summary.ResponseRatios, ratios)
+	}
+	if len(summary.TLSDistribution) > 0 {
+		negotiated, _ := doc.AddSheet("TLS")
+		generateTable(summary.TLSDistribution, negotiated)
 	}
-	doc.Save(fname + ".xlsx")
+	if len(summary.TopClients) > 0 {
+		clients, _ := doc.AddSheet("Top Clients")
+		generateTable(summary.TopClients, clients)
+	}
+	if len(summary.LabelGroups) > 0 {
+		groups, _ := doc.AddSheet("Label Groups")
+		generateTable(summary.LabelGroups, groups)
+	}
+
+	return doc.Write(w)
 }
 
 /*-----------------------------------------------------------------------------
@@ -124,3 +324,235 @@ func generateRow(i interface{}, row *xlsx.Row) {
 		cell.SetValue(fields.Field(i).Interface())
 	}
 }
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    generateWorkerAffinity
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:	 (DATE AND INFO)
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func generateWorkerAffinity(elements *list.List, report *xlsx.Sheet)
+--  elements:   A list of connectionInfo to be summarised
+--    report:   the sheet to write the summary to
+--
+-- RETURNS: 		void
+--
+-- NOTES:			Writes a row per worker with the connections and requests it
+--            handled. A connection is served from start to finish by
+--            connectionInstance on the go routine of the worker that
+--            accepted it, so each of its requests is handled by WorkerID.
+------------------------------------------------------------------------------*/
+func generateWorkerAffinity(elements *list.List, report *xlsx.Sheet) {
+	workers := make(map[int]*workerAffinity)
+	var ids []int
+	for e := elements.Front(); e != nil; e = e.Next() {
+		connInfo := e.Value.(connectionInfo)
+		worker, ok := workers[connInfo.WorkerID]
+		if !ok {
+			worker = &workerAffinity{WorkerID: connInfo.WorkerID}
+			workers[connInfo.WorkerID] = worker
+			ids = append(ids, connInfo.WorkerID)
+		}
+		worker.Connections++
+		worker.Requests += connInfo.NumberOfRequests
+	}
+
+	sort.Ints(ids)
+	generateHeaders(workerAffinity{}, report.AddRow())
+	for _, id := range ids {
+		generateRow(*workers[id], report.AddRow())
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    generateSummary
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:	 (DATE AND INFO)
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func generateSummary(i interface{}, report *xlsx.Sheet)
+--         i:   the structure to be summarised.
+--    report:   the sheet to write the summary to.
+--
+-- RETURNS: 		void
+--
+-- NOTES:			Unlike generateRow this writes one row per field, with the name
+--            of the field followed by its value. Slices are skipped, they are
+--            given sheets of their own.
+------------------------------------------------------------------------------*/
+func generateSummary(i interface{}, report *xlsx.Sheet) {
+	fields := reflect.ValueOf(i)
+	for i := 0; i < fields.NumField(); i++ {
+		if fields.Field(i).Kind() == reflect.Slice {
+			continue
+		}
+		row := report.AddRow()
+		row.AddCell().SetString(fields.Type().Field(i).Name)
+		row.AddCell().SetValue(fields.Field(i).Interface())
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    generateThroughput
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:	 October 15, 2026 the intervals are worked out by throughputIntervals
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func generateThroughput(samples []throughputSample, report *xlsx.Sheet)
+--   samples:   the running byte totals sampled by the observer.
+--    report:   the sheet to write the throughput to.
+--
+-- RETURNS: 		void
+--
+-- NOTES:			Writes a row for the throughput between each pair of samples.
+------------------------------------------------------------------------------*/
+func generateThroughput(samples []throughputSample, report *xlsx.Sheet) {
+	generateHeaders(throughputInterval{}, report.AddRow())
+	for _, interval := range throughputIntervals(samples) {
+		generateRow(interval, report.AddRow())
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    throughputIntervals
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:	 (DATE AND INFO)
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func throughputIntervals(samples []throughputSample) []throughputInterval
+--   samples:   the running byte totals sampled by the observer.
+--
+-- RETURNS: 		[]throughputInterval the throughput between each pair of samples.
+------------------------------------------------------------------------------*/
+func throughputIntervals(samples []throughputSample) []throughputInterval {
+	var intervals []throughputInterval
+	for i := 1; i < len(samples); i++ {
+		interval := throughputInterval{Start: samples[i-1].Time, End: samples[i].Time,
+			Bytes: samples[i].Bytes - samples[i-1].Bytes}
+		if seconds := interval.End.Sub(interval.Start).Seconds(); seconds > 0 {
+			interval.BytesPerSecond = float64(interval.Bytes) / seconds
+		}
+		intervals = append(intervals, interval)
+	}
+
+	return intervals
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    generateTable
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:	 (DATE AND INFO)
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:		func generateTable(rows interface{}, report *xlsx.Sheet)
+--      rows:   a non-empty slice of structures to list.
+--    report:   the sheet to write the rows to.
+--
+-- RETURNS: 		void
+------------------------------------------------------------------------------*/
+func generateTable(rows interface{}, report *xlsx.Sheet) {
+	slice := reflect.ValueOf(rows)
+	generateHeaders(slice.Index(0).Interface(), report.AddRow())
+	for i := 0; i < slice.Len(); i++ {
+		generateRow(slice.Index(i).Interface(), report.AddRow())
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    openSummaryFD
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func openSummaryFD(fd int) *os.File
+--        fd:		a file descriptor inherited from the parent process.
+--
+-- RETURNS:     *os.File the descriptor as a file, nil if fd is 0.
+--
+-- NOTES:			Exits the program if the descriptor isn't open so a harness
+--						that passed the wrong one finds out before the run rather
+--						than after it.
+------------------------------------------------------------------------------*/
+func openSummaryFD(fd int) *os.File {
+	if fd == 0 {
+		return nil
+	}
+
+	file := os.NewFile(uintptr(fd), "summary")
+	if _, err := file.Stat(); err != nil {
+		log.Fatalln("Unable to use -summary-fd:", err)
+	}
+
+	return file
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeExitSummary
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeExitSummary(file *os.File, summary reportSummary, exitCode int)
+--      file:		the file returned by openSummaryFD.
+--   summary:		the summary written to the report.
+--  exitCode:		the status the process is about to exit with.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing if file is nil. Writes one line of JSON holding
+--						the exit status and the summary, durations in nanoseconds as
+--						in the JSON report, then closes the file so a parent reading
+--						a pipe sees the end of it.
+------------------------------------------------------------------------------*/
+func writeExitSummary(file *os.File, summary reportSummary, exitCode int) {
+	if file == nil {
+		return
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(struct {
+		ExitCode int
+		Summary  reportSummary
+	}{exitCode, summary})
+	if err == nil {
+		_, err = file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		log.Println("Unable to write the exit summary:", err)
+	}
+}
@@ -0,0 +1,96 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 report_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func readJSONReport(t *testing.T, path string) []connectionInfo
+--  func TestWriteReportFile(t *testing.T)
+--  func TestWriteReportFileUnwritable(t *testing.T)
+--
+-- NOTES: Tests for where the report is written. Reports are written to the
+--        test's temporary directory, which the test runs in.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"compress/gzip"
+	"container/list"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readJSONReport the connections listed in the JSON report at path, which is
+// gunzipped if its name ends in .gz.
+func readJSONReport(t *testing.T, path string) []connectionInfo {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal("the report wasn't written:", err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		if r, err = gzip.NewReader(file); err != nil {
+			t.Fatal("the report isn't gzipped:", err)
+		}
+	}
+
+	var report struct{ Connections []connectionInfo }
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		t.Fatalf("%s isn't a JSON report: %v", path, err)
+	}
+
+	return report.Connections
+}
+
+// TestWriteReportFile checks that the report is named after fname, with .gz
+// when it is compressed, unless -report-file gives a path, and that a report
+// written to the same path again replaces the last one.
+func TestWriteReportFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	connections := list.New()
+	connections.PushBack(connectionInfo{HostName: "192.0.2.1:40000"})
+	connections.PushBack(connectionInfo{HostName: "192.0.2.2:40000"})
+
+	writeReportFile("", "report", connections, reportSummary{}, "json", false)
+	writeReportFile("", "report", connections, reportSummary{}, "json", true)
+	for _, path := range []string{"report.json", "report.json.gz"} {
+		if listed := readJSONReport(t, path); len(listed) != 2 {
+			t.Errorf("%s listed %d connections, want 2", path, len(listed))
+		}
+	}
+
+	writeReportFile("custom.out", "report", connections, reportSummary{}, "json", false)
+	connections.Remove(connections.Back())
+	writeReportFile("custom.out", "report", connections, reportSummary{}, "json", false)
+	if listed := readJSONReport(t, "custom.out"); len(listed) != 1 || listed[0].HostName != "192.0.2.1:40000" {
+		t.Errorf("custom.out listed %+v, want only the last report's connection", listed)
+	}
+}
+
+// TestWriteReportFileUnwritable checks that a report that can't be written is
+// logged rather than stopping the server.
+func TestWriteReportFileUnwritable(t *testing.T) {
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	path := filepath.Join(t.TempDir(), "missing", "report.json")
+	writeReportFile(path, "report", list.New(), reportSummary{}, "json", false)
+	if !strings.Contains(logged.String(), "Unable to write report") {
+		t.Errorf("the failure wasn't logged, logged %q", logged.String())
+	}
+}
@@ -0,0 +1,271 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 response.go
+--
+-- REVISIONS: 	October 15, 2026 - Added ACK and NACK responses
+--              October 15, 2026 - Added server nonces
+--              October 15, 2026 - Added the debug tee
+--              October 15, 2026 - Added the echo self-check
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func buildResponse(data []byte, cfg *serverConfig) []byte
+--  func splitDelimiter(data []byte, cfg *serverConfig) ([]byte, []byte)
+--  func ackResponse(index int, cfg *serverConfig) []byte
+--  func nackResponse(err error, index int, cfg *serverConfig) []byte
+--  func appendNonce(response []byte, now time.Time, sequence int64, cfg *serverConfig) []byte
+--  func teeEcho(w io.Writer, host string, payload []byte, limit int)
+--  func echoMatches(received []byte, response []byte, cfg *serverConfig) bool
+--
+-- NOTES: This file builds the payload echoed back for a request, or the
+--        acknowledgement sent instead of the echo in ack mode.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    buildResponse
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func buildResponse(data []byte, cfg *serverConfig) []byte
+--      data:		the payload of the request.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the payload to echo back.
+--
+-- NOTES:			The echo prefix and suffix are placed inside the line delimiter
+--						so the client can still split responses on it.
+------------------------------------------------------------------------------*/
+func buildResponse(data []byte, cfg *serverConfig) []byte {
+	if cfg.echoPrefix == "" && cfg.echoSuffix == "" {
+		return data
+	}
+
+	body, delimiter := splitDelimiter(data, cfg)
+	var response bytes.Buffer
+	response.WriteString(cfg.echoPrefix)
+	response.Write(body)
+	response.WriteString(cfg.echoSuffix)
+	response.Write(delimiter)
+
+	return response.Bytes()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    splitDelimiter
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - splits on -delimiter
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func splitDelimiter(data []byte, cfg *serverConfig) ([]byte, []byte)
+--      data:		the payload of the request.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the request without its delimiter.
+--              []byte the delimiter, empty if the framing doesn't use one.
+------------------------------------------------------------------------------*/
+func splitDelimiter(data []byte, cfg *serverConfig) ([]byte, []byte) {
+	if cfg.framing == framingLine && len(data) > 0 && data[len(data)-1] == cfg.delimiter {
+		return data[:len(data)-1], data[len(data)-1:]
+	}
+
+	return data, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    ackResponse
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - ended with -delimiter
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func ackResponse(index int, cfg *serverConfig) []byte
+--     index:		the number of the request on its connection, starting at 1.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the payload sent instead of the echo in ack mode.
+------------------------------------------------------------------------------*/
+func ackResponse(index int, cfg *serverConfig) []byte {
+	return append([]byte(fmt.Sprintf("ACK %d", index)), cfg.delimiter)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    nackResponse
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - undersized requests get the -min-request-reply
+--              October 15, 2026 - ended with -delimiter
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func nackResponse(err error, index int, cfg *serverConfig) []byte
+--       err:		the error reading the request.
+--     index:		the number the request would have had on its connection.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the framed NACK, or nil if the error doesn't get one.
+--
+-- NOTES:			Only requests over the size limit are NACKed, and only in ack
+--						mode. The rest of the request is never read so the connection
+--						is closed after the NACK is sent. Requests under the minimum
+--						size are answered with -min-request-reply if it is set.
+------------------------------------------------------------------------------*/
+func nackResponse(err error, index int, cfg *serverConfig) []byte {
+	if err == errRequestTooSmall && cfg.minRequestReply != "" {
+		return frameResponse(append([]byte(cfg.minRequestReply), cfg.delimiter), cfg)
+	}
+	if !cfg.ack || err != errRequestTooLarge {
+		return nil
+	}
+
+	return frameResponse(append([]byte(fmt.Sprintf("NACK %d", index)), cfg.delimiter), cfg)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    appendNonce
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func appendNonce(response []byte, now time.Time, sequence int64, cfg *serverConfig) []byte
+--  response:		the payload of the response.
+--       now:		when the response was built.
+--  sequence:		the number of nonces the server has issued, this one included.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     []byte the response with " <unix nanoseconds> <sequence>" added
+--              before its delimiter.
+--
+-- NOTES:			Clients compare the time against their own clock to measure
+--						skew. The sequence counts up across every connection, so it
+--						going backwards means the server was restarted.
+------------------------------------------------------------------------------*/
+func appendNonce(response []byte, now time.Time, sequence int64, cfg *serverConfig) []byte {
+	body, delimiter := splitDelimiter(response, cfg)
+	nonce := make([]byte, 0, len(body)+len(delimiter)+42)
+	nonce = append(nonce, body...)
+	nonce = append(nonce, ' ')
+	nonce = strconv.AppendInt(nonce, now.UnixNano(), 10)
+	nonce = append(nonce, ' ')
+	nonce = strconv.AppendInt(nonce, sequence, 10)
+
+	return append(nonce, delimiter...)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    teeEcho
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func teeEcho(w io.Writer, host string, payload []byte, limit int)
+--         w:		where the copy is written, stderr for -debug-tee.
+--      host:		the client the payload is echoed to.
+--   payload:		the payload being echoed.
+--     limit:		the most bytes of the payload copied, 0 for all of it.
+--
+-- RETURNS:     void
+--
+-- NOTES:			The payload is quoted so control characters and the newline
+--						don't break up the output.
+------------------------------------------------------------------------------*/
+func teeEcho(w io.Writer, host string, payload []byte, limit int) {
+	if limit > 0 && len(payload) > limit {
+		fmt.Fprintf(w, "%s: %q...\n", host, payload[:limit])
+		return
+	}
+	fmt.Fprintf(w, "%s: %q\n", host, payload)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    echoMatches
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - checks against the request as read and the -pipeline output
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func echoMatches(received []byte, response []byte, cfg *serverConfig) bool
+--  received:		a copy of the request as it was read, before any -pipeline
+--						stage saw it.
+--  response:		the echo about to be framed and written for it.
+--       cfg:		the server configuration.
+--
+-- RETURNS:     bool true if response is what the configured -pipeline makes of
+--              received, wrapped in the echo prefix and suffix and followed by
+--              a nonce when -nonce is set.
+--
+-- NOTES:			Used by -self-check to catch the buffering and transforms
+--						corrupting the payload. Doesn't apply to ACKs or dedup markers.
+--						The pipeline is run again on its own copy of the payload, so
+--						a stage that writes into the buffer it was given, or doesn't
+--						give the same output twice, shows up as a mismatch. A payload
+--						a stage fails on is expected back untransformed.
+------------------------------------------------------------------------------*/
+func echoMatches(received []byte, response []byte, cfg *serverConfig) bool {
+	body, delimiter := splitDelimiter(received, cfg)
+	if cfg.pipeline != nil {
+		if transformed, err := cfg.pipeline.run(bytes.Clone(body)); err == nil {
+			body = transformed
+		}
+	}
+	if !bytes.HasSuffix(response, delimiter) {
+		return false
+	}
+	echo := response[:len(response)-len(delimiter)]
+
+	expected := make([]byte, 0, len(cfg.echoPrefix)+len(body)+len(cfg.echoSuffix))
+	expected = append(expected, cfg.echoPrefix...)
+	expected = append(expected, body...)
+	expected = append(expected, cfg.echoSuffix...)
+	if !bytes.HasPrefix(echo, expected) {
+		return false
+	}
+	rest := echo[len(expected):]
+
+	return len(rest) == 0 || cfg.nonce && rest[0] == ' '
+}
@@ -0,0 +1,247 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 response_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newTestConnectionState(cfg *serverConfig) *connectionState
+--  func TestSelfCheckClean(t *testing.T)
+--  func TestSelfCheckCorruptingStage(t *testing.T)
+--  func TestEchoMatches(t *testing.T)
+--  func TestBuildResponseWrapped(t *testing.T)
+--  func TestEchoWrappedOnConnection(t *testing.T)
+--  func TestAckOnConnection(t *testing.T)
+--  func TestNonceOnConnection(t *testing.T)
+--  func TestDebugTeeTruncates(t *testing.T)
+--  func TestDebugTeeOnConnection(t *testing.T)
+--
+-- NOTES: Tests for building responses and checking them with -self-check.
+--        Requests go through processRequest as a worker would pass them.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestConnectionState the state of a connection served under cfg, timed by
+// a fakeClock.
+func newTestConnectionState(cfg *serverConfig) *connectionState {
+	return &connectionState{cfg: cfg, rng: newLockedRand(1), nonces: new(int64),
+		clock:  newFakeClock(time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)),
+		logger: newLogger(io.Discard, logLevelError, logFormatText)}
+}
+
+// TestSelfCheckClean checks that correct echoes, transformed or not, aren't
+// counted as mismatches.
+func TestSelfCheckClean(t *testing.T) {
+	stages, err := parsePipeline("upper,reverse,pad=8")
+	if err != nil {
+		t.Fatal("parsePipeline:", err)
+	}
+	for _, cfg := range []*serverConfig{
+		{framing: framingLine, delimiter: '\n', selfCheck: true},
+		{framing: framingLine, delimiter: '\n', selfCheck: true, echoPrefix: "<", echoSuffix: ">", nonce: true},
+		{framing: framingLine, delimiter: '\n', selfCheck: true, pipeline: stages},
+		{framing: framingLength, selfCheck: true, pipeline: stages},
+	} {
+		state := newTestConnectionState(cfg)
+		var connInfo connectionInfo
+		for _, request := range []string{"hello\n", "a\n", "mixed Case\n"} {
+			processRequest([]byte(request), &connInfo, state)
+		}
+		if connInfo.EchoMismatches != 0 {
+			t.Errorf("%d mismatches with %+v, want 0", connInfo.EchoMismatches, cfg)
+		}
+	}
+}
+
+// TestSelfCheckCorruptingStage checks that a stage that writes past its input,
+// over the delimiter in the request's buffer, is caught.
+func TestSelfCheckCorruptingStage(t *testing.T) {
+	exclaim := func(body []byte) ([]byte, error) { return append(body, '!'), nil }
+	cfg := &serverConfig{framing: framingLine, delimiter: '\n', selfCheck: true, pipeline: pipeline{exclaim}}
+	state := newTestConnectionState(cfg)
+
+	var connInfo connectionInfo
+	request := make([]byte, 0, 16)
+	request = append(request, "hello\n"...)
+	response := processRequest(request, &connInfo, state)
+	if connInfo.EchoMismatches != 1 {
+		t.Errorf("%d mismatches for the echo %q, want 1", connInfo.EchoMismatches, response)
+	}
+}
+
+// TestEchoMatches checks echoes against the request they answer.
+func TestEchoMatches(t *testing.T) {
+	cfg := &serverConfig{framing: framingLine, delimiter: '\n', echoPrefix: "[", echoSuffix: "]"}
+	tests := []struct {
+		response string
+		want     bool
+	}{
+		{"[hello]\n", true},
+		{"[hellp]\n", false},
+		{"[hello]", false},
+		{"hello\n", false},
+		{"[hello] 1760486400 1\n", false},
+	}
+	for _, test := range tests {
+		if got := echoMatches([]byte("hello\n"), []byte(test.response), cfg); got != test.want {
+			t.Errorf("echoMatches(%q) = %v, want %v", test.response, got, test.want)
+		}
+	}
+
+	cfg.nonce = true
+	if !echoMatches([]byte("hello\n"), []byte("[hello] 1760486400 1\n"), cfg) {
+		t.Error("an echo with a nonce didn't match with -nonce")
+	}
+}
+
+// TestBuildResponseWrapped checks that the echo prefix and suffix go around the
+// payload, inside the delimiter of line framing.
+func TestBuildResponseWrapped(t *testing.T) {
+	tests := []struct {
+		cfg      serverConfig
+		request  string
+		response string
+	}{
+		{serverConfig{framing: framingLine, delimiter: '\n', echoPrefix: "<<", echoSuffix: ">>"}, "hello\n", "<<hello>>\n"},
+		{serverConfig{framing: framingLine, delimiter: ';', echoPrefix: "<<"}, "hello;", "<<hello;"},
+		{serverConfig{framing: framingLine, delimiter: '\n', echoSuffix: ">>"}, "partial", "partial>>"},
+		{serverConfig{framing: framingHeader, echoPrefix: "<<", echoSuffix: ">>"}, "hello\n", "<<hello\n>>"},
+		{serverConfig{framing: framingLine, delimiter: '\n'}, "hello\n", "hello\n"},
+	}
+	for _, test := range tests {
+		if got := buildResponse([]byte(test.request), &test.cfg); string(got) != test.response {
+			t.Errorf("buildResponse(%q) = %q, want %q", test.request, got, test.response)
+		}
+	}
+}
+
+// TestEchoWrappedOnConnection checks that a client reading lines gets each
+// echo wrapped, with the wrapping counted in BytesSent.
+func TestEchoWrappedOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		echoPrefix: "<<", echoSuffix: ">>"})
+	client, served := serveTestConnection(t, srvInfo)
+
+	reader := bufio.NewReader(client)
+	for _, request := range []string{"one\n", "two\n"} {
+		io.WriteString(client, request)
+		if echo, err := reader.ReadString('\n'); err != nil || echo != "<<"+request[:len(request)-1]+">>\n" {
+			t.Errorf("echo of %q was %q, %v", request, echo, err)
+		}
+	}
+	client.Close()
+
+	if connInfo := <-served; connInfo.BytesReceived != 8 || connInfo.BytesSent != 16 {
+		t.Errorf("%d bytes received and %d sent, want 8 and 16", connInfo.BytesReceived, connInfo.BytesSent)
+	}
+}
+
+// TestAckOnConnection checks that ack mode answers each request with its
+// index, an oversized request with a NACK, and counts the acks as sent.
+func TestAckOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', ack: true, maxLine: 8})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	sent := 0
+	for i, request := range []string{"one\n", "two\n", "three\n", "much too long\n"} {
+		io.WriteString(client, request)
+		want := fmt.Sprintf("ACK %d\n", i+1)
+		if i == 3 {
+			want = "NACK 4\n"
+		}
+		if response, err := reader.ReadString('\n'); err != nil || response != want {
+			t.Fatalf("response to %q was %q, %v, want %q", request, response, err, want)
+		}
+		sent += len(want)
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("read after the NACK returned %v, want the connection closed", err)
+	}
+
+	if connInfo := <-served; connInfo.BytesSent != sent {
+		t.Errorf("%d bytes sent, want the %d bytes of the acks", connInfo.BytesSent, sent)
+	}
+}
+
+// TestNonceOnConnection checks that each echo carries the server's time and a
+// nonce one higher than the last.
+func TestNonceOnConnection(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', nonce: true})
+	client, _ := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	for want := int64(1); want <= 3; want++ {
+		io.WriteString(client, "ping\n")
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal("read:", err)
+		}
+		var body string
+		var timestamp, sequence int64
+		if _, err := fmt.Sscanf(response, "%s %d %d\n", &body, &timestamp, &sequence); err != nil || body != "ping" {
+			t.Fatalf("response %q wasn't the request followed by a nonce: %v", response, err)
+		}
+		if now := srvInfo.clock.Now(); !time.Unix(0, timestamp).Equal(now) {
+			t.Errorf("nonce timestamp %s, want the server's time %s", time.Unix(0, timestamp).UTC(), now)
+		}
+		if sequence != want {
+			t.Errorf("nonce %d, want %d", sequence, want)
+		}
+	}
+}
+
+// TestDebugTeeTruncates checks that the tee names the client and cuts
+// payloads off at -debug-tee-length.
+func TestDebugTeeTruncates(t *testing.T) {
+	var tee bytes.Buffer
+	teeEcho(&tee, "192.0.2.1:40000", []byte("short\n"), 8)
+	teeEcho(&tee, "192.0.2.1:40000", []byte("a much longer payload\n"), 8)
+	if want := "192.0.2.1:40000: \"short\\n\"\n192.0.2.1:40000: \"a much l\"...\n"; tee.String() != want {
+		t.Errorf("the tee wrote %q, want %q", tee.String(), want)
+	}
+}
+
+// TestDebugTeeOnConnection checks that each echoed payload is copied to stderr
+// when -debug-tee is set.
+func TestDebugTeeOnConnection(t *testing.T) {
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal("pipe:", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = write
+	defer func() { os.Stderr = stderr }()
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', debugTee: true})
+	client, served := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+	for _, request := range []string{"one\n", "two\n"} {
+		io.WriteString(client, request)
+		reader.ReadString('\n')
+	}
+	client.Close()
+	<-served
+	write.Close()
+
+	tee, _ := io.ReadAll(read)
+	if !bytes.Contains(tee, []byte(`"one\n"`)) || !bytes.Contains(tee, []byte(`"two\n"`)) {
+		t.Errorf("the tee wrote %q, want both echoes", tee)
+	}
+}
@@ -0,0 +1,168 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 rotate.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error)
+--  func (rotating *rotatingFile) Write(b []byte) (int, error)
+--  func (rotating *rotatingFile) rotate() error
+--  func (rotating *rotatingFile) Close() error
+--
+-- NOTES: This file rotates the files the server appends to while it runs once
+--        they reach -report-max-size, so a long run can't fill the disk. The
+--        rotated files are named after the file with a sequence number, .1
+--        being the newest, and only -report-keep of them are kept.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile a file that is moved aside and started again once it is full.
+type rotatingFile struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64 // the most bytes written to a file before it is rotated, 0 to never rotate
+	keep    int   // the number of rotated files kept
+	file    *os.File
+	size    int64 // the bytes written to file
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newRotatingFile
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error)
+--      path:		the file to write.
+--   maxSize:		the -report-max-size, 0 to never rotate.
+--      keep:		the -report-keep.
+--
+-- RETURNS:     *rotatingFile the file, replacing any file already at path.
+--              error any error creating the file.
+------------------------------------------------------------------------------*/
+func newRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rotatingFile{path: path, maxSize: maxSize, keep: keep, file: file}, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Write
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (rotating *rotatingFile) Write(b []byte) (int, error)
+--         b:		the bytes to write.
+--
+-- RETURNS:     int the number of bytes written.
+--              error any error rotating or writing.
+--
+-- NOTES:			Safe to call from any go routine. A write is never split
+--						between files, the file is rotated first if the write would
+--						take it past maxSize. Only a single write larger than maxSize
+--						makes a file bigger than it.
+------------------------------------------------------------------------------*/
+func (rotating *rotatingFile) Write(b []byte) (int, error) {
+	rotating.mutex.Lock()
+	defer rotating.mutex.Unlock()
+
+	if rotating.maxSize > 0 && rotating.size > 0 && rotating.size+int64(len(b)) > rotating.maxSize {
+		if err := rotating.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rotating.file.Write(b)
+	rotating.size += int64(n)
+
+	return n, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    rotate
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (rotating *rotatingFile) rotate() error
+--
+-- RETURNS:     error any error moving the files or starting the new one.
+--
+-- NOTES:			Must be called with the file locked. Each rotated file moves
+--						up a number, the oldest falling off past keep, then the full
+--						file becomes .1 and an empty one is started in its place.
+------------------------------------------------------------------------------*/
+func (rotating *rotatingFile) rotate() error {
+	if err := rotating.file.Close(); err != nil {
+		return err
+	}
+	for i := rotating.keep - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rotating.path, i), fmt.Sprintf("%s.%d", rotating.path, i+1))
+	}
+	if rotating.keep > 0 {
+		if err := os.Rename(rotating.path, rotating.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(rotating.path)
+	if err != nil {
+		return err
+	}
+	rotating.file, rotating.size = file, 0
+
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    Close
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (rotating *rotatingFile) Close() error
+--
+-- RETURNS:     error any error closing the file being written.
+------------------------------------------------------------------------------*/
+func (rotating *rotatingFile) Close() error {
+	rotating.mutex.Lock()
+	defer rotating.mutex.Unlock()
+
+	return rotating.file.Close()
+}
@@ -0,0 +1,145 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 rotate_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func rotatedFiles(t *testing.T, path string) []string
+--  func TestRotatingFileBounded(t *testing.T)
+--  func TestRotatingFileConcurrentWrites(t *testing.T)
+--  func TestRotatingFileKeepNone(t *testing.T)
+--
+-- NOTES: Tests for rotating files at -report-max-size. Files are written to
+--        the test's temporary directory.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rotatedFiles the contents of the file at path and its rotated files,
+// newest first, failing the test if any can't be read.
+func rotatedFiles(t *testing.T, path string) []string {
+	t.Helper()
+	names, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal("Glob:", err)
+	}
+	contents := make([]string, len(names))
+	for i := range names {
+		name := path
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d", path, i)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal("ReadFile:", err)
+		}
+		contents[i] = string(data)
+	}
+
+	return contents
+}
+
+// TestRotatingFileBounded checks that writing past the size limit rotates the
+// file into several files no larger than the limit, keeping only the newest.
+func TestRotatingFileBounded(t *testing.T) {
+	const maxSize, keep = 100, 3
+	path := filepath.Join(t.TempDir(), "snapshots.log")
+	file, err := newRotatingFile(path, maxSize, keep)
+	if err != nil {
+		t.Fatal("newRotatingFile:", err)
+	}
+	defer file.Close()
+
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(file, "snapshot line %04d\n", i) // 20 bytes, 5 to a file
+	}
+
+	files := rotatedFiles(t, path)
+	if len(files) != keep+1 {
+		t.Fatalf("%d files, want the file and %d rotated ones", len(files), keep)
+	}
+	for i, contents := range files {
+		if len(contents) > maxSize {
+			t.Errorf("file %d is %d bytes, past the limit of %d", i, len(contents), maxSize)
+		}
+	}
+	if !strings.HasSuffix(files[0], "snapshot line 0049\n") || !strings.HasPrefix(files[keep], "snapshot line 0030\n") {
+		t.Errorf("the newest lines weren't kept, the files were %q", files)
+	}
+}
+
+// TestRotatingFileConcurrentWrites checks that writes from many go routines
+// are never torn or split across files.
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	const writers, lines = 8, 100
+	path := filepath.Join(t.TempDir(), "snapshots.log")
+	file, err := newRotatingFile(path, 1000, writers*lines)
+	if err != nil {
+		t.Fatal("newRotatingFile:", err)
+	}
+	defer file.Close()
+
+	var wg sync.WaitGroup
+	for writer := 0; writer < writers; writer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				fmt.Fprintf(file, "writer %d line %04d\n", writer, i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	written := 0
+	for i, contents := range rotatedFiles(t, path) {
+		if len(contents) > 1000 {
+			t.Errorf("file %d is %d bytes, past the limit of 1000", i, len(contents))
+		}
+		for _, line := range strings.SplitAfter(contents, "\n") {
+			if line == "" {
+				continue
+			}
+			var writer, n int
+			if _, err := fmt.Sscanf(line, "writer %d line %d\n", &writer, &n); err != nil || len(line) != 19 {
+				t.Errorf("torn line %q in file %d", line, i)
+			}
+			written++
+		}
+	}
+	if written != writers*lines {
+		t.Errorf("%d lines written, want %d", written, writers*lines)
+	}
+}
+
+// TestRotatingFileKeepNone checks that with nothing kept the file is started
+// again without leaving rotated files behind.
+func TestRotatingFileKeepNone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.log")
+	file, err := newRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatal("newRotatingFile:", err)
+	}
+	defer file.Close()
+
+	file.Write([]byte("first\n"))
+	file.Write([]byte("second\n"))
+	if files := rotatedFiles(t, path); len(files) != 1 || files[0] != "second\n" {
+		t.Errorf("the files were %q, want only the second line", files)
+	}
+}
@@ -0,0 +1,79 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 socket.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func removeStaleSocket(path string) error
+--
+-- NOTES: This file lets the server listen on a Unix socket with -network unix
+--        for benchmarking local IPC. The socket file is removed when the
+--        listener is closed, but a server that was killed leaves its file
+--        behind and the next Listen on the path would fail.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// networkUnix the -network that listens on a Unix socket.
+const networkUnix = "unix"
+
+// staleSocketTimeout how long to try connecting to an existing socket file
+// before deciding nothing is listening on it.
+const staleSocketTimeout = time.Second
+
+var errSocketInUse = errors.New("another process is listening on the socket")
+var errNotSocket = errors.New("the path exists and isn't a socket")
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    removeStaleSocket
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func removeStaleSocket(path string) error
+--      path:		the socket file the server is about to listen on.
+--
+-- RETURNS:     error errSocketInUse if a server is listening on the socket,
+--              errNotSocket if path is some other kind of file, or any
+--              error removing it.
+--
+-- NOTES:			A socket nothing answers on was left by a server that didn't
+--						shut down cleanly, so it is removed. Anything else is left
+--						alone.
+------------------------------------------------------------------------------*/
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errNotSocket
+	}
+
+	if conn, err := net.DialTimeout(networkUnix, path, staleSocketTimeout); err == nil {
+		conn.Close()
+		return errSocketInUse
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,104 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 socket_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestUnixSocketEcho(t *testing.T)
+--  func TestRemoveStaleSocket(t *testing.T)
+--
+-- NOTES: Tests for listening on a Unix socket. Sockets are created in the
+--        test's temporary directory.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestUnixSocketEcho checks that a worker accepting from a Unix socket echoes
+// a line sent by a client dialling the socket's path.
+func TestUnixSocketEcho(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echo.sock")
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, network: networkUnix,
+		framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	listener, err := net.Listen(networkUnix, path)
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = listener, newAcceptGate()
+	*srvInfo.availableServers++
+	spawnWorker(srvInfo)
+	go observerLoop(srvInfo, nil)
+
+	client, err := net.Dial(networkUnix, path)
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(client, "hello\n")
+	if echo, err := bufio.NewReader(client).ReadString('\n'); echo != "hello\n" {
+		t.Errorf("the echo was %q, %v, want %q", echo, err, "hello\n")
+	}
+}
+
+// TestRemoveStaleSocket checks that a socket left by a server that was killed
+// is removed, and that one still being listened on, or a file that isn't a
+// socket, is left alone.
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	if err := removeStaleSocket(filepath.Join(dir, "missing.sock")); err != nil {
+		t.Error("a missing socket was", err)
+	}
+
+	stale := filepath.Join(dir, "stale.sock")
+	listener, err := net.Listen(networkUnix, stale)
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false) // as if the server was killed
+	listener.Close()
+	if err := removeStaleSocket(stale); err != nil {
+		t.Error("a stale socket was", err)
+	}
+	if _, err := os.Lstat(stale); !os.IsNotExist(err) {
+		t.Error("the stale socket wasn't removed")
+	}
+
+	active := filepath.Join(dir, "active.sock")
+	listener, err = net.Listen(networkUnix, active)
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	if err := removeStaleSocket(active); err != errSocketInUse {
+		t.Errorf("a socket being listened on was %v, want %v", err, errSocketInUse)
+	}
+
+	regular := filepath.Join(dir, "regular")
+	os.WriteFile(regular, nil, 0600)
+	if err := removeStaleSocket(regular); err != errNotSocket {
+		t.Errorf("a regular file was %v, want %v", err, errNotSocket)
+	}
+	if _, err := os.Lstat(regular); err != nil {
+		t.Error("the regular file was removed")
+	}
+}
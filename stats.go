@@ -0,0 +1,767 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 stats.go
+--
+-- REVISIONS: 	October 15, 2026 - Connections can be grouped by a label
+--              October 15, 2026 - Added the connection lifetime histogram
+--              October 15, 2026 - Added snapshots of the statistics
+--              October 15, 2026 - The statistics can be reset between reports
+--              October 15, 2026 - Added the TLS version and cipher suite distribution
+--              October 15, 2026 - Added the response to request size ratios
+--              October 15, 2026 - Statistics are dropped while the observer is behind
+--              October 15, 2026 - Added the -pipeline byte counts
+--              October 15, 2026 - Added rollups of the listed connections
+--              October 15, 2026 - Added the -snapshot-interval lines
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newServerStats(groupBy string) *serverStats
+--  func ratioBucketIndex(ratio float64) int
+--  func (stats *serverStats) connectionOpened()
+--  func (stats *serverStats) connectionClosed(connInfo connectionInfo)
+--  func (stats *serverStats) probeClosed()
+--  func (stats *serverStats) connectionDropped()
+--  func (stats *serverStats) recordLag(lag time.Duration)
+--  func (stats *serverStats) prune(cutoff time.Time)
+--  func (stats *serverStats) sampleThroughput(now time.Time, transferred int64)
+--  func (stats *serverStats) summary() reportSummary
+--  func rollupConnections(connections *list.List, summary *reportSummary)
+--  func durationPercentile(sorted []time.Duration, p float64) time.Duration
+--  func (stats *serverStats) reset()
+--  func (stats *serverStats) snapshot() statsSnapshot
+--  func readStats(srvInfo serverInfo) statsSnapshot
+--  func openSnapshotFile(interval time.Duration, path string, maxSize int64, keep int) io.Writer
+--  func writeSnapshotLine(w io.Writer, now time.Time, snapshot statsSnapshot) error
+--
+-- NOTES: This file holds the statistics gathered by the observer. They are only
+--        ever touched from the observer's go routine, other go routines ask
+--        the observer for a snapshot with readStats.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// serverStats the running statistics about the server kept by the observer.
+type serverStats struct {
+	currentConnections int        // connections currently being served
+	connectionsMade    *list.List // connectionInfo for each finished connection still retained
+	totals             reportSummary
+	responseDelay      time.Duration // the total delay added to responses
+	pacingDeviation    time.Duration // the total time delayed responses were sent off their intended delay
+	groupBy            string        // the label connections are grouped by, empty for none
+	labelGroups        map[string]*labelGroup
+	lifetimes          []int         // connections in each lifetime bucket, see lifetimeBounds
+	lifetimeTotal      time.Duration // the time every connection was open for
+	closeReasons       map[string]int
+	tlsCounts          map[tlsCount]int // connections by negotiated TLS version and cipher suite
+	ratios             []int            // connections in each response ratio bucket, see ratioBuckets
+	ratioed            int              // connections counted in ratios
+	grandTotal         int              // connections finished before the last reset
+	lagGuardTripped    bool             // whether -max-observer-lag has been exceeded, so it is only logged once
+}
+
+// statsSnapshot the statistics at a point in time, returned by readStats.
+type statsSnapshot struct {
+	CurrentConnections int            // connections being served
+	PeakConnections    int            // the most connections served at once
+	TotalConnections   int            // connections finished
+	TotalRequests      int            // requests across all finished connections
+	BytesReceived      int            // bytes read from all finished connections
+	BytesSent          int            // bytes written to all finished connections
+	CloseReasons       map[string]int // finished connections by CloseReason, "" for closed by the client
+}
+
+// lifetimeBounds the upper bounds of the connection lifetime buckets. The last
+// bucket holds everything longer.
+var lifetimeBounds = []time.Duration{10 * time.Millisecond, 100 * time.Millisecond,
+	time.Second, 10 * time.Second, time.Minute, 10 * time.Minute}
+
+// lifetimeBucket the connections that were open for a range of time.
+type lifetimeBucket struct {
+	Lifetime    string // the range of time, "< 1s" or ">= 10m0s"
+	Connections int    // the connections open for that long
+}
+
+// ratioBuckets the names of the response ratio buckets, see ratioBucketIndex.
+var ratioBuckets = []string{"< 1", "1", "<= 2", "<= 10", "> 10"}
+
+// ratioBucket the connections whose responses were a range of sizes compared
+// to their requests.
+type ratioBucket struct {
+	ResponseRatio string // the range of BytesSent / BytesReceived
+	Connections   int
+}
+
+// tlsCount the connections that negotiated a TLS version and cipher suite.
+type tlsCount struct {
+	Version     string
+	CipherSuite string
+	Connections int // always 0 when used as a key of serverStats.tlsCounts
+}
+
+// reportSummary totals across every connection the server has finished.
+type reportSummary struct {
+	GeneratedAt time.Time // when the report was written
+
+	TotalConnections      int           // connections finished
+	PeakConnections       int           // the most connections served at once
+	GrandTotalConnections int           // connections finished since the server started, across resets
+	TotalData             int           // AmmountOfData across all connections
+	TotalRequests         int           // requests across all connections
+	BytesReceived         int           // bytes read from all connections
+	BytesSent             int           // bytes written to all connections
+	PrunedConnections     int           // connections no longer listed in detail
+	DroppedConnections    int           // connections only counted because the observer was behind, see -max-observer-lag
+	MaxObserverLag        time.Duration // the longest a finished connection waited for the observer
+	EchoMismatches        int           // echoes that didn't carry their request, if -self-check is set
+	PipelineBytesIn       int           // request bytes passed into the -pipeline stages
+	PipelineBytesOut      int           // bytes the -pipeline stages produced from them
+	PipelineErrors        int           // requests a -pipeline stage failed on
+	Stalls                int           // times a request made no progress for -stuck-threshold, if it is set
+
+	DelayedRequests   int           // requests whose response was delayed
+	MeanResponseDelay time.Duration // the average delay added to a delayed response
+	MaxResponseDelay  time.Duration // the longest delay added to a response
+
+	MeanPacingDeviation time.Duration // the average time a delayed response was sent off its intended delay
+	MaxPacingDeviation  time.Duration // the furthest a delayed response was sent off its intended delay
+
+	MeanDuration          time.Duration // the average time a listed connection was served for
+	MedianDuration        time.Duration // the median time a listed connection was served for
+	P95Duration           time.Duration // the 95th percentile time a listed connection was served for
+	MeanRequests          float64       // the average requests per listed connection
+	MaxConnectionsAtClose int           // the most connections open when a listed connection closed
+
+	LatencyP50  time.Duration // the median time to answer a request, if -latency-hdr is set
+	LatencyP90  time.Duration // the 90th percentile time to answer a request, if -latency-hdr is set
+	LatencyP99  time.Duration // the 99th percentile time to answer a request, if -latency-hdr is set
+	LatencyP999 time.Duration // the 99.9th percentile time to answer a request, if -latency-hdr is set
+	LatencyMax  time.Duration // the longest time to answer a request, if -latency-hdr is set
+
+	NonceEcho    bool  // whether echoes carried the server's time and a sequence number
+	NoncesIssued int64 // the sequence number of the last nonce
+
+	UniqueClients int                 // distinct client addresses that connected
+	TopClients    []clientConcurrency // the clients with the most connections open at once
+
+	LifetimeHistogram []lifetimeBucket // how long connections were open for
+	ResponseRatios    []ratioBucket    // how much connections were sent compared to what they sent
+
+	ResponseRatio    float64    // BytesSent / BytesReceived across all connections
+	MinResponseRatio float64    // the smallest ratio of a connection that sent something
+	MaxResponseRatio float64    // the largest ratio of a connection
+	TLSDistribution  []tlsCount // connections by negotiated TLS version and cipher suite, if -tls-stats is set
+
+	ThroughputSamples []throughputSample // bytes transferred over time, listed on their own sheet
+	LabelGroups       []labelGroup       // totals for each value of the -report-group-by label
+}
+
+// throughputSample the total bytes transferred by the server at a point in time.
+type throughputSample struct {
+	Time  time.Time
+	Bytes int64
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newServerStats
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - added the lifetime buckets
+--              October 15, 2026 - added the close reasons
+--              October 15, 2026 - added the TLS counts
+--              October 15, 2026 - added the response ratio buckets
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newServerStats(groupBy string) *serverStats
+--   groupBy:		the label to total connections by, empty for none.
+--
+-- RETURNS:     *serverStats empty statistics
+------------------------------------------------------------------------------*/
+func newServerStats(groupBy string) *serverStats {
+	return &serverStats{connectionsMade: list.New(), groupBy: groupBy,
+		labelGroups: make(map[string]*labelGroup), lifetimes: make([]int, len(lifetimeBounds)+1),
+		closeReasons: make(map[string]int), tlsCounts: make(map[tlsCount]int),
+		ratios: make([]int, len(ratioBuckets))}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    ratioBucketIndex
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func ratioBucketIndex(ratio float64) int
+--     ratio:		a connection's BytesSent / BytesReceived.
+--
+-- RETURNS:     int the index of the bucket in ratioBuckets.
+--
+-- NOTES:			A plain echo has a ratio of exactly 1 so it gets a bucket of its
+--						own, prefixes, suffixes and nonces push connections above it.
+------------------------------------------------------------------------------*/
+func ratioBucketIndex(ratio float64) int {
+	switch {
+	case ratio < 1:
+		return 0
+	case ratio == 1:
+		return 1
+	case ratio <= 2:
+		return 2
+	case ratio <= 10:
+		return 3
+	}
+
+	return 4
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionOpened
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - tracks the peak connections
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) connectionOpened()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called when a worker accepts a new connection.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) connectionOpened() {
+	stats.currentConnections++
+	if stats.currentConnections > stats.totals.PeakConnections {
+		stats.totals.PeakConnections = stats.currentConnections
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - totals connections by the -report-group-by label
+--              October 15, 2026 - totals the echo mismatches
+--              October 15, 2026 - counts the connection in a lifetime bucket
+--              October 15, 2026 - totals the connection lifetimes
+--              October 15, 2026 - counts the close reasons
+--              October 15, 2026 - counts the negotiated TLS parameters
+--              October 15, 2026 - counts the connection in a response ratio bucket
+--              October 15, 2026 - totals the pacing deviation
+--              October 15, 2026 - totals the -pipeline byte counts
+--              October 15, 2026 - totals the stalls
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) connectionClosed(connInfo connectionInfo)
+--  connInfo:		information about the finished connection.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called when a worker finishes with a connection.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) connectionClosed(connInfo connectionInfo) {
+	connInfo.ConnectionsAtClose = stats.currentConnections
+	stats.connectionsMade.PushBack(connInfo)
+	stats.currentConnections--
+
+	stats.totals.TotalConnections++
+	stats.totals.TotalData += connInfo.AmmountOfData
+	stats.totals.TotalRequests += connInfo.NumberOfRequests
+	stats.totals.BytesReceived += connInfo.BytesReceived
+	stats.totals.BytesSent += connInfo.BytesSent
+	stats.totals.EchoMismatches += connInfo.EchoMismatches
+	stats.totals.PipelineBytesIn += connInfo.PipelineBytesIn
+	stats.totals.PipelineBytesOut += connInfo.PipelineBytesOut
+	stats.totals.PipelineErrors += connInfo.PipelineErrors
+	stats.totals.Stalls += connInfo.Stalls
+	stats.closeReasons[connInfo.CloseReason]++
+	if connInfo.TLSVersion != "" {
+		stats.tlsCounts[tlsCount{Version: connInfo.TLSVersion, CipherSuite: connInfo.TLSCipherSuite}]++
+	}
+	if connInfo.BytesReceived > 0 {
+		ratio := connInfo.ResponseRatio
+		if ratio < stats.totals.MinResponseRatio || stats.ratioed == 0 {
+			stats.totals.MinResponseRatio = ratio
+		}
+		if ratio > stats.totals.MaxResponseRatio {
+			stats.totals.MaxResponseRatio = ratio
+		}
+		stats.ratios[ratioBucketIndex(ratio)]++
+		stats.ratioed++
+	}
+	stats.lifetimeTotal += connInfo.Duration
+	stats.lifetimes[sort.Search(len(lifetimeBounds), func(i int) bool {
+		return connInfo.Duration < lifetimeBounds[i]
+	})]++
+
+	stats.totals.DelayedRequests += connInfo.DelayedRequests
+	stats.responseDelay += connInfo.ResponseDelay
+	if connInfo.MaxResponseDelay > stats.totals.MaxResponseDelay {
+		stats.totals.MaxResponseDelay = connInfo.MaxResponseDelay
+	}
+	stats.pacingDeviation += connInfo.PacingDeviation
+	if connInfo.MaxPacingDeviation > stats.totals.MaxPacingDeviation {
+		stats.totals.MaxPacingDeviation = connInfo.MaxPacingDeviation
+	}
+
+	if stats.groupBy != "" {
+		label := connInfo.Labels[stats.groupBy]
+		group, ok := stats.labelGroups[label]
+		if !ok {
+			group = &labelGroup{Label: label}
+			stats.labelGroups[label] = group
+		}
+		group.Connections++
+		group.Requests += connInfo.NumberOfRequests
+		group.BytesReceived += connInfo.BytesReceived
+		group.BytesSent += connInfo.BytesSent
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    probeClosed
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) probeClosed()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called instead of connectionClosed for the health probe's
+--						connections, which are counted while open but not reported.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) probeClosed() {
+	stats.currentConnections--
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    connectionDropped
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) connectionDropped()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Called instead of connectionClosed while the observer is over
+--						-max-observer-lag. The connection is counted as finished but
+--						nothing else about it is kept.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) connectionDropped() {
+	stats.currentConnections--
+	stats.totals.TotalConnections++
+	stats.totals.DroppedConnections++
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    recordLag
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) recordLag(lag time.Duration)
+--       lag:		how long a finished connection waited for the observer.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (stats *serverStats) recordLag(lag time.Duration) {
+	if lag > stats.totals.MaxObserverLag {
+		stats.totals.MaxObserverLag = lag
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    prune
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) prune(cutoff time.Time)
+--    cutoff:		connections closed before this are dropped.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Connections are listed in the order they closed so pruning stops
+--						at the first connection that is new enough. Pruned connections
+--						are still counted in the totals.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) prune(cutoff time.Time) {
+	for e := stats.connectionsMade.Front(); e != nil; e = stats.connectionsMade.Front() {
+		if !e.Value.(connectionInfo).EndTime.Before(cutoff) {
+			break
+		}
+		stats.connectionsMade.Remove(e)
+		stats.totals.PrunedConnections++
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    sampleThroughput
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) sampleThroughput(now time.Time, transferred int64)
+--       now:		when the sample was taken.
+-- transferred:	the bytes transferred by the server so far.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (stats *serverStats) sampleThroughput(now time.Time, transferred int64) {
+	stats.totals.ThroughputSamples = append(stats.totals.ThroughputSamples,
+		throughputSample{Time: now, Bytes: transferred})
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    summary
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - lists the label groups
+--              October 15, 2026 - lists the lifetime histogram
+--              October 15, 2026 - adds the grand total across resets
+--              October 15, 2026 - lists the TLS distribution
+--              October 15, 2026 - lists the response ratios
+--              October 15, 2026 - reports the mean pacing deviation
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) summary() reportSummary
+--
+-- RETURNS:     reportSummary the totals for the report.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) summary() reportSummary {
+	summary := stats.totals
+	summary.GrandTotalConnections = stats.grandTotal + summary.TotalConnections
+	if summary.DelayedRequests > 0 {
+		summary.MeanResponseDelay = stats.responseDelay / time.Duration(summary.DelayedRequests)
+		summary.MeanPacingDeviation = stats.pacingDeviation / time.Duration(summary.DelayedRequests)
+	}
+	for _, group := range stats.labelGroups {
+		summary.LabelGroups = append(summary.LabelGroups, *group)
+	}
+	sort.Slice(summary.LabelGroups, func(i, j int) bool {
+		return summary.LabelGroups[i].Label < summary.LabelGroups[j].Label
+	})
+	if summary.TotalConnections > 0 {
+		for i, connections := range stats.lifetimes {
+			bucket := lifetimeBucket{Connections: connections}
+			if i < len(lifetimeBounds) {
+				bucket.Lifetime = "< " + lifetimeBounds[i].String()
+			} else {
+				bucket.Lifetime = ">= " + lifetimeBounds[i-1].String()
+			}
+			summary.LifetimeHistogram = append(summary.LifetimeHistogram, bucket)
+		}
+	}
+	if summary.BytesReceived > 0 {
+		summary.ResponseRatio = float64(summary.BytesSent) / float64(summary.BytesReceived)
+		for i, connections := range stats.ratios {
+			summary.ResponseRatios = append(summary.ResponseRatios,
+				ratioBucket{ResponseRatio: ratioBuckets[i], Connections: connections})
+		}
+	}
+	for negotiated, connections := range stats.tlsCounts {
+		negotiated.Connections = connections
+		summary.TLSDistribution = append(summary.TLSDistribution, negotiated)
+	}
+	sort.Slice(summary.TLSDistribution, func(i, j int) bool {
+		a, b := summary.TLSDistribution[i], summary.TLSDistribution[j]
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.CipherSuite < b.CipherSuite
+	})
+
+	return summary
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    rollupConnections
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func rollupConnections(connections *list.List, summary *reportSummary)
+-- connections:	the connectionInfo listed in the report.
+--   summary:		where the rollups are set.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Unlike the totals, which count every connection, the rollups
+--						only cover the connections listed, so pruned connections and
+--						those left out by -report-filter aren't in them. Leaves the
+--						rollups at 0 if nothing is listed.
+------------------------------------------------------------------------------*/
+func rollupConnections(connections *list.List, summary *reportSummary) {
+	if connections.Len() == 0 {
+		return
+	}
+
+	durations := make([]time.Duration, 0, connections.Len())
+	var total time.Duration
+	requests := 0
+	for e := connections.Front(); e != nil; e = e.Next() {
+		connInfo := e.Value.(connectionInfo)
+		durations = append(durations, connInfo.Duration)
+		total += connInfo.Duration
+		requests += connInfo.NumberOfRequests
+		summary.MaxConnectionsAtClose = max(summary.MaxConnectionsAtClose, connInfo.ConnectionsAtClose)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	summary.MeanDuration = total / time.Duration(len(durations))
+	summary.MedianDuration = durationPercentile(durations, 0.5)
+	summary.P95Duration = durationPercentile(durations, 0.95)
+	summary.MeanRequests = float64(requests) / float64(len(durations))
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    durationPercentile
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func durationPercentile(sorted []time.Duration, p float64) time.Duration
+--    sorted:		the durations, shortest first. Must not be empty.
+--         p:		the percentile wanted, from 0 to 1.
+--
+-- RETURNS:     time.Duration the percentile, interpolated linearly between the
+--              two durations either side of it.
+------------------------------------------------------------------------------*/
+func durationPercentile(sorted []time.Duration, p float64) time.Duration {
+	rank := p * float64(len(sorted)-1)
+	below := int(rank)
+	if below == len(sorted)-1 {
+		return sorted[below]
+	}
+	fraction := rank - float64(below)
+
+	return sorted[below] + time.Duration(math.Round(fraction*float64(sorted[below+1]-sorted[below])))
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    reset
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - keeps whether the lag guard has been logged
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) reset()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Clears the finished connections and their totals so the next
+--						report only covers what happens after the reset. The
+--						connections being served, the grand total and the last
+--						throughput sample are kept.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) reset() {
+	fresh := newServerStats(stats.groupBy)
+	fresh.currentConnections = stats.currentConnections
+	fresh.totals.PeakConnections = stats.currentConnections
+	fresh.grandTotal = stats.grandTotal + stats.totals.TotalConnections
+	fresh.lagGuardTripped = stats.lagGuardTripped
+	if samples := stats.totals.ThroughputSamples; len(samples) > 0 {
+		fresh.totals.ThroughputSamples = samples[len(samples)-1:]
+	}
+	*stats = *fresh
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    snapshot
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (stats *serverStats) snapshot() statsSnapshot
+--
+-- RETURNS:     statsSnapshot a copy of the statistics that the observer won't
+--              change.
+------------------------------------------------------------------------------*/
+func (stats *serverStats) snapshot() statsSnapshot {
+	snapshot := statsSnapshot{CurrentConnections: stats.currentConnections,
+		PeakConnections: stats.totals.PeakConnections, TotalConnections: stats.totals.TotalConnections,
+		TotalRequests: stats.totals.TotalRequests, BytesReceived: stats.totals.BytesReceived,
+		BytesSent: stats.totals.BytesSent, CloseReasons: make(map[string]int, len(stats.closeReasons))}
+	for reason, connections := range stats.closeReasons {
+		snapshot.CloseReasons[reason] = connections
+	}
+
+	return snapshot
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    readStats
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - a function like the rest of the observer's,
+--                renamed from the Stats method
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func readStats(srvInfo serverInfo) statsSnapshot
+--   srvInfo:		Information about the server.
+--
+-- RETURNS:     statsSnapshot the server's statistics.
+--
+-- NOTES:			Safe to call from any go routine. The snapshot is taken by the
+--						observer between events so its fields are consistent with each
+--						other. Blocks until the observer gets to the request. The
+--						server is a main package, which can't be imported, so there is
+--						no Server type to embed, the snapshot is read by the admin
+--						protocol and the control endpoint.
+------------------------------------------------------------------------------*/
+func readStats(srvInfo serverInfo) statsSnapshot {
+	reply := make(chan statsSnapshot, 1)
+	srvInfo.statsRequests <- reply
+
+	return <-reply
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    openSnapshotFile
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - the file is rotated at -report-max-size
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func openSnapshotFile(interval time.Duration, path string, maxSize int64, keep int) io.Writer
+--  interval:		the -snapshot-interval.
+--      path:		the -snapshot-file, empty for stdout.
+--   maxSize:		the -report-max-size, 0 to never rotate the file.
+--      keep:		the -report-keep.
+--
+-- RETURNS:     io.Writer where the snapshot lines are written, nil if
+--              interval is 0.
+--
+-- NOTES:			Exits the program if the file can't be created, so a run isn't
+--						wasted finding out at the first snapshot.
+------------------------------------------------------------------------------*/
+func openSnapshotFile(interval time.Duration, path string, maxSize int64, keep int) io.Writer {
+	if interval == 0 {
+		return nil
+	}
+	if path == "" {
+		return os.Stdout
+	}
+
+	file, err := newRotatingFile(path, maxSize, keep)
+	if err != nil {
+		log.Fatalln("Unable to use -snapshot-file:", err)
+	}
+
+	return file
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeSnapshotLine
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeSnapshotLine(w io.Writer, now time.Time, snapshot statsSnapshot) error
+--         w:		where the line is written.
+--       now:		when the snapshot was taken.
+--  snapshot:		the observer's statistics.
+--
+-- RETURNS:     error any error writing the line.
+--
+-- NOTES:			Called by the observer. The totals are of finished connections,
+--						like the report's.
+------------------------------------------------------------------------------*/
+func writeSnapshotLine(w io.Writer, now time.Time, snapshot statsSnapshot) error {
+	_, err := fmt.Fprintf(w, "%s current=%d peak=%d total=%d requests=%d received=%d sent=%d\n",
+		now.Format(time.RFC3339Nano), snapshot.CurrentConnections, snapshot.PeakConnections,
+		snapshot.TotalConnections, snapshot.TotalRequests, snapshot.BytesReceived, snapshot.BytesSent)
+
+	return err
+}
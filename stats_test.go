@@ -0,0 +1,369 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 stats_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startTestObserver(cfg serverConfig) serverInfo
+--  func TestReadStats(t *testing.T)
+--  func TestRetentionPrunesDetail(t *testing.T)
+--  func TestThroughputSteadyTransfer(t *testing.T)
+--  func TestLifetimeBuckets(t *testing.T)
+--  func TestResetOnReport(t *testing.T)
+--  func TestResponseRatioPadded(t *testing.T)
+--  func TestPeakConnections(t *testing.T)
+--  func TestRollupConnections(t *testing.T)
+--  func TestDurationPercentile(t *testing.T)
+--  func TestSnapshotLines(t *testing.T)
+--
+-- NOTES: Tests for the statistics the observer keeps. The observer runs in its
+--        own go routine as it does in the server, the test reports
+--        connections to it over the workers' channels.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestObserver a serverInfo with the observer running on it. The
+// observer is left running once the test finishes.
+func startTestObserver(cfg serverConfig) serverInfo {
+	srvInfo := newTestServerInfo(cfg)
+	go observerLoop(srvInfo, nil)
+
+	return srvInfo
+}
+
+// TestReadStats checks that the snapshot counts the connections opened and
+// closed before it was taken.
+func TestReadStats(t *testing.T) {
+	srvInfo := startTestObserver(serverConfig{})
+	if snapshot := readStats(srvInfo); snapshot.TotalConnections != 0 || snapshot.CurrentConnections != 0 {
+		t.Fatalf("a new server's snapshot was %+v", snapshot)
+	}
+
+	for i := 0; i < 3; i++ {
+		srvInfo.serverConnection <- newConnectionConst
+	}
+	srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 2,
+		BytesReceived: 10, BytesSent: 12, CloseReason: closeReasonIdle}
+	srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40001", NumberOfRequests: 1,
+		BytesReceived: 5, BytesSent: 6}
+
+	snapshot := readStats(srvInfo)
+	if snapshot.CurrentConnections != 1 || snapshot.PeakConnections != 3 || snapshot.TotalConnections != 2 {
+		t.Errorf("connections current %d peak %d total %d, want 1, 3 and 2",
+			snapshot.CurrentConnections, snapshot.PeakConnections, snapshot.TotalConnections)
+	}
+	if snapshot.TotalRequests != 3 || snapshot.BytesReceived != 15 || snapshot.BytesSent != 18 {
+		t.Errorf("requests %d received %d sent %d, want 3, 15 and 18",
+			snapshot.TotalRequests, snapshot.BytesReceived, snapshot.BytesSent)
+	}
+	if snapshot.CloseReasons[closeReasonIdle] != 1 {
+		t.Errorf("close reasons %v, want one %s", snapshot.CloseReasons, closeReasonIdle)
+	}
+}
+
+// TestRetentionPrunesDetail checks that connections closed longer than
+// -retention ago are no longer listed but are still in the totals.
+func TestRetentionPrunesDetail(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{retention: time.Minute})
+	clock := srvInfo.clock.(*fakeClock)
+	stats := newServerStats("")
+	stats.connectionOpened()
+	stats.connectionOpened()
+
+	connectionsClosed(srvInfo, stats, clock.Now(), connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 2,
+		BytesReceived: 10, EndTime: clock.Now()})
+	clock.Advance(30 * time.Second)
+	if stats.connectionsMade.Len() != 1 {
+		t.Fatalf("%d connections listed inside the retention window, want 1", stats.connectionsMade.Len())
+	}
+	clock.Advance(time.Minute)
+	connectionsClosed(srvInfo, stats, clock.Now(), connectionInfo{HostName: "192.0.2.1:40001", NumberOfRequests: 3,
+		BytesReceived: 15, EndTime: clock.Now()})
+
+	if stats.connectionsMade.Len() != 1 || stats.connectionsMade.Front().Value.(connectionInfo).HostName != "192.0.2.1:40001" {
+		t.Errorf("%d connections listed, want only the one inside the retention window", stats.connectionsMade.Len())
+	}
+	summary := stats.summary()
+	if summary.TotalConnections != 2 || summary.TotalRequests != 5 || summary.BytesReceived != 25 {
+		t.Errorf("totals were %d connections, %d requests and %d bytes, want 2, 5 and 25",
+			summary.TotalConnections, summary.TotalRequests, summary.BytesReceived)
+	}
+	if summary.PrunedConnections != 1 {
+		t.Errorf("%d connections pruned, want 1", summary.PrunedConnections)
+	}
+}
+
+// TestThroughputSteadyTransfer checks that a connection echoing the same
+// request every second is sampled as a constant throughput.
+func TestThroughputSteadyTransfer(t *testing.T) {
+	const samples = 5
+	request := strings.Repeat("x", 99) + "\n"
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', throughputSample: time.Second})
+	clock := srvInfo.clock.(*fakeClock)
+	client, _ := serveTestConnection(t, srvInfo)
+	reader := bufio.NewReader(client)
+
+	stats := newServerStats("")
+	ticker := clock.NewTicker(srvInfo.config.throughputSample)
+	defer ticker.Stop()
+	stats.sampleThroughput(clock.Now(), atomic.LoadInt64(srvInfo.bytesTransferred))
+	for i := 0; i < samples; i++ {
+		io.WriteString(client, request)
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal("read:", err)
+		}
+		// the echo is counted once the write returns, after the client has it
+		for atomic.LoadInt64(srvInfo.bytesTransferred) < int64(2*len(request)*(i+1)) {
+			runtime.Gosched()
+		}
+		clock.Advance(time.Second)
+		stats.sampleThroughput(<-ticker.Chan(), atomic.LoadInt64(srvInfo.bytesTransferred))
+	}
+
+	intervals := throughputIntervals(stats.summary().ThroughputSamples)
+	if len(intervals) != samples {
+		t.Fatalf("%d throughput intervals, want %d", len(intervals), samples)
+	}
+	for _, interval := range intervals {
+		if interval.BytesPerSecond != 2*float64(len(request)) {
+			t.Errorf("%s to %s ran at %.0f bytes a second, want %d", interval.Start.Format(time.TimeOnly),
+				interval.End.Format(time.TimeOnly), interval.BytesPerSecond, 2*len(request))
+		}
+	}
+}
+
+// TestLifetimeBuckets checks that short and long connections, timed on the
+// server's clock, are counted in the lifetime buckets they fall in.
+func TestLifetimeBuckets(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	clock := srvInfo.clock.(*fakeClock)
+	stats := newServerStats("")
+	for _, lifetime := range []time.Duration{5 * time.Millisecond, 50 * time.Millisecond,
+		30 * time.Second, 45 * time.Second, 2 * time.Hour} {
+		client, served := serveTestConnection(t, srvInfo)
+		io.WriteString(client, "hello\n")
+		bufio.NewReader(client).ReadString('\n') // the connection has started
+		clock.Advance(lifetime)
+		client.Close()
+		stats.connectionOpened()
+		stats.connectionClosed(<-served)
+	}
+
+	var buckets []string
+	for _, bucket := range stats.summary().LifetimeHistogram {
+		buckets = append(buckets, fmt.Sprintf("%s:%d", bucket.Lifetime, bucket.Connections))
+	}
+	if got, want := strings.Join(buckets, " "), "< 10ms:1 < 100ms:1 < 1s:0 < 10s:0 < 1m0s:2 < 10m0s:0 >= 10m0s:1"; got != want {
+		t.Errorf("the lifetime histogram was %s, want %s", got, want)
+	}
+}
+
+// TestResetOnReport checks that with -stats-reset-on-report each periodic
+// report only covers the connections closed in its interval, while the grand
+// total keeps counting.
+func TestResetOnReport(t *testing.T) {
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	srvInfo := startTestObserver(serverConfig{reportInterval: time.Minute, resetOnReport: true,
+		reportFile: reportFile, reportFormat: "json"})
+	clock := srvInfo.clock.(*fakeClock)
+
+	port := 40000
+	for interval, connections := range []int{1, 2} {
+		for i := 0; i < connections; i++ {
+			srvInfo.serverConnection <- newConnectionConst
+			srvInfo.connectInfo <- connectionInfo{HostName: fmt.Sprintf("192.0.2.1:%d", port), NumberOfRequests: 1}
+			port++
+		}
+		readStats(srvInfo) // the connections have been counted
+		clock.Advance(time.Minute)
+
+		var report struct {
+			Connections []connectionInfo
+			Summary     reportSummary
+		}
+		for attempt := 0; !report.Summary.GeneratedAt.Equal(clock.Now()); attempt++ {
+			if attempt == 100 {
+				t.Fatalf("report %d wasn't written", interval+1)
+			}
+			readStats(srvInfo) // gives the observer a turn to take the tick
+			if data, err := os.ReadFile(reportFile); err == nil {
+				json.Unmarshal(data, &report)
+			}
+		}
+		if len(report.Connections) != connections || report.Summary.TotalConnections != connections ||
+			report.Summary.TotalRequests != connections {
+			t.Errorf("report %d listed %d connections totalling %d with %d requests, want %d of each",
+				interval+1, len(report.Connections), report.Summary.TotalConnections, report.Summary.TotalRequests, connections)
+		}
+		if want := port - 40000; report.Summary.GrandTotalConnections != want {
+			t.Errorf("report %d had a grand total of %d connections, want %d", interval+1, report.Summary.GrandTotalConnections, want)
+		}
+	}
+}
+
+// TestResponseRatioPadded checks that a connection whose echoes are padded
+// records a ratio over 1 and is counted in the report's buckets, and that one
+// that sent nothing records 0 and is left out of them.
+func TestResponseRatioPadded(t *testing.T) {
+	stages, err := parsePipeline("pad=20")
+	if err != nil {
+		t.Fatal("parsePipeline:", err)
+	}
+	stats := newServerStats("")
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', pipeline: stages})
+	for _, requests := range [][]string{{"hello", "world"}, nil} {
+		client, served := serveTestConnection(t, srvInfo)
+		reader := bufio.NewReader(client)
+		for _, request := range requests {
+			go io.WriteString(client, request+"\n")
+			reader.ReadString('\n')
+		}
+		client.Close()
+		stats.connectionOpened()
+		stats.connectionClosed(<-served)
+	}
+
+	padded := stats.connectionsMade.Front().Value.(connectionInfo)
+	if padded.ResponseRatio <= 1 || padded.ResponseRatio != float64(padded.BytesSent)/float64(padded.BytesReceived) {
+		t.Errorf("%d bytes received and %d sent gave a ratio of %v, want their ratio, over 1",
+			padded.BytesReceived, padded.BytesSent, padded.ResponseRatio)
+	}
+	if silent := stats.connectionsMade.Back().Value.(connectionInfo); silent.ResponseRatio != 0 {
+		t.Errorf("a connection that sent nothing had a ratio of %v, want 0", silent.ResponseRatio)
+	}
+
+	var buckets []string
+	for _, bucket := range stats.summary().ResponseRatios {
+		buckets = append(buckets, fmt.Sprintf("%s:%d", bucket.ResponseRatio, bucket.Connections))
+	}
+	if got := strings.Join(buckets, " "); got != "< 1:0 1:0 <= 2:0 <= 10:1 > 10:0" {
+		t.Errorf("the ratio buckets were %s", got)
+	}
+}
+
+// TestPeakConnections checks that the peak is the most connections open at
+// once, not the total, and isn't raised by connections that come after.
+func TestPeakConnections(t *testing.T) {
+	const overlapping = 7
+	srvInfo := startTestObserver(serverConfig{})
+	for i := 0; i < overlapping; i++ {
+		srvInfo.serverConnection <- newConnectionConst
+	}
+	for i := 0; i < overlapping; i++ {
+		srvInfo.connectInfo <- connectionInfo{HostName: fmt.Sprintf("192.0.2.1:%d", 40000+i)}
+	}
+	for i := 0; i < 3; i++ {
+		srvInfo.serverConnection <- newConnectionConst
+		srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.2:40000"}
+	}
+
+	snapshot := readStats(srvInfo)
+	if snapshot.PeakConnections != overlapping || snapshot.TotalConnections != overlapping+3 {
+		t.Errorf("peak %d of %d connections, want %d of %d",
+			snapshot.PeakConnections, snapshot.TotalConnections, overlapping, overlapping+3)
+	}
+}
+
+// TestRollupConnections checks the duration, request and concurrency rollups
+// of a known list of connections, and that an empty list leaves them at 0.
+func TestRollupConnections(t *testing.T) {
+	connections := list.New()
+	for i, duration := range []int{40, 10, 100, 30, 20} {
+		connections.PushBack(connectionInfo{Duration: time.Duration(duration) * time.Millisecond,
+			NumberOfRequests: i + 1, ConnectionsAtClose: 5 - i})
+	}
+
+	var summary reportSummary
+	rollupConnections(connections, &summary)
+	if summary.MeanDuration != 40*time.Millisecond || summary.MedianDuration != 30*time.Millisecond ||
+		summary.P95Duration != 88*time.Millisecond {
+		t.Errorf("the mean, median and 95th percentile durations were %s, %s and %s, want 40ms, 30ms and 88ms",
+			summary.MeanDuration, summary.MedianDuration, summary.P95Duration)
+	}
+	if summary.MeanRequests != 3 || summary.MaxConnectionsAtClose != 5 {
+		t.Errorf("%v requests per connection and at most %d connections at close, want 3 and 5",
+			summary.MeanRequests, summary.MaxConnectionsAtClose)
+	}
+
+	var empty reportSummary
+	rollupConnections(list.New(), &empty)
+	if empty.MeanDuration != 0 || empty.MedianDuration != 0 || empty.P95Duration != 0 || empty.MeanRequests != 0 ||
+		empty.MaxConnectionsAtClose != 0 {
+		t.Errorf("an empty list gave the rollups %s, %s, %s, %v and %d, want them left at 0", empty.MeanDuration,
+			empty.MedianDuration, empty.P95Duration, empty.MeanRequests, empty.MaxConnectionsAtClose)
+	}
+}
+
+// TestDurationPercentile checks that percentiles between two durations are
+// interpolated linearly, and that the ends and a single duration are exact.
+func TestDurationPercentile(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms, 100 * ms}, 0, 10 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms, 100 * ms}, 0.5, 30 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms, 100 * ms}, 0.95, 88 * ms},
+		{[]time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms, 100 * ms}, 1, 100 * ms},
+		{[]time.Duration{10 * ms, 20 * ms}, 0.95, 19500 * time.Microsecond},
+		{[]time.Duration{7 * ms}, 0.95, 7 * ms},
+	}
+
+	for _, test := range tests {
+		if got := durationPercentile(test.sorted, test.p); got != test.want {
+			t.Errorf("durationPercentile(%v, %v) = %s, want %s", test.sorted, test.p, got, test.want)
+		}
+	}
+}
+
+// TestSnapshotLines checks that the observer writes a line of running totals
+// each -snapshot-interval, timed on the server clock, each with the totals at
+// the time it was written.
+func TestSnapshotLines(t *testing.T) {
+	srvInfo := newTestServerInfo(serverConfig{snapshotInterval: time.Second})
+	lines, writer := io.Pipe()
+	srvInfo.snapshotFile = writer
+	clock := srvInfo.clock.(*fakeClock)
+	go observerLoop(srvInfo, nil)
+	readStats(srvInfo) // the observer's tickers have been started
+
+	reader := bufio.NewReader(lines)
+	clock.Advance(time.Second)
+	first, _ := reader.ReadString('\n')
+	srvInfo.serverConnection <- newConnectionConst
+	srvInfo.connectInfo <- connectionInfo{HostName: "192.0.2.1:40000", NumberOfRequests: 3, BytesReceived: 12, BytesSent: 12}
+	srvInfo.serverConnection <- newConnectionConst
+	clock.Advance(time.Second)
+	second, _ := reader.ReadString('\n')
+
+	if want := "2026-10-15T00:00:01Z current=0 peak=0 total=0 requests=0 received=0 sent=0\n"; first != want {
+		t.Errorf("the first snapshot was %q, want %q", first, want)
+	}
+	if want := "2026-10-15T00:00:02Z current=1 peak=1 total=1 requests=3 received=12 sent=12\n"; second != want {
+		t.Errorf("the second snapshot was %q, want %q", second, want)
+	}
+}
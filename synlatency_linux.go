@@ -0,0 +1,79 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 synlatency_linux.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func synToAccept(conn net.Conn) time.Duration
+--
+-- NOTES: This file estimates how long a connection waited in the kernel between
+--        its SYN arriving and Accept returning, using TCP_INFO.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// synLatencySupported whether synToAccept can measure anything on this platform.
+const synLatencySupported = true
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    synToAccept
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func synToAccept(conn net.Conn) time.Duration
+--      conn:		a connection Accept has just returned.
+--
+-- RETURNS:     time.Duration the estimated time since the SYN arrived, 0 if
+--              it can't be measured.
+--
+-- NOTES:			The final ACK of the handshake arrived tcpi_last_ack_recv
+--						milliseconds ago, and the SYN arrived about one round trip
+--						before that. The round trip time at this point is the one
+--						measured during the handshake. Only accurate to the
+--						millisecond, and only if called before any data is read.
+------------------------------------------------------------------------------*/
+func synToAccept(conn net.Conn) time.Duration {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return 0
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return 0
+	}
+
+	var info *unix.TCPInfo
+	raw.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(info.Last_ack_recv)*time.Millisecond + time.Duration(info.Rtt)*time.Microsecond
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 synlatency_linux_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestSynToAcceptPopulated(t *testing.T)
+--
+-- NOTES: Tests for the SYN to accept latency. A connection is made over the
+--        loopback interface, so the kernel has measured a handshake.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestSynToAcceptPopulated checks that an accepted TCP connection has a SYN
+// to accept latency and that a connection that isn't TCP has none.
+func TestSynToAcceptPopulated(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal("accept:", err)
+	}
+	defer conn.Close()
+
+	if latency := synToAccept(conn); latency <= 0 || latency > time.Minute {
+		t.Errorf("SYN to accept latency of %s on loopback", latency)
+	}
+	server, other := net.Pipe()
+	defer server.Close()
+	defer other.Close()
+	if latency := synToAccept(server); latency != 0 {
+		t.Errorf("SYN to accept latency of %s on a pipe, want 0", latency)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 synlatency_other.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func synToAccept(conn net.Conn) time.Duration
+--
+-- NOTES: SYN to accept latency is only measured on Linux.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// synLatencySupported whether synToAccept can measure anything on this platform.
+const synLatencySupported = false
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    synToAccept
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func synToAccept(conn net.Conn) time.Duration
+--      conn:		a connection Accept has just returned.
+--
+-- RETURNS:     time.Duration always 0.
+------------------------------------------------------------------------------*/
+func synToAccept(conn net.Conn) time.Duration {
+	return 0
+}
@@ -0,0 +1,67 @@
+//go:build linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 tfo_linux_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestTFOListenerEchoes(t *testing.T)
+--
+-- NOTES: Tests for TCP Fast Open. The client connects without Fast Open, as
+--        the kernel may not have it enabled for clients.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+)
+
+// TestTFOListenerEchoes checks that a listener with Fast Open enabled still
+// accepts and echoes, and that a handshake without data in the SYN isn't
+// counted as Fast Open.
+func TestTFOListenerEchoes(t *testing.T) {
+	listenConfig := net.ListenConfig{Control: tfoControl}
+	listener, err := listenConfig.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen with Fast Open:", err)
+	}
+	defer listener.Close()
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n'})
+	fastOpen := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(fastOpen)
+			return
+		}
+		defer conn.Close()
+		fastOpen <- fastOpened(conn)
+		connectionInstance(context.Background(), conn, srvInfo, 1)
+	}()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer client.Close()
+	io.WriteString(client, "hello\n")
+	if echo, err := bufio.NewReader(client).ReadString('\n'); err != nil || echo != "hello\n" {
+		t.Errorf("echo was %q, %v", echo, err)
+	}
+	if opened, ok := <-fastOpen; !ok || opened {
+		t.Errorf("a handshake without SYN data was counted as Fast Open %v, accepted %v", opened, ok)
+	}
+}
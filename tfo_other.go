@@ -0,0 +1,72 @@
+//go:build !linux
+
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 tfo_other.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func tfoControl(network string, address string, raw syscall.RawConn) error
+--  func fastOpened(conn net.Conn) bool
+--
+-- NOTES: TCP Fast Open is only enabled on Linux, elsewhere the listener is
+--        left as it is.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// tfoSupported whether -tfo can enable TCP Fast Open on this platform.
+const tfoSupported = false
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    tfoControl
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func tfoControl(network string, address string, raw syscall.RawConn) error
+--   network:		the network being listened on.
+--   address:		the address being listened on.
+--       raw:		the listening socket, before listen is called.
+--
+-- RETURNS:     error always nil.
+------------------------------------------------------------------------------*/
+func tfoControl(network string, address string, raw syscall.RawConn) error {
+	return nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    fastOpened
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func fastOpened(conn net.Conn) bool
+--      conn:		a connection Accept has just returned.
+--
+-- RETURNS:     bool always false.
+------------------------------------------------------------------------------*/
+func fastOpened(conn net.Conn) bool {
+	return false
+}
@@ -0,0 +1,242 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 tls.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newTLSConfig(certFile string, keyFile string) (*tls.Config, error)
+--  func tlsConnection(conn net.Conn) *tls.Conn
+--  func tlsNegotiated(conn *tls.Conn) (string, string)
+--  func handshake(conn *tls.Conn, timeout time.Duration) error
+--  func isTimeout(err error) bool
+--  func isRenegotiationError(err error) bool
+--  func isPlaintextOnTLS(conn *tls.Conn, err error) bool
+--
+-- NOTES: This file holds the TLS support for encrypted echo connections.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+const closeReasonRenegotiation = "renegotiation-rejected"
+
+// renegotiationMessage the part of crypto/tls's error, in TLS 1.2 and 1.3,
+// for a client hello received after the handshake.
+const renegotiationMessage = "unexpected handshake message of type *tls.clientHelloMsg"
+
+// closeReasonHandshakeTimeout the CloseReason of a connection that didn't finish
+// its TLS handshake within -handshake-timeout.
+const closeReasonHandshakeTimeout = "handshake-timeout"
+
+// closeReasonPlaintext the CloseReason of a connection that sent plaintext
+// instead of a TLS handshake.
+const closeReasonPlaintext = "plaintext-on-tls"
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newTLSConfig
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - drops the Renegotiation setting, it only applies to clients
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newTLSConfig(certFile string, keyFile string) (*tls.Config, error)
+--  certFile:		the PEM encoded certificate.
+--   keyFile:		the PEM encoded private key for the certificate.
+--
+-- RETURNS:     *tls.Config the configuration for the listener.
+--              error       any error loading the key pair.
+--
+-- NOTES:			Go never renegotiates as a server, a client asking to is
+--						answered with an alert and its connection fails.
+------------------------------------------------------------------------------*/
+func newTLSConfig(certFile string, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isRenegotiationError
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - documented as best effort
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func isRenegotiationError(err error) bool
+--       err:		an error reading from a TLS connection.
+--
+-- RETURNS:     bool true if the error was caused by the client trying to
+--              renegotiate.
+--
+-- NOTES:			Best effort. crypto/tls doesn't export an error or an alert for
+--						this, so the only way to tell is the text of the error it
+--						returns for a client hello after the handshake, which isn't
+--						part of its API. If a Go release rewords it these connections
+--						are counted as plain errors instead.
+------------------------------------------------------------------------------*/
+func isRenegotiationError(err error) bool {
+	return strings.Contains(err.Error(), renegotiationMessage)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    tlsConnection
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func tlsConnection(conn net.Conn) *tls.Conn
+--      conn:		a connection a worker has accepted.
+--
+-- RETURNS:     *tls.Conn the TLS connection conn wraps, nil if it isn't using TLS.
+------------------------------------------------------------------------------*/
+func tlsConnection(conn net.Conn) *tls.Conn {
+	for {
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			return tlsConn
+		}
+		wrapped, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = wrapped.NetConn()
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    tlsNegotiated
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func tlsNegotiated(conn *tls.Conn) (string, string)
+--      conn:		a TLS connection.
+--
+-- RETURNS:     string the TLS version, e.g. "TLS 1.3".
+--              string the cipher suite, e.g. "TLS_AES_128_GCM_SHA256".
+--
+-- NOTES:			Both are empty if the handshake never completed. The handshake
+--						runs on the first read so this is called once the connection
+--						is finished with.
+------------------------------------------------------------------------------*/
+func tlsNegotiated(conn *tls.Conn) (string, string) {
+	state := conn.ConnectionState()
+	if !state.HandshakeComplete {
+		return "", ""
+	}
+
+	return tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    handshake
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func handshake(conn *tls.Conn, timeout time.Duration) error
+--      conn:		a TLS connection that hasn't been read from.
+--   timeout:		how long the client has to finish the handshake.
+--
+-- RETURNS:     error any error from the handshake, a timeout if the client
+--              took too long.
+--
+-- NOTES:			Runs the handshake up front rather than on the first read so a
+--						stalled client can't hold the worker past the timeout. The
+--						deadline is cleared once the handshake is done.
+------------------------------------------------------------------------------*/
+func handshake(conn *tls.Conn, timeout time.Duration) error {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isTimeout
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func isTimeout(err error) bool
+--       err:		an error from a connection.
+--
+-- RETURNS:     bool true if the error was caused by a deadline passing.
+------------------------------------------------------------------------------*/
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    isPlaintextOnTLS
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func isPlaintextOnTLS(conn *tls.Conn, err error) bool
+--      conn:		the TLS connection the error came from.
+--       err:		an error from the connection.
+--
+-- RETURNS:     bool true if the client sent something other than a TLS
+--              record before the handshake finished.
+--
+-- NOTES:			crypto/tls returns a RecordHeaderError when the first bytes
+--						aren't a TLS record header, which is how a plaintext client
+--						or a downgrade probe shows up. Once the handshake is done the
+--						same error means a corrupt record, so it isn't counted.
+------------------------------------------------------------------------------*/
+func isPlaintextOnTLS(conn *tls.Conn, err error) bool {
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &recordErr) && !conn.ConnectionState().HandshakeComplete
+}
@@ -0,0 +1,289 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 tls_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func writeTestCertificate(t *testing.T) (string, string)
+--  func serveTestTLSConnection(t *testing.T, srvInfo serverInfo, config *tls.Config) (net.Conn, <-chan connectionInfo)
+--  func TestNewTLSConfigHandshake(t *testing.T)
+--  func TestIsRenegotiationError(t *testing.T)
+--  func TestTLSDistribution(t *testing.T)
+--  func TestHandshakeTimeout(t *testing.T)
+--  func TestPlaintextOnTLS(t *testing.T)
+--  func TestTLSListenerEcho(t *testing.T)
+--
+-- NOTES: Tests for the TLS support. Certificates are generated for each test
+--        and the handshake runs over a net.Pipe, or over TCP when a worker
+--        accepts from a TLS listener.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self signed certificate for localhost and its
+// key to the test's temporary directory, returning their paths.
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), DNSNames: []string{"localhost"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("CreateCertificate:", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("MarshalECPrivateKey:", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	return certFile, keyFile
+}
+
+// serveTestTLSConnection serves the server's end of a net.Pipe over TLS as
+// worker 1 would, returning the client's end, which hasn't handshaked, and
+// where the connection's information is sent once it has been served.
+func serveTestTLSConnection(t *testing.T, srvInfo serverInfo, config *tls.Config) (net.Conn, <-chan connectionInfo) {
+	server, client := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	served := make(chan connectionInfo, 1)
+	go func() {
+		conn := tls.Server(server, config)
+		defer conn.Close()
+		served <- connectionInstance(context.Background(), conn, srvInfo, 1)
+	}()
+
+	return client, served
+}
+
+// TestNewTLSConfigHandshake checks that a client can handshake with the
+// listener's configuration and have data echoed over it.
+func TestNewTLSConfigHandshake(t *testing.T) {
+	config, err := newTLSConfig(writeTestCertificate(t))
+	if err != nil {
+		t.Fatal("newTLSConfig:", err)
+	}
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go func() {
+		conn := tls.Server(server, config)
+		io.CopyN(conn, conn, 5)
+	}()
+	conn := tls.Client(client, &tls.Config{InsecureSkipVerify: true})
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, "hello"); err != nil {
+		t.Fatal("write:", err)
+	}
+	echo := make([]byte, 5)
+	if _, err := io.ReadFull(conn, echo); err != nil || string(echo) != "hello" {
+		t.Errorf("echo was %q, %v", echo, err)
+	}
+}
+
+// TestIsRenegotiationError checks the errors crypto/tls returns for a client
+// hello after the handshake are told apart from other errors.
+func TestIsRenegotiationError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("tls: received unexpected handshake message of type *tls.clientHelloMsg when waiting for *tls.helloRequestMsg"), true},
+		{errors.New("tls: received unexpected handshake message of type *tls.clientHelloMsg"), true},
+		{errors.New("tls: received unexpected handshake message of type *tls.keyUpdateMsg"), false},
+		{io.EOF, false},
+		{errClosedByShutdown, false},
+	}
+	for _, test := range tests {
+		if got := isRenegotiationError(test.err); got != test.want {
+			t.Errorf("isRenegotiationError(%q) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+// TestTLSDistribution checks that connections from clients forcing different
+// TLS versions and cipher suites are counted by what they negotiated.
+func TestTLSDistribution(t *testing.T) {
+	config, err := newTLSConfig(writeTestCertificate(t))
+	if err != nil {
+		t.Fatal("newTLSConfig:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', tlsStats: true})
+	clients := []*tls.Config{
+		{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+		{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+		{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}},
+		{MinVersion: tls.VersionTLS13},
+	}
+	stats := newServerStats("")
+	var tls13Suite string // depends on whether the machine has AES in hardware
+	for _, clientConfig := range clients {
+		client, served := serveTestTLSConnection(t, srvInfo, config)
+		clientConfig.InsecureSkipVerify = true
+		conn := tls.Client(client, clientConfig)
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, "hello\n")
+		if _, err := io.ReadFull(conn, make([]byte, 6)); err != nil {
+			t.Fatal("reading the echo:", err)
+		}
+		if state := conn.ConnectionState(); state.Version == tls.VersionTLS13 {
+			tls13Suite = tls.CipherSuiteName(state.CipherSuite)
+		}
+		client.Close()
+		stats.connectionOpened()
+		stats.connectionClosed(<-served)
+	}
+
+	want := []tlsCount{
+		{Version: "TLS 1.2", CipherSuite: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", Connections: 2},
+		{Version: "TLS 1.2", CipherSuite: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", Connections: 1},
+		{Version: "TLS 1.3", CipherSuite: tls13Suite, Connections: 1},
+	}
+	distribution := stats.summary().TLSDistribution
+	if len(distribution) != len(want) {
+		t.Fatalf("the distribution was %+v, want %+v", distribution, want)
+	}
+	for i := range want {
+		if distribution[i] != want[i] {
+			t.Errorf("distribution row %d was %+v, want %+v", i, distribution[i], want[i])
+		}
+	}
+}
+
+// TestHandshakeTimeout checks that a client which never sends its hello is
+// dropped once -handshake-timeout has passed, and that one which handshakes
+// in time is echoed.
+func TestHandshakeTimeout(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	config, err := newTLSConfig(writeTestCertificate(t))
+	if err != nil {
+		t.Fatal("newTLSConfig:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', handshakeTimeout: timeout})
+
+	_, served := serveTestTLSConnection(t, srvInfo, config)
+	start := time.Now()
+	select {
+	case connInfo := <-served:
+		if connInfo.CloseReason != closeReasonHandshakeTimeout {
+			t.Errorf("the stalled client was closed for %q, want %q", connInfo.CloseReason, closeReasonHandshakeTimeout)
+		}
+		if waited := time.Since(start); waited < timeout {
+			t.Errorf("the stalled client was dropped after %s, before the timeout", waited)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stalled handshake wasn't aborted")
+	}
+
+	client, served := serveTestTLSConnection(t, srvInfo, config)
+	conn := tls.Client(client, &tls.Config{InsecureSkipVerify: true})
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "hello\n")
+	if echo, err := bufio.NewReader(conn).ReadString('\n'); echo != "hello\n" {
+		t.Errorf("the echo was %q, %v", echo, err)
+	}
+	client.Close()
+	if connInfo := <-served; connInfo.CloseReason == closeReasonHandshakeTimeout {
+		t.Error("a client that handshaked in time was closed for the handshake timeout")
+	}
+}
+
+// TestPlaintextOnTLS checks that a client sending plaintext to the TLS
+// listener is closed as plaintext-on-tls, whether or not the handshake is run
+// up front, and counted as such in the statistics.
+func TestPlaintextOnTLS(t *testing.T) {
+	config, err := newTLSConfig(writeTestCertificate(t))
+	if err != nil {
+		t.Fatal("newTLSConfig:", err)
+	}
+	stats := newServerStats("")
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n', handshakeTimeout: timeout})
+		client, served := serveTestTLSConnection(t, srvInfo, config)
+		go io.Copy(io.Discard, client)
+		go io.WriteString(client, "hello\n")
+
+		connInfo := <-served
+		if connInfo.CloseReason != closeReasonPlaintext {
+			t.Errorf("with -handshake-timeout %s plaintext was closed for %q, want %q",
+				timeout, connInfo.CloseReason, closeReasonPlaintext)
+		}
+		stats.connectionOpened()
+		stats.connectionClosed(connInfo)
+	}
+
+	if attempts := stats.snapshot().CloseReasons[closeReasonPlaintext]; attempts != 2 {
+		t.Errorf("%d plaintext attempts counted, want 2", attempts)
+	}
+}
+
+// TestTLSListenerEcho checks that a worker accepting from a TLS listener, as
+// newServerInfo sets one up with -tls-cert and -tls-key, echoes a line sent
+// over tls.Dial, and that a certificate that can't be loaded is an error.
+func TestTLSListenerEcho(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	config, err := newTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatal("newTLSConfig:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{mode: modeThread, proto: protoTCP, framing: framingLine, delimiter: '\n'})
+	srvInfo.ctx, srvInfo.cancel = context.WithCancel(context.Background())
+	defer srvInfo.cancel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	// the listener is left open, closing it would shut the observer down
+	srvInfo.listener, srvInfo.acceptGate = tls.NewListener(listener, config), newAcceptGate()
+	*srvInfo.availableServers++
+	spawnWorker(srvInfo)
+	go observerLoop(srvInfo, nil)
+
+	client, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal("tls.Dial:", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(client, "hello\n")
+	if echo, err := bufio.NewReader(client).ReadString('\n'); echo != "hello\n" {
+		t.Errorf("the echo was %q, %v, want %q", echo, err, "hello\n")
+	}
+
+	if _, err := newTLSConfig(filepath.Join(t.TempDir(), "missing.pem"), keyFile); err == nil {
+		t.Error("newTLSConfig loaded a certificate that doesn't exist")
+	}
+}
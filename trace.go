@@ -0,0 +1,90 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 trace.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func startTrace(fname string) *os.File
+--  func stopTrace(traceFile *os.File)
+--
+-- NOTES: This file captures a runtime trace of the server for use with
+--        "go tool trace".
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"log"
+	"os"
+	"runtime/trace"
+)
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    startTrace
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func startTrace(fname string) *os.File
+--     fname:		the file to write the trace to.
+--
+-- RETURNS:     *os.File the trace file, nil if tracing is off.
+--
+-- NOTES:			Exits the program if the trace can't be started.
+------------------------------------------------------------------------------*/
+func startTrace(fname string) *os.File {
+	if fname == "" {
+		return nil
+	}
+
+	traceFile, err := os.Create(fname)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = trace.Start(traceFile); err != nil {
+		log.Fatalln(err)
+	}
+
+	return traceFile
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    stopTrace
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func stopTrace(traceFile *os.File)
+-- traceFile:		the file returned by startTrace.
+--
+-- RETURNS:     void
+--
+-- NOTES:			Flushes the trace to the file and closes it. Must be called
+--						before the process exits or the trace will be truncated.
+------------------------------------------------------------------------------*/
+func stopTrace(traceFile *os.File) {
+	if traceFile == nil {
+		return
+	}
+
+	trace.Stop()
+	if err := traceFile.Close(); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,72 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 trace_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestTraceWritten(t *testing.T)
+--  func TestTraceFlushedOnShutdown(t *testing.T)
+--
+-- NOTES: Smoke tests for -trace. Only one trace can run at a time, so these
+--        tests aren't run in parallel.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTraceWritten checks that a trace file is written between startTrace and
+// stopTrace, and that tracing is off without a file name.
+func TestTraceWritten(t *testing.T) {
+	if traceFile := startTrace(""); traceFile != nil {
+		t.Fatal("a trace was started without -trace")
+	}
+	stopTrace(nil)
+
+	fname := filepath.Join(t.TempDir(), "server.trace")
+	traceFile := startTrace(fname)
+	traced := make(chan bool) // something for the trace to record
+	go func() { traced <- true }()
+	<-traced
+	stopTrace(traceFile)
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal("ReadFile:", err)
+	}
+	if !bytes.HasPrefix(data, []byte("go 1.")) {
+		t.Errorf("the trace file starts %q, want a runtime/trace header", data[:min(len(data), 16)])
+	}
+}
+
+// TestTraceFlushedOnShutdown checks that the trace is flushed when the server
+// shuts down cleanly.
+func TestTraceFlushedOnShutdown(t *testing.T) {
+	if reportFile := testChildReport(); reportFile != "" {
+		srvInfo := newTestServerInfo(serverConfig{reportFile: reportFile, reportFormat: "json"})
+		srvInfo.traceFile = startTrace(filepath.Join(filepath.Dir(reportFile), "server.trace"))
+		go observerLoop(srvInfo, nil)
+		requestShutdown(srvInfo, nil)
+		select {}
+	}
+
+	_, status, reportFile := runTestChild(t, "TestTraceFlushedOnShutdown")
+	if status != 0 {
+		t.Fatalf("the server exited with %d, want 0", status)
+	}
+	info, err := os.Stat(filepath.Join(filepath.Dir(reportFile), "server.trace"))
+	if err != nil || info.Size() == 0 {
+		t.Errorf("the trace file wasn't written, %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 udp.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newUDPServer(network string, address string) (*udpServer, error)
+--  func (server *udpServer) serve(srvInfo serverInfo)
+--  func (server *udpServer) record(addr net.Addr, received int, sent int, now time.Time) bool
+--  func (server *udpServer) finish() []connectionInfo
+--
+-- NOTES: This file echoes datagrams with -proto udp. There is no listener or
+--        worker pool, one go routine reads each datagram and writes it back
+--        to its sender. Every source address is treated as a connection:
+--        the first datagram from it counts as it connecting, and its bytes
+--        and datagrams are kept in a connectionInfo keyed by the address.
+--        UDP has no close, so the sources are reported when the server exits
+--        and each one's EndTime is its last datagram. Sources are never
+--        forgotten while the server runs.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxDatagram the largest datagram that can be echoed.
+const maxDatagram = 65535
+
+// udpServer the socket datagrams are echoed on and what each source has sent.
+type udpServer struct {
+	conn    net.PacketConn
+	mutex   sync.Mutex
+	sources map[string]*connectionInfo // by source address, NumberOfRequests counts its datagrams
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newUDPServer
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newUDPServer(network string, address string) (*udpServer, error)
+--   network:		the -network, tcp, tcp4 or tcp6.
+--   address:		the address to listen on.
+--
+-- RETURNS:     *udpServer the server, listening on the UDP network matching
+--              network.
+--              error any error listening.
+------------------------------------------------------------------------------*/
+func newUDPServer(network string, address string) (*udpServer, error) {
+	conn, err := net.ListenPacket(strings.Replace(network, protoTCP, protoUDP, 1), address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &udpServer{conn: conn, sources: make(map[string]*connectionInfo)}, nil
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    serve
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:   October 15, 2026 - logs through the leveled logger
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (server *udpServer) serve(srvInfo serverInfo)
+--	 srvInfo:		information about the overall server
+--
+-- RETURNS:     void
+--
+-- NOTES:			Echoes datagrams until the socket is closed. A new source is
+--						reported to the observer like a new connection. A permanent
+--						read error shuts the server down.
+------------------------------------------------------------------------------*/
+func (server *udpServer) serve(srvInfo serverInfo) {
+	buffer := make([]byte, maxDatagram)
+	for {
+		n, addr, err := server.conn.ReadFrom(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			srvInfo.logger.Warn("Read failed", "err", err)
+			if !isTemporary(err) {
+				requestShutdown(srvInfo, err)
+				return
+			}
+			continue
+		}
+
+		sent, err := server.conn.WriteTo(buffer[:n], addr)
+		if err != nil {
+			srvInfo.logger.Warn("Unable to echo", "host", addr, "err", err)
+		}
+		atomic.AddInt64(srvInfo.bytesTransferred, int64(n+sent))
+		if server.record(addr, n, sent, srvInfo.clock.Now()) {
+			srvInfo.serverConnection <- newConnectionConst
+		}
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    record
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (server *udpServer) record(addr net.Addr, received int, sent int, now time.Time) bool
+--      addr:		the source of the datagram.
+--  received:		the bytes in the datagram.
+--      sent:		the bytes echoed back.
+--       now:		when the datagram was echoed.
+--
+-- RETURNS:     bool whether this was the first datagram from addr.
+------------------------------------------------------------------------------*/
+func (server *udpServer) record(addr net.Addr, received int, sent int, now time.Time) bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	key := addr.String()
+	source, ok := server.sources[key]
+	if !ok {
+		source = &connectionInfo{HostName: key, LocalAddr: server.conn.LocalAddr().String(), StartTime: now}
+		server.sources[key] = source
+	}
+	source.AmmountOfData += received
+	source.BytesReceived += received
+	source.BytesSent += sent
+	source.NumberOfRequests++
+	source.EndTime = now
+	source.Duration = now.Sub(source.StartTime)
+
+	return !ok
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    finish
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (server *udpServer) finish() []connectionInfo
+--
+-- RETURNS:     []connectionInfo every source, in the order they were first
+--              seen. nil on a nil server.
+--
+-- NOTES:			Closes the socket so nothing more is recorded.
+------------------------------------------------------------------------------*/
+func (server *udpServer) finish() []connectionInfo {
+	if server == nil {
+		return nil
+	}
+	server.conn.Close()
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	sources := make([]connectionInfo, 0, len(server.sources))
+	for _, source := range server.sources {
+		if source.BytesReceived > 0 {
+			source.ResponseRatio = float64(source.BytesSent) / float64(source.BytesReceived)
+		}
+		sources = append(sources, *source)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].StartTime.Before(sources[j].StartTime) })
+
+	return sources
+}
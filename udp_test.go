@@ -0,0 +1,88 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 udp_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestUDPEchoCounted(t *testing.T)
+--
+-- NOTES: Tests for -proto udp. The server reads from a socket on the loopback
+--        address and the clients are UDP sockets of their own.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUDPEchoCounted checks that datagrams from two sources are echoed to
+// their senders, that each source is counted as connecting once, and that
+// their datagrams and bytes are reported when the server finishes.
+func TestUDPEchoCounted(t *testing.T) {
+	server, err := newUDPServer(protoTCP, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("newUDPServer:", err)
+	}
+	srvInfo := newTestServerInfo(serverConfig{proto: protoUDP})
+	srvInfo.udp = server
+	srvInfo.serverConnection = make(chan int, 2)
+	served := make(chan bool)
+	go func() {
+		server.serve(srvInfo)
+		close(served)
+	}()
+
+	sent := map[string][]string{}
+	for _, datagrams := range [][]string{{"a", "bb", "ccc", strings.Repeat("d", 1000), "e"}, {"hello", "world"}} {
+		client, err := net.Dial(protoUDP, server.conn.LocalAddr().String())
+		if err != nil {
+			t.Fatal("dial:", err)
+		}
+		defer client.Close()
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		for _, datagram := range datagrams {
+			client.Write([]byte(datagram))
+			echo := make([]byte, maxDatagram)
+			if n, err := client.Read(echo); string(echo[:n]) != datagram {
+				t.Errorf("a %d byte datagram was echoed as %d bytes, %v", len(datagram), n, err)
+			}
+		}
+		sent[client.LocalAddr().String()] = datagrams
+	}
+
+	// a datagram is recorded after it is echoed, so wait for the last ones
+	for recorded := 0; recorded < 7; runtime.Gosched() {
+		server.mutex.Lock()
+		recorded = 0
+		for _, source := range server.sources {
+			recorded += source.NumberOfRequests
+		}
+		server.mutex.Unlock()
+	}
+	sources := server.finish()
+	<-served
+	if len(srvInfo.serverConnection) != 2 || len(sources) != 2 {
+		t.Fatalf("%d sources connected and %d reported, want 2", len(srvInfo.serverConnection), len(sources))
+	}
+	for _, source := range sources {
+		datagrams := sent[source.HostName]
+		size := len(strings.Join(datagrams, ""))
+		if source.NumberOfRequests != len(datagrams) || source.BytesReceived != size || source.BytesSent != size ||
+			source.ResponseRatio != 1 {
+			t.Errorf("%s sent %d datagrams, %d bytes, had %d echoed at a ratio of %v, want %d, %d bytes each way and 1",
+				source.HostName, source.NumberOfRequests, source.BytesReceived, source.BytesSent, source.ResponseRatio,
+				len(datagrams), size)
+		}
+	}
+}
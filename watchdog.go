@@ -0,0 +1,356 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 watchdog.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func newStuckWatchdog(threshold time.Duration, clock Clock) *stuckWatchdog
+--  func (watchdog *stuckWatchdog) watch(hostName string) *connProgress
+--  func (watchdog *stuckWatchdog) unwatch(progress *connProgress)
+--  func (watchdog *stuckWatchdog) run()
+--  func (watchdog *stuckWatchdog) check(now time.Time)
+--  func writeStackDump(now time.Time) (string, error)
+--  func (progress *connProgress) advance()
+--  func (progress *connProgress) begin()
+--  func (progress *connProgress) end()
+--  func (progress *connProgress) stalls() int
+--
+-- NOTES: This file turns hung workers into stack dumps. With -stuck-threshold
+--        each connection records when it last read or wrote anything, and
+--        whether its worker is handling a request. A connection that has
+--        been handling a request for longer than the threshold without any
+--        progress is stalled: the stacks of every go routine are written to
+--        stuck-<time>.txt and the stall is counted on the connection. A
+--        worker waiting for the client's next request isn't stalled, the
+--        client is just idle. Each stall is dumped once, a connection has to
+--        make progress before it can stall again.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"log"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stuckWatchdog the connections being watched for stalls.
+type stuckWatchdog struct {
+	mutex     sync.Mutex
+	threshold time.Duration
+	clock     Clock
+	watched   map[*connProgress]bool
+}
+
+// connProgress the progress of a connection, updated by its worker and read
+// by the watchdog. Every field but hostName is updated atomically.
+type connProgress struct {
+	hostName string
+	clock    Clock
+	last     int64 // when the connection last made progress, in unix nanoseconds
+	busy     int32 // 1 while the worker is handling a request
+	dumped   int32 // 1 once the current stall has been dumped
+	stalled  int32 // the stalls dumped
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    newStuckWatchdog
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func newStuckWatchdog(threshold time.Duration, clock Clock) *stuckWatchdog
+-- threshold:		how long a connection can go without progress.
+--     clock:		the clock progress is timed by.
+--
+-- RETURNS:     *stuckWatchdog a running watchdog, nil if threshold is 0.
+------------------------------------------------------------------------------*/
+func newStuckWatchdog(threshold time.Duration, clock Clock) *stuckWatchdog {
+	if threshold <= 0 {
+		return nil
+	}
+	watchdog := &stuckWatchdog{threshold: threshold, clock: clock, watched: make(map[*connProgress]bool)}
+	go watchdog.run()
+
+	return watchdog
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    watch
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (watchdog *stuckWatchdog) watch(hostName string) *connProgress
+--  hostName:		the client the connection is from, for the log.
+--
+-- RETURNS:     *connProgress the connection's progress, to be passed to
+--              unwatch once it closes. nil on a nil watchdog.
+------------------------------------------------------------------------------*/
+func (watchdog *stuckWatchdog) watch(hostName string) *connProgress {
+	if watchdog == nil {
+		return nil
+	}
+	progress := &connProgress{hostName: hostName, clock: watchdog.clock}
+	progress.advance()
+
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	watchdog.watched[progress] = true
+
+	return progress
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    unwatch
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (watchdog *stuckWatchdog) unwatch(progress *connProgress)
+--  progress:		the progress returned by watch.
+--
+-- RETURNS:     void
+------------------------------------------------------------------------------*/
+func (watchdog *stuckWatchdog) unwatch(progress *connProgress) {
+	if watchdog == nil {
+		return
+	}
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+
+	delete(watchdog.watched, progress)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    run
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (watchdog *stuckWatchdog) run()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Checks the connections every half threshold, so a stall is
+--						caught within one and a half thresholds of the last progress.
+------------------------------------------------------------------------------*/
+func (watchdog *stuckWatchdog) run() {
+	ticker := watchdog.clock.NewTicker(watchdog.threshold / 2)
+	defer ticker.Stop()
+
+	for now := range ticker.Chan() {
+		watchdog.check(now)
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    check
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (watchdog *stuckWatchdog) check(now time.Time)
+--       now:		the time of the check.
+--
+-- RETURNS:     void
+--
+-- NOTES:			A single dump holds every go routine, so connections found
+--						stalled in the same check share one.
+------------------------------------------------------------------------------*/
+func (watchdog *stuckWatchdog) check(now time.Time) {
+	var stalled []*connProgress
+	watchdog.mutex.Lock()
+	for progress := range watchdog.watched {
+		last := time.Unix(0, atomic.LoadInt64(&progress.last))
+		if atomic.LoadInt32(&progress.busy) == 1 && now.Sub(last) > watchdog.threshold &&
+			atomic.CompareAndSwapInt32(&progress.dumped, 0, 1) {
+			atomic.AddInt32(&progress.stalled, 1)
+			stalled = append(stalled, progress)
+		}
+	}
+	watchdog.mutex.Unlock()
+	if len(stalled) == 0 {
+		return
+	}
+
+	fname, err := writeStackDump(now)
+	if err != nil {
+		log.Println("Unable to write the stack dump:", err)
+	}
+	for _, progress := range stalled {
+		log.Println(progress.hostName, "made no progress for", watchdog.threshold.String()+", stacks written to", fname)
+	}
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    writeStackDump
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func writeStackDump(now time.Time) (string, error)
+--       now:		the time the dump is named after.
+--
+-- RETURNS:     string the file the stacks were written to.
+--              error any error writing them.
+--
+-- NOTES:			The stacks are written in the same format as an unrecovered
+--						panic, with how long each go routine has been blocked.
+------------------------------------------------------------------------------*/
+func writeStackDump(now time.Time) (string, error) {
+	fname := "stuck-" + now.Format("20060102-150405.000000000") + ".txt"
+	file, err := os.Create(fname)
+	if err != nil {
+		return fname, err
+	}
+	err = pprof.Lookup("goroutine").WriteTo(file, 2)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return fname, err
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    advance
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (progress *connProgress) advance()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil progress. Called whenever the connection
+--						reads or writes something, ends any stall.
+------------------------------------------------------------------------------*/
+func (progress *connProgress) advance() {
+	if progress == nil {
+		return
+	}
+
+	atomic.StoreInt64(&progress.last, progress.clock.Now().UnixNano())
+	atomic.StoreInt32(&progress.dumped, 0)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    begin
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (progress *connProgress) begin()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil progress. Called once a request has been
+--						read, counts as progress as the request may have been
+--						buffered without a read.
+------------------------------------------------------------------------------*/
+func (progress *connProgress) begin() {
+	if progress == nil {
+		return
+	}
+
+	progress.advance()
+	atomic.StoreInt32(&progress.busy, 1)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    end
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (progress *connProgress) end()
+--
+-- RETURNS:     void
+--
+-- NOTES:			Does nothing on a nil progress. Called once the worker goes
+--						back to waiting for the client.
+------------------------------------------------------------------------------*/
+func (progress *connProgress) end() {
+	if progress == nil {
+		return
+	}
+
+	atomic.StoreInt32(&progress.busy, 0)
+}
+
+/*-----------------------------------------------------------------------------
+-- FUNCTION:    stalls
+--
+-- DATE:        October 15, 2026
+--
+-- REVISIONS:
+--
+-- DESIGNER:		Marc Vouve
+--
+-- PROGRAMMER:	Marc Vouve
+--
+-- INTERFACE:   func (progress *connProgress) stalls() int
+--
+-- RETURNS:     int the stalls dumped for the connection, 0 on a nil progress.
+------------------------------------------------------------------------------*/
+func (progress *connProgress) stalls() int {
+	if progress == nil {
+		return 0
+	}
+
+	return int(atomic.LoadInt32(&progress.stalled))
+}
@@ -0,0 +1,78 @@
+/*------------------------------------------------------------------------------
+-- DATE:	       October 15, 2026
+--
+-- Source File:	 watchdog_test.go
+--
+-- REVISIONS: 	(Date and Description)
+--
+-- DESIGNER:	   Marc Vouve
+--
+-- PROGRAMMER:	 Marc Vouve
+--
+--
+-- INTERFACE:
+--	func TestStuckRequestDumped(t *testing.T)
+--
+-- NOTES: Tests for the stuck watchdog. The watchdog's ticker and the stalled
+--        request's delay both run on a fakeClock, and the dump is written to
+--        the test's temporary directory.
+------------------------------------------------------------------------------*/
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStuckRequestDumped checks that a request held up past -stuck-threshold
+// has the stacks dumped once, showing the stalled worker, and the stall
+// counted on its connection.
+func TestStuckRequestDumped(t *testing.T) {
+	t.Chdir(t.TempDir())
+	logReader, logWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal("pipe:", err)
+	}
+	log.SetOutput(logWriter)
+	defer log.SetOutput(os.Stderr)
+
+	srvInfo := newTestServerInfo(serverConfig{framing: framingLine, delimiter: '\n',
+		delay: 5 * time.Second, delayDist: delayFixed, stuckThreshold: time.Second})
+	clock := srvInfo.clock.(*fakeClock)
+	srvInfo.watchdog = newStuckWatchdog(time.Second, clock)
+	waitForWaiters(clock, 1) // the watchdog's ticker
+	client, served := serveTestConnection(t, srvInfo)
+	go io.WriteString(client, "hello\n")
+	waitForWaiters(clock, 2) // the request's delay
+
+	clock.Advance(2 * time.Second)
+	line, _ := bufio.NewReader(logReader).ReadString('\n')
+	if !strings.Contains(line, "made no progress") {
+		t.Fatalf("the stall was logged as %q", line)
+	}
+	dumps, _ := filepath.Glob("stuck-*.txt")
+	if len(dumps) != 1 || dumps[0] != "stuck-20261015-000002.000000000.txt" {
+		t.Fatalf("the dumps written were %v, want the one named after the check", dumps)
+	}
+	if dump, err := os.ReadFile(dumps[0]); err != nil || !strings.Contains(string(dump), "processRequest") {
+		t.Errorf("the dump didn't show the stalled worker, %v", err)
+	}
+
+	clock.Advance(3 * time.Second)
+	if echo, err := bufio.NewReader(client).ReadString('\n'); echo != "hello\n" {
+		t.Errorf("the echo was %q, %v, want the request once it was no longer stalled", echo, err)
+	}
+	client.Close()
+	if connInfo := <-served; connInfo.Stalls != 1 {
+		t.Errorf("%d stalls counted, want 1", connInfo.Stalls)
+	}
+	if dumps, _ := filepath.Glob("stuck-*.txt"); len(dumps) != 1 {
+		t.Errorf("%d dumps written, want the stall dumped once", len(dumps))
+	}
+}